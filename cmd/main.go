@@ -31,8 +31,13 @@ func main() {
 	database.Connect()
 	tron.NewClient()
 	//database.SeedDatabase()
-	//	log.Println(tron.ConvertUSDToTRX(10.00))
 	go cron.NewPaymentCron()
+	go cron.NewRenewalCron()
+	go cron.NewWebhookCron()
+	go cron.NewSweepCron()
+	go cron.NewRefundCron()
+	go cron.NewSubscriptionCron()
+	go cron.NewIdempotencyCron()
 	
 	// Seed admin before starting server
 	database.SeedAdmin()
@@ -21,14 +21,56 @@ type Config struct {
 	DATABASE_PORT string
 	DATABASE_PASS string
 	//  wallet stuff
-	TRX_HOT_WALLET_ADDRESS    string
-	TRX_WALLET_ENCRYPTION_KEY string
-	TRON_GRID_API_KEY         string
-	BINANCE_API_URL           string
-	TRON_GRPC_MAINNET         string
-	TRON_GRPC_TESTNET         string
-	TRON_GRID_API_URL_MAINNET string
-	TRON_GRID_API_URL_TESTNET string
+	TRX_HOT_WALLET_ADDRESS     string
+	TRX_HOT_WALLET_PRIVATE_KEY string
+	TRX_TOPUP_MODE             string // "transfer" sends TRX for fees, "delegate" delegates staked energy
+	TRX_WALLET_ENCRYPTION_KEY  string
+	TRON_GRID_API_KEY          string
+	BINANCE_API_URL            string
+	COINGECKO_API_URL          string
+	KRAKEN_API_URL             string
+	TRON_GRPC_MAINNET          string
+	TRON_GRPC_TESTNET          string
+	TRON_GRID_API_URL_MAINNET  string
+	TRON_GRID_API_URL_TESTNET  string
+	TRON_GRID_BASE_URL_MAINNET string
+	TRON_GRID_BASE_URL_TESTNET string
+	// pricing
+	PRICE_SOURCES                 string  // comma separated: binance, coingecko, kraken, static
+	STATIC_PRICES                 string  // manual prices for the static source, "TRX=0.25,USDT=1"
+	PRICE_MAX_DEVIATION_PERCENT   float64 // quotes further than this from the median are rejected
+	PRICE_CACHE_TTL_SECONDS       int
+	PRICE_TIMEOUT_SECONDS         int
+	FX_SOURCES                    string // comma separated: frankfurter, kraken, static
+	STATIC_FX_RATES               string // manual USD value of one unit of a fiat currency for the static source, "EUR=1.08,GBP=1.27"
+	FRANKFURTER_API_URL           string
+	FX_CACHE_TTL_SECONDS          int
+	QUOTE_LOCK_MINUTES            int    // how long a payment's quoted amount is honoured
+	PAYMENT_EXPIRY_MINUTES        int    // payment window when neither the plan nor the currency sets one
+	LATE_PAYMENT_WINDOW_HOURS     int    // how long expired payments are watched for late deposits
+	PARTIAL_PAYMENT_GRACE_MINUTES int    // how long an underpaid payment stays open for the rest from its first deposit
+	REQUOTE_POLICY                string // "requote" or "expire", what happens to unpaid payments when their quote lock ends
+	MAX_REQUOTES                  int    // unpaid payments are expired after this many re-quotes
+	// sweeping
+	SWEEP_POLICY   string // "immediate", "schedule" or "threshold"
+	SWEEP_SCHEDULE string // cron spec the "schedule" policy sweeps on
+	// hosted checkout
+	CHECKOUT_TEMPLATE      string // html/template the checkout page is rendered from
+	CHECKOUT_BRAND_NAME    string
+	CHECKOUT_PRIMARY_COLOR string // any CSS color
+	CHECKOUT_LOGO_URL      string
+	CHECKOUT_SUCCESS_URL   string // where the customer is sent once paid, {payment_id} is replaced
+	CHECKOUT_CANCEL_URL    string // where the customer is sent after cancelling, {payment_id} is replaced
+	// html/template invoices are rendered from at /invoices/:id
+	INVOICE_TEMPLATE string
+	// subscriptions
+	SUBSCRIPTION_REMINDER_DAYS int // days before a subscription ends the customer is reminded
+	RENEWAL_INVOICE_DAYS       int // days before a subscription ends its renewal is invoiced, 0 turns renewals off
+	// refunds
+	REFUND_LINK_SECRET    string // signs the links customers give their refund address through
+	REFUND_LINK_TTL_HOURS int
+	// how long Idempotency-Key responses are kept for replays
+	IDEMPOTENCY_TTL_HOURS int
 	//emailing config stuff
 	EMAIL_SMTP_HOST string
 	EMAIL_SMTP_PORT int
@@ -55,25 +97,72 @@ func NewConfig() {
 		port = 587
 	}
 
+	topUpMode := os.Getenv("TRX_TOPUP_MODE")
+	if topUpMode == "" {
+		topUpMode = "transfer"
+	}
+
 	Cfg = &Config{
 		APP_NAME: os.Getenv("APP_NAME"),
 		APP_ENV:  os.Getenv("APP_ENV"),
 		APP_PORT: os.Getenv("APP_PORT"),
 		APP_URL:  os.Getenv("APP_URL"),
 
-		DATABASE_NAME:             os.Getenv("DATABASE_NAME"),
-		DATABASE_HOST:             os.Getenv("DATABASE_HOST"),
-		DATABASE_USER:             os.Getenv("DATABASE_USER"),
-		DATABASE_PORT:             os.Getenv("DATABASE_PORT"),
-		DATABASE_PASS:             os.Getenv("DATABASE_PASS"),
-		BINANCE_API_URL:           os.Getenv("BINANCE_API_URL"),
-		TRON_GRID_API_URL_MAINNET: os.Getenv("TRON_GRID_API_URL_MAINNET"),
-		TRON_GRID_API_URL_TESTNET: os.Getenv("TRON_GRID_API_URL_TESTNET"),
-		TRX_HOT_WALLET_ADDRESS:    os.Getenv("TRX_HOT_WALLET_ADDRESS"),
-		TRX_WALLET_ENCRYPTION_KEY: os.Getenv("TRX_WALLET_ENCRYPTION_KEY"),
-		TRON_GRID_API_KEY:         os.Getenv("TRON_GRID_API_KEY"),
-		TRON_GRPC_MAINNET:         os.Getenv("TRON_GRPC_MAINNET"),
-		TRON_GRPC_TESTNET:         os.Getenv("TRON_GRPC_TESTNET"),
+		DATABASE_NAME:              os.Getenv("DATABASE_NAME"),
+		DATABASE_HOST:              os.Getenv("DATABASE_HOST"),
+		DATABASE_USER:              os.Getenv("DATABASE_USER"),
+		DATABASE_PORT:              os.Getenv("DATABASE_PORT"),
+		DATABASE_PASS:              os.Getenv("DATABASE_PASS"),
+		BINANCE_API_URL:            envOrDefault("BINANCE_API_URL", "https://api.binance.com/api/v3/ticker/price?symbol=TRXUSDT"),
+		COINGECKO_API_URL:          envOrDefault("COINGECKO_API_URL", "https://api.coingecko.com/api/v3/simple/price"),
+		KRAKEN_API_URL:             envOrDefault("KRAKEN_API_URL", "https://api.kraken.com/0/public/Ticker"),
+		TRON_GRID_API_URL_MAINNET:  os.Getenv("TRON_GRID_API_URL_MAINNET"),
+		TRON_GRID_API_URL_TESTNET:  os.Getenv("TRON_GRID_API_URL_TESTNET"),
+		TRON_GRID_BASE_URL_MAINNET: envOrDefault("TRON_GRID_BASE_URL_MAINNET", "https://api.trongrid.io"),
+		TRON_GRID_BASE_URL_TESTNET: envOrDefault("TRON_GRID_BASE_URL_TESTNET", "https://api.shasta.trongrid.io"),
+		TRX_HOT_WALLET_ADDRESS:     os.Getenv("TRX_HOT_WALLET_ADDRESS"),
+		TRX_HOT_WALLET_PRIVATE_KEY: os.Getenv("TRX_HOT_WALLET_PRIVATE_KEY"),
+		TRX_TOPUP_MODE:             topUpMode,
+		TRX_WALLET_ENCRYPTION_KEY:  os.Getenv("TRX_WALLET_ENCRYPTION_KEY"),
+		TRON_GRID_API_KEY:          os.Getenv("TRON_GRID_API_KEY"),
+		TRON_GRPC_MAINNET:          os.Getenv("TRON_GRPC_MAINNET"),
+		TRON_GRPC_TESTNET:          os.Getenv("TRON_GRPC_TESTNET"),
+
+		PRICE_SOURCES:                 envOrDefault("PRICE_SOURCES", "binance,coingecko,kraken"),
+		STATIC_PRICES:                 os.Getenv("STATIC_PRICES"),
+		PRICE_MAX_DEVIATION_PERCENT:   envFloatOrDefault("PRICE_MAX_DEVIATION_PERCENT", 3),
+		PRICE_CACHE_TTL_SECONDS:       envIntOrDefault("PRICE_CACHE_TTL_SECONDS", 30),
+		PRICE_TIMEOUT_SECONDS:         envIntOrDefault("PRICE_TIMEOUT_SECONDS", 5),
+		FX_SOURCES:                    envOrDefault("FX_SOURCES", "frankfurter"),
+		STATIC_FX_RATES:               os.Getenv("STATIC_FX_RATES"),
+		FRANKFURTER_API_URL:           envOrDefault("FRANKFURTER_API_URL", "https://api.frankfurter.app/latest"),
+		FX_CACHE_TTL_SECONDS:          envIntOrDefault("FX_CACHE_TTL_SECONDS", 3600),
+		QUOTE_LOCK_MINUTES:            envIntOrDefault("QUOTE_LOCK_MINUTES", 15),
+		PAYMENT_EXPIRY_MINUTES:        envIntOrDefault("PAYMENT_EXPIRY_MINUTES", 15),
+		LATE_PAYMENT_WINDOW_HOURS:     envIntOrDefault("LATE_PAYMENT_WINDOW_HOURS", 72),
+		PARTIAL_PAYMENT_GRACE_MINUTES: envIntOrDefault("PARTIAL_PAYMENT_GRACE_MINUTES", 1440),
+		REQUOTE_POLICY:                envOrDefault("REQUOTE_POLICY", "requote"),
+		MAX_REQUOTES:                  envIntOrDefault("MAX_REQUOTES", 3),
+
+		SWEEP_POLICY:   envOrDefault("SWEEP_POLICY", "immediate"),
+		SWEEP_SCHEDULE: envOrDefault("SWEEP_SCHEDULE", "@hourly"),
+
+		CHECKOUT_TEMPLATE:      envOrDefault("CHECKOUT_TEMPLATE", "static/checkout.html"),
+		CHECKOUT_BRAND_NAME:    envOrDefault("CHECKOUT_BRAND_NAME", envOrDefault("APP_NAME", "BytePayments")),
+		CHECKOUT_PRIMARY_COLOR: envOrDefault("CHECKOUT_PRIMARY_COLOR", "#2563eb"),
+		CHECKOUT_LOGO_URL:      os.Getenv("CHECKOUT_LOGO_URL"),
+		CHECKOUT_SUCCESS_URL:   os.Getenv("CHECKOUT_SUCCESS_URL"),
+		CHECKOUT_CANCEL_URL:    os.Getenv("CHECKOUT_CANCEL_URL"),
+
+		INVOICE_TEMPLATE: envOrDefault("INVOICE_TEMPLATE", "static/invoice.html"),
+
+		SUBSCRIPTION_REMINDER_DAYS: envIntOrDefault("SUBSCRIPTION_REMINDER_DAYS", 3),
+		RENEWAL_INVOICE_DAYS:       envIntOrDefault("RENEWAL_INVOICE_DAYS", 3),
+
+		REFUND_LINK_SECRET:    os.Getenv("REFUND_LINK_SECRET"),
+		REFUND_LINK_TTL_HOURS: envIntOrDefault("REFUND_LINK_TTL_HOURS", 72),
+
+		IDEMPOTENCY_TTL_HOURS: envIntOrDefault("IDEMPOTENCY_TTL_HOURS", 24),
 
 		EMAIL_SMTP_HOST: os.Getenv("EMAIL_SMTP_HOST"),
 		EMAIL_SMTP_PORT: port,
@@ -85,3 +174,26 @@ func NewConfig() {
 	}
 
 }
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func envIntOrDefault(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+func envFloatOrDefault(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
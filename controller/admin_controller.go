@@ -1,7 +1,6 @@
 package controller
 
 import (
-	"strconv"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -89,16 +88,18 @@ func AdminAuthMiddleware() fiber.Handler {
 
 // GetAllPaymentsHandler godoc
 // @Summary      Get all payments
-// @Description  Get all payments for admin management
+// @Description  Get all payments for admin management, optionally the ones with a merchant reference. Merchant servers use GET /api/v1/payments with a payments:read API key, which only lists the payments of that key (Admin only)
 // @Tags         admin
 // @Accept       json
 // @Produce      json
+// @Param        external_reference  query  string  false  "Merchant order reference"
+// @Param        api_key_id          query  string  false  "API key the reference belongs to, customer payments when empty"
 // @Success      200  {object}  dto.ApiResponse "Payments retrieved successfully"
 // @Failure      401  {object}  dto.ApiResponse "Unauthorized"
 // @Router       /api/v1/admin/payments [get]
 func GetAllPaymentsHandler(ctx *fiber.Ctx) error {
 	adminService := service.NewAdminManagementService(repository.NewPaymentRepository(database.DB))
-	payments, err := adminService.GetAllPayments()
+	payments, err := adminService.GetAllPayments(ctx.Query("api_key_id"), ctx.Query("external_reference"))
 	if err != nil {
 		return ctx.Status(500).JSON(dto.NewError("Failed to fetch payments", err))
 	}
@@ -194,12 +195,15 @@ func CreateCurrencyHandler(ctx *fiber.Ctx) error {
 	}
 
 	currency := &model.Currency{
-		Code:         req.Code,
-		Name:         req.Name,
-		Network:      req.Network,
-		IsToken:      req.IsToken,
-		ContractAddr: req.ContractAddr,
-		Enabled:      req.Enabled,
+		Code:           req.Code,
+		Name:           req.Name,
+		Network:        req.Network,
+		IsToken:        req.IsToken,
+		ContractAddr:   req.ContractAddr,
+		Enabled:        req.Enabled,
+		Confirmations:  req.Confirmations,
+		SweepThreshold: req.SweepThreshold,
+		ExpiryMinutes:  req.ExpiryMinutes,
 	}
 
 	currencyService := service.NewCurrenciesService(repository.NewCurrenciesRepository(database.DB))
@@ -254,6 +258,11 @@ func UpdateCurrencyHandler(ctx *fiber.Ctx) error {
 	existingCurrency.IsToken = req.IsToken
 	existingCurrency.ContractAddr = req.ContractAddr
 	existingCurrency.Enabled = req.Enabled
+	if req.Confirmations > 0 {
+		existingCurrency.Confirmations = req.Confirmations
+	}
+	existingCurrency.SweepThreshold = req.SweepThreshold
+	existingCurrency.ExpiryMinutes = req.ExpiryMinutes
 
 	err = currencyService.UpdateCurrency(existingCurrency)
 	if err != nil {
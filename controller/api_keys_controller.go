@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+	"gorm.io/gorm"
+)
+
+// apiKeyHeader carries the merchant API key on server-to-server requests.
+const apiKeyHeader = "X-API-Key"
+
+// APIKeyMiddleware requires a live API key carrying scope, it's stored in the context as "api_key".
+func APIKeyMiddleware(scope model.APIKeyScope) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if ctx.Get(apiKeyHeader) == "" {
+			return ctx.Status(401).JSON(dto.NewError(apiKeyHeader+" header missing", nil))
+		}
+		if _, err := authenticateAPIKey(ctx, scope); err != nil {
+			return ctx.Status(apiKeyErrorStatus(err)).JSON(dto.NewError("API key rejected", err))
+		}
+		return ctx.Next()
+	}
+}
+
+// authenticateAPIKey checks the API key of the request, if any, against scope. It returns nil
+// without an error when the request carries no key.
+func authenticateAPIKey(ctx *fiber.Ctx, scope model.APIKeyScope) (*model.APIKey, error) {
+	secret := ctx.Get(apiKeyHeader)
+	if secret == "" {
+		return nil, nil
+	}
+
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(database.DB))
+	key, err := apiKeyService.Authenticate(secret, scope)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Locals("api_key", key)
+	return key, nil
+}
+
+func apiKeyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidAPIKey):
+		return 401
+	case errors.Is(err, service.ErrAPIKeyMissingScope):
+		return 403
+	default:
+		return 500
+	}
+}
+
+// CreateAPIKeyHandler godoc
+// @Summary      Issue API key
+// @Description  Issue a merchant API key with the given scopes (payments:create, payments:read, refunds:write, subscriptions:read). The key is only returned once (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body  dto.CreateAPIKeyRequest  true  "API key"
+// @Success      201  {object}  dto.ApiResponse{data=dto.APIKeySecretResponse} "API key issued"
+// @Failure      400  {object}  dto.ApiResponse "Invalid request"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/api-keys [post]
+func CreateAPIKeyHandler(ctx *fiber.Ctx) error {
+	var req dto.CreateAPIKeyRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Invalid request body", err))
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Validation failed", err))
+	}
+
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(database.DB))
+	key, err := apiKeyService.CreateAPIKey(req)
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to issue API key", err))
+	}
+	return ctx.Status(201).JSON(dto.NewSuccess("API key issued", key))
+}
+
+// GetAPIKeysHandler godoc
+// @Summary      Get API keys
+// @Description  Get the merchant API keys with their prefix, scopes and last use, revoked ones included (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  dto.ApiResponse{data=[]model.APIKey} "API keys retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/api-keys [get]
+func GetAPIKeysHandler(ctx *fiber.Ctx) error {
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(database.DB))
+	keys, err := apiKeyService.GetAPIKeys()
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch API keys", err))
+	}
+	return ctx.JSON(dto.NewSuccess("API keys fetched successfully", keys))
+}
+
+// RotateAPIKeyHandler godoc
+// @Summary      Rotate API key
+// @Description  Replace the secret of an API key, keeping its name and scopes. The old secret stops working at once and the new one is only returned once (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "API key ID"
+// @Success      200  {object}  dto.ApiResponse{data=dto.APIKeySecretResponse} "API key rotated"
+// @Failure      400  {object}  dto.ApiResponse "API key can't be rotated"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Failure      404  {object}  dto.ApiResponse "API key not found"
+// @Router       /api/v1/admin/api-keys/{id}/rotate [post]
+func RotateAPIKeyHandler(ctx *fiber.Ctx) error {
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(database.DB))
+	key, err := apiKeyService.RotateAPIKey(ctx.Params("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ctx.Status(404).JSON(dto.NewError("API key not found", err))
+		}
+		return ctx.Status(400).JSON(dto.NewError("Failed to rotate API key", err))
+	}
+	return ctx.JSON(dto.NewSuccess("API key rotated", key))
+}
+
+// RevokeAPIKeyHandler godoc
+// @Summary      Revoke API key
+// @Description  Revoke an API key, requests made with it are rejected from then on (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "API key ID"
+// @Success      200  {object}  dto.ApiResponse{data=model.APIKey} "API key revoked"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Failure      404  {object}  dto.ApiResponse "API key not found"
+// @Router       /api/v1/admin/api-keys/{id}/revoke [post]
+func RevokeAPIKeyHandler(ctx *fiber.Ctx) error {
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(database.DB))
+	key, err := apiKeyService.RevokeAPIKey(ctx.Params("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ctx.Status(404).JSON(dto.NewError("API key not found", err))
+		}
+		return ctx.Status(500).JSON(dto.NewError("Failed to revoke API key", err))
+	}
+	return ctx.JSON(dto.NewSuccess("API key revoked", key))
+}
@@ -0,0 +1,36 @@
+package controller
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+	"gorm.io/gorm"
+)
+
+// CheckoutHandler godoc
+// @Summary      Hosted checkout page
+// @Description  Server-rendered checkout page of a payment with its amount, QR code, countdown and live status
+// @Tags         payments
+// @Produce      html
+// @Param        payment_id  path  string  true  "Payment ID"
+// @Success      200  {string}  string  "Checkout page"
+// @Failure      404  {string}  string  "Payment not found"
+// @Router       /checkout/{payment_id} [get]
+func CheckoutHandler(ctx *fiber.Ctx) error {
+	checkoutService := service.NewCheckoutService(repository.NewPaymentRepository(database.DB))
+	page, err := checkoutService.RenderCheckout(ctx.Params("payment_id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ctx.Status(404).SendString("Payment not found")
+		}
+		log.Printf("Failed to render checkout page: %v", err)
+		return ctx.Status(500).SendString("Checkout is unavailable, please try again later")
+	}
+
+	ctx.Type("html", "utf-8")
+	return ctx.Send(page)
+}
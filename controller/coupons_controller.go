@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+	"gorm.io/gorm"
+)
+
+// CreateCouponHandler godoc
+// @Summary      Create coupon
+// @Description  Create a discount code, percent or fixed off, optionally limited to a validity window, a number of redemptions, redemptions per email and certain plans. Customers redeem it with coupon_code when creating a payment (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body  dto.CreateCouponRequest  true  "Coupon"
+// @Success      201  {object}  dto.ApiResponse{data=model.Coupon} "Coupon created"
+// @Failure      400  {object}  dto.ApiResponse "Invalid request"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/coupons [post]
+func CreateCouponHandler(ctx *fiber.Ctx) error {
+	var req dto.CreateCouponRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Invalid request body", err))
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Validation failed", err))
+	}
+
+	couponService := service.NewCouponService(repository.NewCouponRepository(database.DB))
+	coupon, err := couponService.CreateCoupon(req)
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to create coupon", err))
+	}
+	return ctx.Status(201).JSON(dto.NewSuccess("Coupon created", coupon))
+}
+
+// GetCouponsHandler godoc
+// @Summary      Get coupons
+// @Description  Get the coupons with how often they were redeemed and how many redemptions they have left (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  dto.ApiResponse{data=[]dto.CouponResponse} "Coupons retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/coupons [get]
+func GetCouponsHandler(ctx *fiber.Ctx) error {
+	couponService := service.NewCouponService(repository.NewCouponRepository(database.DB))
+	coupons, err := couponService.GetCoupons()
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch coupons", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Coupons fetched successfully", coupons))
+}
+
+// UpdateCouponHandler godoc
+// @Summary      Update coupon
+// @Description  Change the terms of a coupon or disable it. Payments already made with it keep their discount (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path  string                   true  "Coupon ID"
+// @Param        request  body  dto.UpdateCouponRequest  true  "Coupon"
+// @Success      200  {object}  dto.ApiResponse{data=model.Coupon} "Coupon updated"
+// @Failure      400  {object}  dto.ApiResponse "Invalid request"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Failure      404  {object}  dto.ApiResponse "Coupon not found"
+// @Router       /api/v1/admin/coupons/{id} [put]
+func UpdateCouponHandler(ctx *fiber.Ctx) error {
+	var req dto.UpdateCouponRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Invalid request body", err))
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Validation failed", err))
+	}
+
+	couponService := service.NewCouponService(repository.NewCouponRepository(database.DB))
+	coupon, err := couponService.UpdateCoupon(ctx.Params("id"), req)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ctx.Status(404).JSON(dto.NewError("Coupon not found", err))
+		}
+		return ctx.Status(400).JSON(dto.NewError("Failed to update coupon", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Coupon updated", coupon))
+}
+
+// DeleteCouponHandler godoc
+// @Summary      Delete coupon
+// @Description  Delete a coupon that was never redeemed, redeemed coupons can only be disabled (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Coupon ID"
+// @Success      200  {object}  dto.ApiResponse "Coupon deleted"
+// @Failure      400  {object}  dto.ApiResponse "Coupon was redeemed"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/coupons/{id} [delete]
+func DeleteCouponHandler(ctx *fiber.Ctx) error {
+	couponService := service.NewCouponService(repository.NewCouponRepository(database.DB))
+	if err := couponService.DeleteCoupon(ctx.Params("id")); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to delete coupon", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Coupon deleted", nil))
+}
+
+// GetCouponRedemptionsHandler godoc
+// @Summary      Coupon redemption report
+// @Description  Get the payments a coupon was redeemed on with their original price and discount, and the discount and revenue totals of the completed ones per fiat currency (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Coupon ID"
+// @Success      200  {object}  dto.ApiResponse{data=dto.CouponRedemptionReport} "Redemptions retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Failure      404  {object}  dto.ApiResponse "Coupon not found"
+// @Router       /api/v1/admin/coupons/{id}/redemptions [get]
+func GetCouponRedemptionsHandler(ctx *fiber.Ctx) error {
+	couponService := service.NewCouponService(repository.NewCouponRepository(database.DB))
+	report, err := couponService.GetRedemptionReport(ctx.Params("id"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ctx.Status(404).JSON(dto.NewError("Coupon not found", err))
+		}
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch coupon redemptions", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Coupon redemptions fetched successfully", report))
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+)
+
+// GetDepositsHandler godoc
+// @Summary      Get deposits
+// @Description  Get the on-chain transfers credited to payments, optionally for a single payment (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        payment_id  query  string  false  "Payment ID"
+// @Success      200  {object}  dto.ApiResponse{data=[]model.Deposit} "Deposits retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/deposits [get]
+func GetDepositsHandler(ctx *fiber.Ctx) error {
+	depositService := service.NewDepositService(repository.NewDepositRepository(database.DB))
+	deposits, err := depositService.GetDeposits(ctx.Query("payment_id"))
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch deposits", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Deposits fetched successfully", deposits))
+}
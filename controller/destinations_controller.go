@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+)
+
+// CreateDestinationHandler godoc
+// @Summary      Create destination wallet
+// @Description  Add a wallet swept funds can be sent to, like cold storage or an operations wallet (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body  dto.CreateDestinationRequest  true  "Destination wallet data"
+// @Success      201  {object}  dto.ApiResponse{data=model.DestinationWallet} "Destination wallet created successfully"
+// @Failure      400  {object}  dto.ApiResponse "Invalid request"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/destinations [post]
+func CreateDestinationHandler(ctx *fiber.Ctx) error {
+	var req dto.CreateDestinationRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Invalid request body", err))
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Validation failed", err))
+	}
+
+	destinationService := service.NewDestinationService(repository.NewDestinationRepository(database.DB))
+	destination, err := destinationService.CreateDestination(req)
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to create destination wallet", err))
+	}
+	return ctx.Status(201).JSON(dto.NewSuccess("Destination wallet created successfully", destination))
+}
+
+// GetDestinationsHandler godoc
+// @Summary      Get destination wallets
+// @Description  Get all wallets swept funds can be sent to (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  dto.ApiResponse{data=[]model.DestinationWallet} "Destination wallets retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/destinations [get]
+func GetDestinationsHandler(ctx *fiber.Ctx) error {
+	destinationService := service.NewDestinationService(repository.NewDestinationRepository(database.DB))
+	destinations, err := destinationService.GetDestinations()
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch destination wallets", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Destination wallets fetched successfully", destinations))
+}
+
+// UpdateDestinationHandler godoc
+// @Summary      Update destination wallet
+// @Description  Update the name, address or enabled flag of a destination wallet. Rules sending to a disabled wallet are skipped (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path  string                        true  "Destination wallet ID"
+// @Param        request  body  dto.UpdateDestinationRequest  true  "Destination wallet data"
+// @Success      200  {object}  dto.ApiResponse{data=model.DestinationWallet} "Destination wallet updated successfully"
+// @Failure      400  {object}  dto.ApiResponse "Invalid request"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/destinations/{id} [put]
+func UpdateDestinationHandler(ctx *fiber.Ctx) error {
+	destinationID := ctx.Params("id")
+
+	var req dto.UpdateDestinationRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Invalid request body", err))
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Validation failed", err))
+	}
+
+	destinationService := service.NewDestinationService(repository.NewDestinationRepository(database.DB))
+	destination, err := destinationService.UpdateDestination(destinationID, req)
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to update destination wallet", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Destination wallet updated successfully", destination))
+}
+
+// DeleteDestinationHandler godoc
+// @Summary      Delete destination wallet
+// @Description  Delete a destination wallet that no sweep rule uses (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Destination wallet ID"
+// @Success      200  {object}  dto.ApiResponse "Destination wallet deleted successfully"
+// @Failure      400  {object}  dto.ApiResponse "Destination wallet is in use"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/destinations/{id} [delete]
+func DeleteDestinationHandler(ctx *fiber.Ctx) error {
+	destinationID := ctx.Params("id")
+
+	destinationService := service.NewDestinationService(repository.NewDestinationRepository(database.DB))
+	if err := destinationService.DeleteDestination(destinationID); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to delete destination wallet", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Destination wallet deleted successfully", nil))
+}
+
+// CreateSweepRuleHandler godoc
+// @Summary      Create sweep rule
+// @Description  Add a rule splitting the swept funds of a currency and/or plan across destination wallets by percentage. The percentages must add up to 100 (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body  dto.CreateSweepRuleRequest  true  "Sweep rule data"
+// @Success      201  {object}  dto.ApiResponse{data=model.SweepRule} "Sweep rule created successfully"
+// @Failure      400  {object}  dto.ApiResponse "Invalid request"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/sweep-rules [post]
+func CreateSweepRuleHandler(ctx *fiber.Ctx) error {
+	var req dto.CreateSweepRuleRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Invalid request body", err))
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Validation failed", err))
+	}
+
+	destinationService := service.NewDestinationService(repository.NewDestinationRepository(database.DB))
+	rule, err := destinationService.CreateRule(req)
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to create sweep rule", err))
+	}
+	return ctx.Status(201).JSON(dto.NewSuccess("Sweep rule created successfully", rule))
+}
+
+// GetSweepRulesHandler godoc
+// @Summary      Get sweep rules
+// @Description  Get all sweep rules with their splits (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  dto.ApiResponse{data=[]model.SweepRule} "Sweep rules retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/sweep-rules [get]
+func GetSweepRulesHandler(ctx *fiber.Ctx) error {
+	destinationService := service.NewDestinationService(repository.NewDestinationRepository(database.DB))
+	rules, err := destinationService.GetRules()
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch sweep rules", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Sweep rules fetched successfully", rules))
+}
+
+// UpdateSweepRuleHandler godoc
+// @Summary      Update sweep rule
+// @Description  Update a sweep rule, its splits are replaced with the ones sent. Past sweeps keep the split they applied (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path  string                      true  "Sweep rule ID"
+// @Param        request  body  dto.UpdateSweepRuleRequest  true  "Sweep rule data"
+// @Success      200  {object}  dto.ApiResponse{data=model.SweepRule} "Sweep rule updated successfully"
+// @Failure      400  {object}  dto.ApiResponse "Invalid request"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/sweep-rules/{id} [put]
+func UpdateSweepRuleHandler(ctx *fiber.Ctx) error {
+	ruleID := ctx.Params("id")
+
+	var req dto.UpdateSweepRuleRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Invalid request body", err))
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Validation failed", err))
+	}
+
+	destinationService := service.NewDestinationService(repository.NewDestinationRepository(database.DB))
+	rule, err := destinationService.UpdateRule(ruleID, req)
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to update sweep rule", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Sweep rule updated successfully", rule))
+}
+
+// DeleteSweepRuleHandler godoc
+// @Summary      Delete sweep rule
+// @Description  Delete a sweep rule and its splits (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Sweep rule ID"
+// @Success      200  {object}  dto.ApiResponse "Sweep rule deleted successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/sweep-rules/{id} [delete]
+func DeleteSweepRuleHandler(ctx *fiber.Ctx) error {
+	ruleID := ctx.Params("id")
+
+	destinationService := service.NewDestinationService(repository.NewDestinationRepository(database.DB))
+	if err := destinationService.DeleteRule(ruleID); err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to delete sweep rule", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Sweep rule deleted successfully", nil))
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+)
+
+const (
+	idempotencyKeyHeader      = "Idempotency-Key"
+	idempotencyReplayedHeader = "Idempotent-Replayed"
+	idempotencyKeyMaxLen      = 255
+)
+
+// errIdempotencyNoCaller rejects idempotency keys of requests there's nothing to scope them to.
+var errIdempotencyNoCaller = errors.New("idempotency keys need an API key, a verification token or a resource to act on")
+
+// IdempotencyResource names the resource a public request acts on, idempotency keys of callers
+// without an API key or verification token are scoped to it. It returns "" when the request
+// doesn't name one.
+type IdempotencyResource func(ctx *fiber.Ctx) string
+
+// IdempotencyByEmail scopes keys to the email in the body, for the verification endpoints.
+func IdempotencyByEmail(ctx *fiber.Ctx) string {
+	var body struct {
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(ctx.Body(), &body); err != nil || body.Email == "" {
+		return ""
+	}
+	return "verification:" + body.Email
+}
+
+// IdempotencyByPayment scopes keys to the payment in the route.
+func IdempotencyByPayment(ctx *fiber.Ctx) string {
+	return "payment:" + ctx.Params("id")
+}
+
+// IdempotencyByRefundLink scopes keys to the signed refund link in the body, only the customer
+// the link was emailed to shares the scope. The handler still checks the signature.
+func IdempotencyByRefundLink(ctx *fiber.Ctx) string {
+	var body struct {
+		Expires   int64  `json:"expires"`
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal(ctx.Body(), &body); err != nil || body.Signature == "" {
+		return ""
+	}
+	return fmt.Sprintf("refund:%s:%d:%s", ctx.Params("id"), body.Expires, body.Signature)
+}
+
+// IdempotencyMiddleware makes POST, PUT, PATCH and DELETE requests carrying an Idempotency-Key
+// header safe to retry. The first response is stored for IDEMPOTENCY_TTL_HOURS and replayed to
+// retries with the same body, a different body under the same key is a conflict. Keys are scoped
+// to the caller or to resource, see idempotencyScope. Routes with a resource in their path need
+// the middleware on the route itself, group middleware doesn't see route params.
+func IdempotencyMiddleware(resource IdempotencyResource) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		key := ctx.Get(idempotencyKeyHeader)
+		if key == "" {
+			return ctx.Next()
+		}
+		switch ctx.Method() {
+		case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+		default:
+			return ctx.Next()
+		}
+		if len(key) > idempotencyKeyMaxLen {
+			return ctx.Status(400).JSON(dto.NewError("Invalid "+idempotencyKeyHeader, errors.New("idempotency key is longer than 255 characters")))
+		}
+
+		scope, err := idempotencyScope(ctx, resource)
+		if err != nil {
+			if errors.Is(err, errIdempotencyNoCaller) {
+				return ctx.Status(400).JSON(dto.NewError("Invalid "+idempotencyKeyHeader, err))
+			}
+			return ctx.Status(apiKeyErrorStatus(err)).JSON(dto.NewError("API key rejected", err))
+		}
+
+		idempotencyService := service.NewIdempotencyService(repository.NewIdempotencyRepository(database.DB))
+		record, replay, err := idempotencyService.Begin(scope, key, ctx.Method(), ctx.Path(), ctx.Body())
+		if err != nil {
+			if errors.Is(err, service.ErrIdempotencyKeyReused) || errors.Is(err, service.ErrIdempotencyKeyInFlight) {
+				return ctx.Status(409).JSON(dto.NewError("Idempotency key conflict", err))
+			}
+			return ctx.Status(500).JSON(dto.NewError("Failed to check idempotency key", err))
+		}
+
+		if replay {
+			ctx.Set(idempotencyReplayedHeader, "true")
+			ctx.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			return ctx.Status(record.StatusCode).SendString(record.Response)
+		}
+
+		if err := ctx.Next(); err != nil {
+			idempotencyService.Release(record)
+			return err
+		}
+		idempotencyService.Complete(record, ctx.Response().StatusCode(), ctx.Response().Body())
+		return nil
+	}
+}
+
+// idempotencyScope identifies the caller an idempotency key belongs to. Merchants are scoped by
+// the ID of their API key, so rotating it keeps their keys, and customers by the email of the
+// verification token in the body. Other callers are scoped by the resource of the route, when it
+// has one.
+func idempotencyScope(ctx *fiber.Ctx, resource IdempotencyResource) (string, error) {
+	if secret := ctx.Get(apiKeyHeader); secret != "" {
+		apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(database.DB))
+		key, err := apiKeyService.Identify(secret)
+		if err != nil {
+			return "", err
+		}
+		return "api_key:" + key.ID, nil
+	}
+
+	var body struct {
+		VerificationToken string `json:"verification_token"`
+	}
+	if err := json.Unmarshal(ctx.Body(), &body); err == nil && body.VerificationToken != "" {
+		if email, ok := service.NewVerificationService().VerifiedEmail(body.VerificationToken); ok {
+			return "email:" + email, nil
+		}
+	}
+
+	if resource != nil {
+		if scope := resource(ctx); scope != "" {
+			return scope, nil
+		}
+	}
+	return "", errIdempotencyNoCaller
+}
@@ -0,0 +1,122 @@
+package controller
+
+import (
+	"errors"
+	"log"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+	"gorm.io/gorm"
+)
+
+// CreateInvoiceHandler godoc
+// @Summary      Create invoice
+// @Description  Bill an arbitrary amount in any fiat currency, given directly or as line items, instead of a plan. The invoice gets a payment on the customer's deposit wallet and is emailed to them with its PDF. Admins and merchant servers with a payments:create API key (POST /api/v1/invoices) can create invoices
+// @Tags         invoices
+// @Accept       json
+// @Produce      json
+// @Param        request  body  dto.CreateInvoiceRequest  true  "Invoice"
+// @Param        X-API-Key header string false "Merchant API key with the payments:create scope"
+// @Param        Idempotency-Key header string false "Retries with the same key and body get the original response"
+// @Success      201  {object}  dto.ApiResponse{data=dto.InvoiceResponse} "Invoice created"
+// @Failure      400  {object}  dto.ApiResponse "Invalid request"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Failure      403  {object}  dto.ApiResponse "API key lacks the payments:create scope"
+// @Failure      409  {object}  dto.ApiResponse "Idempotency key reused with a different body or still in progress"
+// @Failure      422  {object}  dto.ApiResponse "Invoice creation failed"
+// @Router       /api/v1/invoices [post]
+// @Router       /api/v1/admin/invoices [post]
+func CreateInvoiceHandler(ctx *fiber.Ctx) error {
+	var req dto.CreateInvoiceRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Invalid request body", err))
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Validation failed", err))
+	}
+
+	// set by APIKeyMiddleware, admins create invoices without a key
+	apiKey, _ := ctx.Locals("api_key").(*model.APIKey)
+
+	paymentService := service.NewPaymentService(repository.NewPaymentRepository(database.DB))
+	invoice, err := paymentService.CreateInvoice(req, apiKey)
+	if err != nil {
+		return ctx.Status(422).JSON(dto.NewError("Invoice creation failed", err))
+	}
+	return ctx.Status(201).JSON(dto.NewSuccess("Invoice created", invoice))
+}
+
+// GetInvoicesHandler godoc
+// @Summary      Get invoices
+// @Description  Get invoices with their line items, optionally for one customer email (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        email  query  string  false  "Customer email"
+// @Success      200  {object}  dto.ApiResponse{data=[]model.Invoice} "Invoices retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/invoices [get]
+func GetInvoicesHandler(ctx *fiber.Ctx) error {
+	invoiceService := service.NewInvoiceService(repository.NewInvoiceRepository(database.DB))
+	invoices, err := invoiceService.GetInvoices(ctx.Query("email"))
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch invoices", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Invoices fetched successfully", invoices))
+}
+
+// InvoiceHandler godoc
+// @Summary      Invoice page
+// @Description  Printable invoice with its line items, payment status and a link to pay it
+// @Tags         invoices
+// @Produce      html
+// @Param        id  path  string  true  "Invoice ID"
+// @Success      200  {string}  string  "Invoice page"
+// @Failure      404  {string}  string  "Invoice not found"
+// @Router       /invoices/{id} [get]
+func InvoiceHandler(ctx *fiber.Ctx) error {
+	invoiceService := service.NewInvoiceService(repository.NewInvoiceRepository(database.DB))
+	page, err := invoiceService.RenderInvoiceHTML(ctx.Params("id"))
+	if err != nil {
+		return invoiceRenderError(ctx, err)
+	}
+
+	ctx.Type("html", "utf-8")
+	return ctx.Send(page)
+}
+
+// InvoicePDFHandler godoc
+// @Summary      Invoice PDF
+// @Description  The invoice as a PDF, the same document emailed to the customer
+// @Tags         invoices
+// @Produce      application/pdf
+// @Param        id  path  string  true  "Invoice ID"
+// @Success      200  {file}  file  "Invoice PDF"
+// @Failure      404  {string}  string  "Invoice not found"
+// @Router       /invoices/{id}/pdf [get]
+func InvoicePDFHandler(ctx *fiber.Ctx) error {
+	invoiceService := service.NewInvoiceService(repository.NewInvoiceRepository(database.DB))
+	pdf, err := invoiceService.RenderInvoicePDF(ctx.Params("id"))
+	if err != nil {
+		return invoiceRenderError(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, "application/pdf")
+	ctx.Set(fiber.HeaderContentDisposition, `inline; filename="invoice-`+ctx.Params("id")+`.pdf"`)
+	return ctx.Send(pdf)
+}
+
+func invoiceRenderError(ctx *fiber.Ctx, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ctx.Status(404).SendString("Invoice not found")
+	}
+	log.Printf("Failed to render invoice: %v", err)
+	return ctx.Status(500).SendString("Invoice is unavailable, please try again later")
+}
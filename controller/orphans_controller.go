@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+)
+
+// GetOrphansHandler godoc
+// @Summary      Get orphaned deposits
+// @Description  Get the transfers found on deposit wallets no payment was waiting on, optionally by status (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        status  query  string  false  "Status (open, attached, refunded, swept)"
+// @Success      200  {object}  dto.ApiResponse{data=[]model.OrphanDeposit} "Orphaned deposits retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/orphans [get]
+func GetOrphansHandler(ctx *fiber.Ctx) error {
+	orphanService := service.NewOrphanService(repository.NewOrphanRepository(database.DB))
+	orphans, err := orphanService.GetOrphans(ctx.Query("status"))
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch orphaned deposits", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Orphaned deposits fetched successfully", orphans))
+}
+
+// ResolveOrphanHandler godoc
+// @Summary      Resolve orphaned deposit
+// @Description  Attach an orphaned deposit to a payment, refund it or sweep it as unallocated funds (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path  string                    true  "Orphaned deposit ID"
+// @Param        request  body  dto.ResolveOrphanRequest  true  "Outcome"
+// @Success      200  {object}  dto.ApiResponse{data=model.OrphanDeposit} "Orphaned deposit resolved"
+// @Failure      400  {object}  dto.ApiResponse "Orphaned deposit can't be resolved"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/orphans/{id}/resolve [post]
+func ResolveOrphanHandler(ctx *fiber.Ctx) error {
+	var req dto.ResolveOrphanRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Invalid request body", err))
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Validation failed", err))
+	}
+
+	orphanService := service.NewOrphanService(repository.NewOrphanRepository(database.DB))
+	orphan, err := orphanService.ResolveOrphan(ctx.Params("id"), req)
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to resolve orphaned deposit", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Orphaned deposit resolved", orphan))
+}
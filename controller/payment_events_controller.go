@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/internal/pubsub"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+	"github.com/valyala/fasthttp"
+	"gorm.io/gorm"
+)
+
+// eventsKeepAlive is how often an idle stream is pinged, so proxies don't close it.
+const eventsKeepAlive = 15 * time.Second
+
+// PaymentEventsHandler godoc
+// @Summary      Stream payment status
+// @Description  Streams the status, confirmations and amounts of a payment as they change, over Server-Sent Events or, when the request is a WebSocket upgrade, over a WebSocket. The current snapshot is sent first and the stream ends once the payment is completed or cancelled.
+// @Tags         payments
+// @Produce      text/event-stream
+// @Param        id path string true "Payment ID"
+// @Success      200  {object}  dto.PaymentEvent "Stream of payment snapshots"
+// @Failure      404  {object}  dto.ApiResponse "Payment not found"
+// @Router       /api/v1/payments/{id}/events [get]
+func PaymentEventsHandler(ctx *fiber.Ctx) error {
+	id := ctx.Params("id")
+	paymentService := service.NewPaymentService(repository.NewPaymentRepository(database.DB))
+
+	if _, err := paymentService.GetPaymentEvent(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ctx.Status(404).JSON(dto.NewError("Payment not found", err))
+		}
+		return ctx.Status(500).JSON(dto.NewError("Failed to get payment", err))
+	}
+
+	if websocket.IsWebSocketUpgrade(ctx) {
+		return paymentEventsSocket(ctx)
+	}
+
+	ctx.Set("Content-Type", "text/event-stream")
+	ctx.Set("Cache-Control", "no-cache")
+	ctx.Set("Connection", "keep-alive")
+	ctx.Set("X-Accel-Buffering", "no")
+
+	ctx.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		streamPaymentEvents(paymentService, id, func(msg []byte) error {
+			if msg == nil {
+				fmt.Fprint(w, ": keepalive\n\n")
+			} else {
+				fmt.Fprintf(w, "data: %s\n\n", msg)
+			}
+			return w.Flush()
+		})
+	}))
+	return nil
+}
+
+var paymentEventsSocket = websocket.New(func(conn *websocket.Conn) {
+	id := conn.Params("id")
+	paymentService := service.NewPaymentService(repository.NewPaymentRepository(database.DB))
+
+	// the client only ever closes the socket, reading is how the close is noticed
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	streamPaymentEvents(paymentService, id, func(msg []byte) error {
+		select {
+		case <-closed:
+			return errors.New("websocket closed by the client")
+		default:
+		}
+		conn.SetWriteDeadline(time.Now().Add(eventsKeepAlive))
+		if msg == nil {
+			return conn.WriteMessage(websocket.PingMessage, nil)
+		}
+		return conn.WriteMessage(websocket.TextMessage, msg)
+	})
+	conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+})
+
+// streamPaymentEvents sends the payment's current snapshot followed by every change published for
+// it, until send fails or the payment reaches a final status. A nil message asks send for a
+// keepalive.
+func streamPaymentEvents(paymentService service.PaymentService, id string, send func(msg []byte) error) {
+	updates, unsubscribe := pubsub.Payments.Subscribe(id)
+	defer unsubscribe()
+
+	// read after subscribing, so no change slips between the snapshot and the stream
+	event, err := paymentService.GetPaymentEvent(id)
+	if err != nil {
+		log.Printf("Failed to get status event for payment %s: %v", id, err)
+		return
+	}
+	last, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode status event for payment %s: %v", id, err)
+		return
+	}
+	if err := send(last); err != nil || streamEnded(event.Status) {
+		return
+	}
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case msg, ok := <-updates:
+			if !ok {
+				return
+			}
+			if bytes.Equal(msg, last) {
+				continue
+			}
+			last = msg
+			if err := send(msg); err != nil {
+				return
+			}
+
+			var update dto.PaymentEvent
+			if err := json.Unmarshal(msg, &update); err == nil && streamEnded(update.Status) {
+				return
+			}
+		case <-keepAlive.C:
+			if err := send(nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// streamEnded reports whether a payment in status can't change anymore. Expired payments stay
+// streamed, a late deposit can still move them to late_payment and on to completed.
+func streamEnded(status model.PaymentStatus) bool {
+	return status == model.Completed || status == model.Cancelled
+}
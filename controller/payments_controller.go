@@ -7,19 +7,25 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/thebytearray/BytePayments/dto"
 	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/model"
 	"github.com/thebytearray/BytePayments/repository"
 	"github.com/thebytearray/BytePayments/service"
 )
 
 // CreatePaymentHandler godoc
 // @Summary      Create a new payment
-// @Description  Creates a new payment with the specified plan and currency, generates a TRX wallet address and QR code for payment
+// @Description  Creates a new payment with the specified plan and currency, generates a TRX wallet address and QR code for payment. The plan is priced in fiat_currency when given and coupon_code takes its discount off. Customers verify their email first, merchant servers send an API key with the payments:create scope instead
 // @Tags         payments
 // @Accept       json
 // @Produce      json
 // @Param        request body dto.CreatePaymentRequest true "Payment creation request"
+// @Param        X-API-Key header string false "Merchant API key, replaces the verification token"
+// @Param        Idempotency-Key header string false "Retries with the same key and body get the original response"
 // @Success      200  {object}  dto.ApiResponse{data=dto.PaymentResponse} "Payment created successfully"
 // @Failure      400  {object}  dto.ApiResponse "Invalid request body or validation error"
+// @Failure      401  {object}  dto.ApiResponse "Invalid API key"
+// @Failure      403  {object}  dto.ApiResponse "API key lacks the payments:create scope"
+// @Failure      409  {object}  dto.ApiResponse "Idempotency key reused with a different body or still in progress"
 // @Failure      422  {object}  dto.ApiResponse "Payment creation failed"
 // @Router       /api/v1/payments/create [post]
 func CreatePaymentHandler(ctx *fiber.Ctx) error {
@@ -38,11 +44,17 @@ func CreatePaymentHandler(ctx *fiber.Ctx) error {
 
 	}
 
+	//server-to-server calls authenticate with an API key instead of the email verification
+	apiKey, err := authenticateAPIKey(ctx, model.ScopePaymentsCreate)
+	if err != nil {
+		return ctx.Status(apiKeyErrorStatus(err)).JSON(dto.NewError("API key rejected", err))
+	}
+
 	//payment service
 	//
 	paymentService := service.NewPaymentService(repository.NewPaymentRepository(database.DB))
 
-	resp, err := paymentService.CreatePayment(body)
+	resp, err := paymentService.CreatePayment(body, apiKey)
 
 	if err != nil {
 		return ctx.Status(http.StatusExpectationFailed).JSON(dto.NewError("Payment creation failed", err))
@@ -58,6 +70,7 @@ func CreatePaymentHandler(ctx *fiber.Ctx) error {
 // @Accept       json
 // @Produce      json
 // @Param        id path string true "Payment ID" example("payment_123456")
+// @Param        Idempotency-Key header string false "Retries with the same key get the original response"
 // @Success      200  {object}  dto.ApiResponse "Payment cancelled successfully"
 // @Failure      404  {object}  dto.ApiResponse "Payment not found"
 // @Router       /api/v1/payments/{id}/cancel [patch]
@@ -86,3 +99,71 @@ func GetPaymentStatusHandler(ctx *fiber.Ctx) error {
 	resp := paymentService.CheckPaymentStatusById(id)
 	return ctx.JSON(resp)
 }
+
+// GetMerchantPaymentsHandler godoc
+// @Summary      Get merchant payments
+// @Description  Get the payments created with the API key of the request, newest first. Requires the payments:read scope
+// @Tags         payments
+// @Accept       json
+// @Produce      json
+// @Param        X-API-Key header string true "Merchant API key with the payments:read scope"
+// @Param        external_reference  query  string  false  "Merchant order reference"
+// @Success      200  {object}  dto.ApiResponse{data=[]model.Payment} "Payments retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Invalid API key"
+// @Failure      403  {object}  dto.ApiResponse "API key is missing the payments:read scope"
+// @Router       /api/v1/payments [get]
+func GetMerchantPaymentsHandler(ctx *fiber.Ctx) error {
+	// set by APIKeyMiddleware
+	apiKey := ctx.Locals("api_key").(*model.APIKey)
+
+	paymentService := service.NewPaymentService(repository.NewPaymentRepository(database.DB))
+	payments, err := paymentService.GetMerchantPayments(apiKey, ctx.Query("external_reference"))
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch payments", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Payments fetched successfully", payments))
+}
+
+// GetLatePaymentsHandler godoc
+// @Summary      Get late payments
+// @Description  Get the expired payments that received a deposit afterwards and wait for review (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  dto.ApiResponse{data=[]model.Payment} "Late payments retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/payments/late [get]
+func GetLatePaymentsHandler(ctx *fiber.Ctx) error {
+	paymentService := service.NewPaymentService(repository.NewPaymentRepository(database.DB))
+	payments, err := paymentService.GetLatePayments()
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch late payments", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Late payments fetched successfully", payments))
+}
+
+// ResolveLatePaymentHandler godoc
+// @Summary      Resolve late payment
+// @Description  Accept a late payment, completing it with its confirmed deposits, or reject it and expire it again (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path  string                        true  "Payment ID"
+// @Param        request  body  dto.ResolveLatePaymentRequest  true  "Review decision"
+// @Success      200  {object}  dto.ApiResponse{data=model.Payment} "Late payment resolved"
+// @Failure      400  {object}  dto.ApiResponse "Payment can't be resolved"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/payments/{id}/late [post]
+func ResolveLatePaymentHandler(ctx *fiber.Ctx) error {
+	var req dto.ResolveLatePaymentRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Invalid request body", err))
+	}
+
+	paymentService := service.NewPaymentService(repository.NewPaymentRepository(database.DB))
+	payment, err := paymentService.ResolveLatePayment(ctx.Params("id"), req.Accept)
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to resolve late payment", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Late payment resolved", payment))
+}
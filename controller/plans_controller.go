@@ -35,7 +35,7 @@ func GetPlansHandler(ctx *fiber.Ctx) error {
 
 // CreatePlanHandler godoc
 // @Summary      Create a new plan
-// @Description  Create a new subscription plan (Admin only)
+// @Description  Create a new subscription plan, priced in USD or in any ISO-4217 currency with optional fixed prices in others (Admin only)
 // @Tags         admin
 // @Accept       json
 // @Produce      json
@@ -59,12 +59,16 @@ func CreatePlanHandler(ctx *fiber.Ctx) error {
 
 	plansService := service.NewPlansService(repository.NewPlansRepository(database.DB))
 
+	planID := util.GenerateUniqueID()
 	plan := &model.Plan{
-		ID:           util.GenerateUniqueID(),
-		Name:         req.Name,
-		Description:  req.Description,
-		PriceUSD:     req.PriceUSD,
-		DurationDays: req.DurationDays,
+		ID:            planID,
+		Name:          req.Name,
+		Description:   req.Description,
+		PriceUSD:      req.PriceUSD,
+		DurationDays:  req.DurationDays,
+		ExpiryMinutes: req.ExpiryMinutes,
+		Currency:      req.Currency,
+		Prices:        planPrices(planID, req.Prices),
 	}
 
 	err := plansService.CreatePlan(plan)
@@ -77,7 +81,7 @@ func CreatePlanHandler(ctx *fiber.Ctx) error {
 
 // UpdatePlanHandler godoc
 // @Summary      Update a plan
-// @Description  Update an existing subscription plan (Admin only)
+// @Description  Update an existing subscription plan, its fixed prices are replaced by the ones given (Admin only)
 // @Tags         admin
 // @Accept       json
 // @Produce      json
@@ -118,6 +122,9 @@ func UpdatePlanHandler(ctx *fiber.Ctx) error {
 	existingPlan.Description = req.Description
 	existingPlan.PriceUSD = req.PriceUSD
 	existingPlan.DurationDays = req.DurationDays
+	existingPlan.ExpiryMinutes = req.ExpiryMinutes
+	existingPlan.Currency = req.Currency
+	existingPlan.Prices = planPrices(existingPlan.ID, req.Prices)
 
 	err = plansService.UpdatePlan(existingPlan)
 	if err != nil {
@@ -159,3 +166,16 @@ func DeletePlanHandler(ctx *fiber.Ctx) error {
 
 	return ctx.JSON(dto.NewSuccess("Plan deleted successfully", nil))
 }
+
+func planPrices(planID string, prices []dto.PlanPriceRequest) []model.PlanPrice {
+	var planPrices []model.PlanPrice
+	for _, price := range prices {
+		planPrices = append(planPrices, model.PlanPrice{
+			ID:       util.GenerateUniqueID(),
+			PlanID:   planID,
+			Currency: price.Currency,
+			Amount:   price.Amount,
+		})
+	}
+	return planPrices
+}
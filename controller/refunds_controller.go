@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+	"gorm.io/gorm"
+)
+
+// GetRefundsHandler godoc
+// @Summary      Get refunds
+// @Description  Get refunds, optionally by status or payment (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        status      query  string  false  "Status (awaiting_address, requested, approved, sent, confirmed, rejected, failed)"
+// @Param        payment_id  query  string  false  "Payment ID"
+// @Success      200  {object}  dto.ApiResponse{data=[]model.Refund} "Refunds retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/refunds [get]
+func GetRefundsHandler(ctx *fiber.Ctx) error {
+	refundService := service.NewRefundService(repository.NewRefundRepository(database.DB))
+	refunds, err := refundService.GetRefunds(ctx.Query("status"), ctx.Query("payment_id"))
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch refunds", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Refunds fetched successfully", refunds))
+}
+
+// CreateRefundHandler godoc
+// @Summary      Create refund
+// @Description  Refund part of what a completed, cancelled or expired payment received, the customer is emailed a link to give the refund address (Admin only, merchant servers use POST /api/v1/refunds with a refunds:write API key)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body  dto.CreateRefundRequest  true  "Refund"
+// @Success      201  {object}  dto.ApiResponse{data=model.Refund} "Refund created"
+// @Failure      400  {object}  dto.ApiResponse "Payment can't be refunded"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/refunds [post]
+func CreateRefundHandler(ctx *fiber.Ctx) error {
+	var req dto.CreateRefundRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Invalid request body", err))
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Validation failed", err))
+	}
+
+	refundService := service.NewRefundService(repository.NewRefundRepository(database.DB))
+	refund, err := refundService.CreateRefund(req.PaymentID, req.Amount, model.RefundManual)
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to create refund", err))
+	}
+	return ctx.Status(201).JSON(dto.NewSuccess("Refund created", refund))
+}
+
+// ApproveRefundHandler godoc
+// @Summary      Approve refund
+// @Description  Approve a requested refund, it's sent from the hot wallet on the next refund run (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Refund ID"
+// @Success      200  {object}  dto.ApiResponse{data=model.Refund} "Refund approved"
+// @Failure      400  {object}  dto.ApiResponse "Refund can't be approved"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/refunds/{id}/approve [post]
+func ApproveRefundHandler(ctx *fiber.Ctx) error {
+	refundService := service.NewRefundService(repository.NewRefundRepository(database.DB))
+	refund, err := refundService.ApproveRefund(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to approve refund", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Refund approved", refund))
+}
+
+// RejectRefundHandler godoc
+// @Summary      Reject refund
+// @Description  Reject a refund that hasn't been sent (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Refund ID"
+// @Success      200  {object}  dto.ApiResponse{data=model.Refund} "Refund rejected"
+// @Failure      400  {object}  dto.ApiResponse "Refund can't be rejected"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/refunds/{id}/reject [post]
+func RejectRefundHandler(ctx *fiber.Ctx) error {
+	refundService := service.NewRefundService(repository.NewRefundRepository(database.DB))
+	refund, err := refundService.RejectRefund(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to reject refund", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Refund rejected", refund))
+}
+
+// RetryRefundHandler godoc
+// @Summary      Retry refund
+// @Description  Send a failed refund again (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Refund ID"
+// @Success      200  {object}  dto.ApiResponse{data=model.Refund} "Refund queued"
+// @Failure      400  {object}  dto.ApiResponse "Refund can't be retried"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/refunds/{id}/retry [post]
+func RetryRefundHandler(ctx *fiber.Ctx) error {
+	refundService := service.NewRefundService(repository.NewRefundRepository(database.DB))
+	refund, err := refundService.RetryRefund(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to retry refund", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Refund queued", refund))
+}
+
+// ResendRefundLinkHandler godoc
+// @Summary      Resend refund link
+// @Description  Email the customer a new refund address link, the previous one stops working (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Refund ID"
+// @Success      200  {object}  dto.ApiResponse{data=model.Refund} "Refund link sent"
+// @Failure      400  {object}  dto.ApiResponse "Refund link can't be sent"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/refunds/{id}/link [post]
+func ResendRefundLinkHandler(ctx *fiber.Ctx) error {
+	refundService := service.NewRefundService(repository.NewRefundRepository(database.DB))
+	refund, err := refundService.ResendRefundLink(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to send refund link", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Refund link sent", refund))
+}
+
+// GetRefundHandler godoc
+// @Summary      Get refund from link
+// @Description  Get the refund a signed refund link points to
+// @Tags         refunds
+// @Accept       json
+// @Produce      json
+// @Param        id         path   string  true  "Refund ID"
+// @Param        expires    query  int     true  "Link expiry, unix seconds"
+// @Param        signature  query  string  true  "Link signature"
+// @Success      200  {object}  dto.ApiResponse{data=dto.RefundResponse} "Refund retrieved successfully"
+// @Failure      403  {object}  dto.ApiResponse "Invalid or expired link"
+// @Router       /api/v1/refunds/{id} [get]
+func GetRefundHandler(ctx *fiber.Ctx) error {
+	refundService := service.NewRefundService(repository.NewRefundRepository(database.DB))
+	refund, err := refundService.GetRefundByLink(ctx.Params("id"), int64(ctx.QueryInt("expires")), ctx.Query("signature"))
+	if err != nil {
+		return ctx.Status(refundErrorStatus(err)).JSON(dto.NewError("Failed to fetch refund", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Refund fetched successfully", refund))
+}
+
+// SubmitRefundAddressHandler godoc
+// @Summary      Submit refund address
+// @Description  Give the address a refund is sent to, through a signed refund link
+// @Tags         refunds
+// @Accept       json
+// @Produce      json
+// @Param        id       path  string                    true  "Refund ID"
+// @Param        request  body  dto.RefundAddressRequest  true  "Refund address and link signature"
+// @Success      200  {object}  dto.ApiResponse{data=dto.RefundResponse} "Refund address saved"
+// @Failure      400  {object}  dto.ApiResponse "Invalid address"
+// @Failure      403  {object}  dto.ApiResponse "Invalid or expired link"
+// @Router       /api/v1/refunds/{id}/address [post]
+func SubmitRefundAddressHandler(ctx *fiber.Ctx) error {
+	var req dto.RefundAddressRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Invalid request body", err))
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Validation failed", err))
+	}
+
+	refundService := service.NewRefundService(repository.NewRefundRepository(database.DB))
+	refund, err := refundService.SubmitRefundAddress(ctx.Params("id"), req)
+	if err != nil {
+		return ctx.Status(refundErrorStatus(err)).JSON(dto.NewError("Failed to save refund address", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Refund address saved", refund))
+}
+
+func refundErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidRefundLink):
+		return 403
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return 404
+	default:
+		return 400
+	}
+}
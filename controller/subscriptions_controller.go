@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+)
+
+// GetSubscriptionsHandler godoc
+// @Summary      Get subscriptions
+// @Description  Get the subscriptions granted by completed payments, optionally by email or status (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        email   query  string  false  "Customer email"
+// @Param        status  query  string  false  "Status (active, expired)"
+// @Success      200  {object}  dto.ApiResponse{data=[]model.Subscription} "Subscriptions retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/subscriptions [get]
+func GetSubscriptionsHandler(ctx *fiber.Ctx) error {
+	subscriptionService := service.NewSubscriptionService(repository.NewSubscriptionRepository(database.DB))
+	subscriptions, err := subscriptionService.GetSubscriptions(ctx.Query("email"), ctx.Query("status"))
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch subscriptions", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Subscriptions fetched successfully", subscriptions))
+}
+
+// GetEntitlementHandler godoc
+// @Summary      Check entitlement
+// @Description  Check whether an email currently has an active subscription, to a given plan or to any (Admin only, merchant servers use GET /api/v1/entitlements with a subscriptions:read API key)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        email    query  string  true   "Customer email"
+// @Param        plan_id  query  string  false  "Plan ID"
+// @Success      200  {object}  dto.ApiResponse{data=dto.EntitlementResponse} "Entitlement retrieved successfully"
+// @Failure      400  {object}  dto.ApiResponse "Email is required"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/entitlements [get]
+func GetEntitlementHandler(ctx *fiber.Ctx) error {
+	email := ctx.Query("email")
+	if email == "" {
+		return ctx.Status(400).JSON(dto.NewError("Validation failed", errors.New("email is required")))
+	}
+
+	subscriptionService := service.NewSubscriptionService(repository.NewSubscriptionRepository(database.DB))
+	entitlement, err := subscriptionService.GetEntitlement(email, ctx.Query("plan_id"))
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to check entitlement", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Entitlement fetched successfully", entitlement))
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+)
+
+// GetSweepsHandler godoc
+// @Summary      Get sweeps
+// @Description  Get the transfers of payment funds from deposit wallets to the hot wallet, optionally filtered by status and payment (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        status      query  string  false  "Sweep status (pending, confirmed, failed, manual_review)"
+// @Param        payment_id  query  string  false  "Payment ID"
+// @Success      200  {object}  dto.ApiResponse{data=[]model.Sweep} "Sweeps retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/sweeps [get]
+func GetSweepsHandler(ctx *fiber.Ctx) error {
+	sweepService := service.NewSweepService(repository.NewSweepRepository(database.DB))
+	sweeps, err := sweepService.GetSweeps(ctx.Query("status"), ctx.Query("payment_id"))
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch sweeps", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Sweeps fetched successfully", sweeps))
+}
+
+// GetSweepPlanHandler godoc
+// @Summary      Plan sweeps
+// @Description  Dry run of the sweep policy, lists the deposit wallets of unswept payments, whether the next run sweeps them and the estimated fees. Nothing is sent (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  dto.ApiResponse{data=dto.SweepPlan} "Sweep plan created successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/sweeps/plan [get]
+func GetSweepPlanHandler(ctx *fiber.Ctx) error {
+	sweepService := service.NewSweepService(repository.NewSweepRepository(database.DB))
+	plan, err := sweepService.PlanSweeps()
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to plan sweeps", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Sweep plan created successfully", plan))
+}
+
+// RetrySweepHandler godoc
+// @Summary      Retry sweep
+// @Description  Re-send a failed sweep or one waiting for manual review (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Sweep ID"
+// @Success      200  {object}  dto.ApiResponse{data=model.Sweep} "Sweep re-sent"
+// @Failure      400  {object}  dto.ApiResponse "Sweep can't be retried"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/sweeps/{id}/retry [post]
+func RetrySweepHandler(ctx *fiber.Ctx) error {
+	sweepID := ctx.Params("id")
+
+	sweepService := service.NewSweepService(repository.NewSweepRepository(database.DB))
+	sweep, err := sweepService.RetrySweep(sweepID)
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to retry sweep", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Sweep re-sent", sweep))
+}
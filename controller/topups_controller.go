@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+)
+
+// GetAllTopUpsHandler godoc
+// @Summary      Get all wallet top-ups
+// @Description  Get all TRX top-ups and energy delegations sent to deposit wallets for TRC20 sweeps, for reconciliation (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  dto.ApiResponse{data=[]model.TopUp} "Top-ups retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/topups [get]
+func GetAllTopUpsHandler(ctx *fiber.Ctx) error {
+	topUpService := service.NewTopUpService(repository.NewTopUpRepository(database.DB))
+	topUps, err := topUpService.GetAllTopUps()
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch top-ups", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Top-ups fetched successfully", topUps))
+}
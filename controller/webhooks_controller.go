@@ -0,0 +1,153 @@
+package controller
+
+import (
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+)
+
+// CreateWebhookEndpointHandler godoc
+// @Summary      Create webhook endpoint
+// @Description  Register a URL that receives HMAC-SHA256 signed payment events. The signing secret is only returned once (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body  dto.CreateWebhookEndpointRequest  true  "Webhook endpoint data"
+// @Success      201  {object}  dto.ApiResponse{data=dto.WebhookEndpointSecretResponse} "Webhook endpoint created successfully"
+// @Failure      400  {object}  dto.ApiResponse "Invalid request"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/webhooks [post]
+func CreateWebhookEndpointHandler(ctx *fiber.Ctx) error {
+	var req dto.CreateWebhookEndpointRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Invalid request body", err))
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Validation failed", err))
+	}
+
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(database.DB))
+	endpoint, err := webhookService.CreateEndpoint(req)
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to create webhook endpoint", err))
+	}
+	return ctx.Status(201).JSON(dto.NewSuccess("Webhook endpoint created successfully", endpoint))
+}
+
+// GetWebhookEndpointsHandler godoc
+// @Summary      Get webhook endpoints
+// @Description  Get all registered webhook endpoints (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Success      200  {object}  dto.ApiResponse{data=[]model.WebhookEndpoint} "Webhook endpoints retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/webhooks [get]
+func GetWebhookEndpointsHandler(ctx *fiber.Ctx) error {
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(database.DB))
+	endpoints, err := webhookService.GetEndpoints()
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch webhook endpoints", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Webhook endpoints fetched successfully", endpoints))
+}
+
+// UpdateWebhookEndpointHandler godoc
+// @Summary      Update webhook endpoint
+// @Description  Update the URL, subscribed events or enabled flag of a webhook endpoint (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id       path  string                            true  "Webhook endpoint ID"
+// @Param        request  body  dto.UpdateWebhookEndpointRequest  true  "Webhook endpoint data"
+// @Success      200  {object}  dto.ApiResponse{data=model.WebhookEndpoint} "Webhook endpoint updated successfully"
+// @Failure      400  {object}  dto.ApiResponse "Invalid request"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/webhooks/{id} [put]
+func UpdateWebhookEndpointHandler(ctx *fiber.Ctx) error {
+	endpointID := ctx.Params("id")
+
+	var req dto.UpdateWebhookEndpointRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Invalid request body", err))
+	}
+
+	validate := validator.New()
+	if err := validate.Struct(req); err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Validation failed", err))
+	}
+
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(database.DB))
+	endpoint, err := webhookService.UpdateEndpoint(endpointID, req)
+	if err != nil {
+		return ctx.Status(400).JSON(dto.NewError("Failed to update webhook endpoint", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Webhook endpoint updated successfully", endpoint))
+}
+
+// DeleteWebhookEndpointHandler godoc
+// @Summary      Delete webhook endpoint
+// @Description  Delete a webhook endpoint, its pending deliveries are dropped (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Webhook endpoint ID"
+// @Success      200  {object}  dto.ApiResponse "Webhook endpoint deleted successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/webhooks/{id} [delete]
+func DeleteWebhookEndpointHandler(ctx *fiber.Ctx) error {
+	endpointID := ctx.Params("id")
+
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(database.DB))
+	if err := webhookService.DeleteEndpoint(endpointID); err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to delete webhook endpoint", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Webhook endpoint deleted successfully", nil))
+}
+
+// GetWebhookDeliveriesHandler godoc
+// @Summary      Get webhook deliveries
+// @Description  Get the webhook delivery log, optionally filtered by endpoint and status (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        endpoint_id  query  string  false  "Webhook endpoint ID"
+// @Param        status       query  string  false  "Delivery status (pending, succeeded, failed)"
+// @Success      200  {object}  dto.ApiResponse{data=[]model.WebhookDelivery} "Webhook deliveries retrieved successfully"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Router       /api/v1/admin/webhooks/deliveries [get]
+func GetWebhookDeliveriesHandler(ctx *fiber.Ctx) error {
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(database.DB))
+	deliveries, err := webhookService.GetDeliveries(ctx.Query("endpoint_id"), ctx.Query("status"))
+	if err != nil {
+		return ctx.Status(500).JSON(dto.NewError("Failed to fetch webhook deliveries", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Webhook deliveries fetched successfully", deliveries))
+}
+
+// ReplayWebhookDeliveryHandler godoc
+// @Summary      Replay webhook delivery
+// @Description  Queue a new delivery with the same payload as an earlier one (Admin only)
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        id  path  string  true  "Webhook delivery ID"
+// @Success      200  {object}  dto.ApiResponse{data=model.WebhookDelivery} "Webhook delivery queued for replay"
+// @Failure      401  {object}  dto.ApiResponse "Unauthorized"
+// @Failure      404  {object}  dto.ApiResponse "Delivery not found"
+// @Router       /api/v1/admin/webhooks/deliveries/{id}/replay [post]
+func ReplayWebhookDeliveryHandler(ctx *fiber.Ctx) error {
+	deliveryID := ctx.Params("id")
+
+	webhookService := service.NewWebhookService(repository.NewWebhookRepository(database.DB))
+	delivery, err := webhookService.ReplayDelivery(deliveryID)
+	if err != nil {
+		return ctx.Status(404).JSON(dto.NewError("Failed to replay webhook delivery", err))
+	}
+	return ctx.JSON(dto.NewSuccess("Webhook delivery queued for replay", delivery))
+}
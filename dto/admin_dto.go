@@ -17,35 +17,57 @@ type AdminInfo struct {
 }
 
 type CreatePlanRequest struct {
-	Name         string  `json:"name" validate:"required"`
-	Description  string  `json:"description" validate:"required"`
-	PriceUSD     float64 `json:"price_usd" validate:"required,gt=0"`
-	DurationDays int64   `json:"duration_days" validate:"required,gt=0"`
+	Name          string  `json:"name" validate:"required"`
+	Description   string  `json:"description" validate:"required"`
+	PriceUSD      float64 `json:"price_usd" validate:"required_without=Prices,omitempty,gt=0"`
+	DurationDays  int64   `json:"duration_days" validate:"required,gt=0"`
+	ExpiryMinutes int64   `json:"expiry_minutes" validate:"gte=0"`
+	// ISO-4217 currency the plan is priced in, USD when empty. Plans in another currency need a
+	// price in it in Prices
+	Currency string             `json:"currency" validate:"omitempty,iso4217"`
+	Prices   []PlanPriceRequest `json:"prices" validate:"dive"`
 }
 
 type UpdatePlanRequest struct {
-	Name         string  `json:"name" validate:"required"`
-	Description  string  `json:"description" validate:"required"`
-	PriceUSD     float64 `json:"price_usd" validate:"required,gt=0"`
-	DurationDays int64   `json:"duration_days" validate:"required,gt=0"`
+	Name          string  `json:"name" validate:"required"`
+	Description   string  `json:"description" validate:"required"`
+	PriceUSD      float64 `json:"price_usd" validate:"required_without=Prices,omitempty,gt=0"`
+	DurationDays  int64   `json:"duration_days" validate:"required,gt=0"`
+	ExpiryMinutes int64   `json:"expiry_minutes" validate:"gte=0"`
+	// ISO-4217 currency the plan is priced in, USD when empty. Plans in another currency need a
+	// price in it in Prices
+	Currency string             `json:"currency" validate:"omitempty,iso4217"`
+	Prices   []PlanPriceRequest `json:"prices" validate:"dive"`
+}
+
+// PlanPriceRequest fixes the price of a plan in a currency other than USD.
+type PlanPriceRequest struct {
+	Currency string  `json:"currency" validate:"required,iso4217"`
+	Amount   float64 `json:"amount" validate:"required,gt=0"`
 }
 
 type CreateCurrencyRequest struct {
-	Code         string `json:"code" validate:"required"`
-	Name         string `json:"name" validate:"required"`
-	Network      string `json:"network" validate:"required"`
-	IsToken      bool   `json:"is_token"`
-	ContractAddr string `json:"contract_addr"`
-	Enabled      bool   `json:"enabled"`
+	Code           string  `json:"code" validate:"required"`
+	Name           string  `json:"name" validate:"required"`
+	Network        string  `json:"network" validate:"required"`
+	IsToken        bool    `json:"is_token"`
+	ContractAddr   string  `json:"contract_addr"`
+	Enabled        bool    `json:"enabled"`
+	Confirmations  int64   `json:"confirmations" validate:"gte=0"`
+	SweepThreshold float64 `json:"sweep_threshold" validate:"gte=0"`
+	ExpiryMinutes  int64   `json:"expiry_minutes" validate:"gte=0"`
 }
 
 type UpdateCurrencyRequest struct {
-	Code         string `json:"code" validate:"required"`
-	Name         string `json:"name" validate:"required"`
-	Network      string `json:"network" validate:"required"`
-	IsToken      bool   `json:"is_token"`
-	ContractAddr string `json:"contract_addr"`
-	Enabled      bool   `json:"enabled"`
+	Code           string  `json:"code" validate:"required"`
+	Name           string  `json:"name" validate:"required"`
+	Network        string  `json:"network" validate:"required"`
+	IsToken        bool    `json:"is_token"`
+	ContractAddr   string  `json:"contract_addr"`
+	Enabled        bool    `json:"enabled"`
+	Confirmations  int64   `json:"confirmations" validate:"gte=0"`
+	SweepThreshold float64 `json:"sweep_threshold" validate:"gte=0"`
+	ExpiryMinutes  int64   `json:"expiry_minutes" validate:"gte=0"`
 }
 
 type ChangePasswordRequest struct {
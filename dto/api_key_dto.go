@@ -0,0 +1,14 @@
+package dto
+
+import "github.com/thebytearray/BytePayments/model"
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required"`
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+}
+
+// APIKeySecretResponse is only returned when a key is issued or rotated, the key can't be read again.
+type APIKeySecretResponse struct {
+	model.APIKey
+	Key string `json:"key"`
+}
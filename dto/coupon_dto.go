@@ -0,0 +1,62 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/thebytearray/BytePayments/model"
+)
+
+type CreateCouponRequest struct {
+	Code           string     `json:"code" validate:"required,max=50"`
+	Description    string     `json:"description" validate:"max=255"`
+	Type           string     `json:"type" validate:"required,oneof=percent fixed"`
+	Value          float64    `json:"value" validate:"gt=0"`                 // percent off (below 100), or amount off in currency
+	Currency       string     `json:"currency" validate:"omitempty,iso4217"` // of fixed discounts, USD when empty
+	PlanIDs        []string   `json:"plan_ids"`                              // empty for every plan
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	MaxRedemptions int        `json:"max_redemptions" validate:"gte=0"`
+	PerEmailLimit  int        `json:"per_email_limit" validate:"gte=0"`
+}
+
+type UpdateCouponRequest struct {
+	Description    string     `json:"description" validate:"max=255"`
+	Type           string     `json:"type" validate:"required,oneof=percent fixed"`
+	Value          float64    `json:"value" validate:"gt=0"`
+	Currency       string     `json:"currency" validate:"omitempty,iso4217"`
+	PlanIDs        []string   `json:"plan_ids"`
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	MaxRedemptions int        `json:"max_redemptions" validate:"gte=0"`
+	PerEmailLimit  int        `json:"per_email_limit" validate:"gte=0"`
+	Enabled        bool       `json:"enabled"`
+}
+
+// CouponResponse is a coupon with the redemptions it has left.
+type CouponResponse struct {
+	model.Coupon
+	Redemptions          int64  `json:"redemptions"`                     // payments made with it, cancelled and expired ones excluded
+	RemainingRedemptions *int64 `json:"remaining_redemptions,omitempty"` // unset when unlimited
+}
+
+// CouponRedemptionReport lists the payments a coupon was redeemed on. The totals only count
+// completed payments and are per fiat currency.
+type CouponRedemptionReport struct {
+	Coupon         CouponResponse     `json:"coupon"`
+	Completed      int64              `json:"completed"`
+	DiscountTotals map[string]float64 `json:"discount_totals"`
+	RevenueTotals  map[string]float64 `json:"revenue_totals"` // what was charged after the discount
+	Redemptions    []CouponRedemption `json:"redemptions"`
+}
+
+type CouponRedemption struct {
+	PaymentID      string              `json:"payment_id"`
+	Email          string              `json:"email"`
+	PlanID         string              `json:"plan_id"`
+	Status         model.PaymentStatus `json:"status"`
+	FiatCurrency   string              `json:"fiat_currency"`
+	OriginalAmount float64             `json:"original_amount"`
+	DiscountAmount float64             `json:"discount_amount"`
+	FiatAmount     float64             `json:"fiat_amount"`
+	CreatedAt      string              `json:"created_at"`
+}
@@ -0,0 +1,34 @@
+package dto
+
+type CreateDestinationRequest struct {
+	Name    string `json:"name" validate:"required"`
+	Address string `json:"address" validate:"required"`
+}
+
+type UpdateDestinationRequest struct {
+	Name    string `json:"name" validate:"required"`
+	Address string `json:"address" validate:"required"`
+	Enabled bool   `json:"enabled"`
+}
+
+type SweepSplitRequest struct {
+	DestinationID string  `json:"destination_id" validate:"required"`
+	Percent       float64 `json:"percent" validate:"gt=0,lte=100"`
+}
+
+type CreateSweepRuleRequest struct {
+	Name         string              `json:"name" validate:"required"`
+	CurrencyCode string              `json:"currency_code"`
+	PlanID       string              `json:"plan_id"`
+	Priority     int                 `json:"priority"`
+	Splits       []SweepSplitRequest `json:"splits" validate:"required,min=1,dive"`
+}
+
+type UpdateSweepRuleRequest struct {
+	Name         string              `json:"name" validate:"required"`
+	CurrencyCode string              `json:"currency_code"`
+	PlanID       string              `json:"plan_id"`
+	Priority     int                 `json:"priority"`
+	Enabled      bool                `json:"enabled"`
+	Splits       []SweepSplitRequest `json:"splits" validate:"required,min=1,dive"`
+}
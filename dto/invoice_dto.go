@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"encoding/json"
+
+	"github.com/thebytearray/BytePayments/model"
+)
+
+// CreateInvoiceRequest bills an arbitrary amount instead of a plan. Amount is only needed without
+// line items, with them it must match their total when given. Amounts are in Currency.
+type CreateInvoiceRequest struct {
+	Email        string                   `json:"email" validate:"required,email"`
+	CurrencyCode string                   `json:"currency_code" validate:"required"`     // crypto currency it's paid in
+	Currency     string                   `json:"currency" validate:"omitempty,iso4217"` // ISO-4217 currency it's priced in, USD when empty
+	Description  string                   `json:"description" validate:"max=1000"`
+	Amount       float64                  `json:"amount" validate:"omitempty,gt=0"`
+	LineItems    []InvoiceLineItemRequest `json:"line_items" validate:"dive"`
+	// the merchant's order context, as on payments
+	ExternalReference string          `json:"external_reference" validate:"omitempty,max=100"`
+	Metadata          json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`
+	SuccessURL        string          `json:"success_url" validate:"omitempty,http_url,max=500"`
+	CancelURL         string          `json:"cancel_url" validate:"omitempty,http_url,max=500"`
+}
+
+type InvoiceLineItemRequest struct {
+	Description string  `json:"description" validate:"required,max=255"`
+	Quantity    float64 `json:"quantity" validate:"gt=0"`
+	UnitPrice   float64 `json:"unit_price" validate:"gt=0"`
+}
+
+type InvoiceResponse struct {
+	model.Invoice
+	Payment    PaymentResponse `json:"payment"`
+	InvoiceURL string          `json:"invoice_url"` // HTML rendering, printable
+	PdfURL     string          `json:"pdf_url"`
+}
@@ -0,0 +1,7 @@
+package dto
+
+type ResolveOrphanRequest struct {
+	Action        string `json:"action" validate:"required,oneof=attach refund sweep"`
+	PaymentID     string `json:"payment_id" validate:"required_if=Action attach"`
+	RefundAddress string `json:"refund_address" validate:"required_if=Action refund"`
+}
@@ -1,22 +1,92 @@
 package dto
 
-import "github.com/thebytearray/BytePayments/model"
+import (
+	"encoding/json"
+
+	"github.com/thebytearray/BytePayments/model"
+)
 
 type CreatePaymentRequest struct {
 	PlanId            string `json:"plan_id"`
 	Email             string `json:"email"`
 	CurrencyCode      string `json:"currency_code" validate:"required"`
-	VerificationToken string `json:"verification_token" validate:"required"`
+	FiatCurrency      string `json:"fiat_currency" validate:"omitempty,iso4217"` // ISO-4217 currency to be quoted in, the plan's own when empty
+	CouponCode        string `json:"coupon_code" validate:"omitempty,max=50"`    // discount code, case-insensitive
+	VerificationToken string `json:"verification_token"`                         // not needed when the request carries an API key
+	// the merchant's order context, echoed back in responses and webhooks
+	ExternalReference string          `json:"external_reference" validate:"omitempty,max=100"`   // unique per API key
+	Metadata          json.RawMessage `json:"metadata,omitempty" swaggertype:"object"`           // any JSON object, up to 4 KB
+	SuccessURL        string          `json:"success_url" validate:"omitempty,http_url,max=500"` // overrides CHECKOUT_SUCCESS_URL
+	CancelURL         string          `json:"cancel_url" validate:"omitempty,http_url,max=500"`  // overrides CHECKOUT_CANCEL_URL
 }
 
 type PaymentResponse struct {
-	PaymentId        string              `json:"payment_id"`
-	Status           model.PaymentStatus `json:"status"`
-	PlanId           string              `json:"plan_id"`
-	Email            string              `json:"email"`
-	QrImage          string              `json:"qr_image"`
-	TrxAmount        float64             `json:"trx_amount"`
-	TrxWalletAddress string              `json:"trx_wallet_address"`
-	CreatedAt        string              `json:"created_at"`
-	UpdatedAt        string              `json:"updated_at"`
+	PaymentId             string                   `json:"payment_id"`
+	Status                model.PaymentStatus      `json:"status"`
+	PlanId                string                   `json:"plan_id"`
+	InvoiceID             string                   `json:"invoice_id,omitempty"` // set instead of the plan on invoice payments
+	Email                 string                   `json:"email"`
+	QrImage               string                   `json:"qr_image"`
+	CurrencyCode          string                   `json:"currency_code"`
+	FiatCurrency          string                   `json:"fiat_currency"` // the price the payment was quoted from
+	FiatAmount            float64                  `json:"fiat_amount"`
+	AmountUSD             float64                  `json:"amount_usd"`
+	CouponCode            string                   `json:"coupon_code,omitempty"`
+	OriginalAmount        float64                  `json:"original_amount,omitempty"` // fiat price before the coupon
+	DiscountAmount        float64                  `json:"discount_amount,omitempty"`
+	Amount                float64                  `json:"amount"` // still due, the remaining amount once partially paid
+	TrxAmount             float64                  `json:"trx_amount"`
+	TotalAmount           float64                  `json:"total_amount"`
+	PaidAmount            float64                  `json:"paid_amount"`
+	RemainingAmount       float64                  `json:"remaining_amount"`
+	TrxWalletAddress      string                   `json:"trx_wallet_address"`
+	Confirmations         int64                    `json:"confirmations"`
+	RequiredConfirmations int64                    `json:"required_confirmations"`
+	Deposits              []PaymentDepositResponse `json:"deposits,omitempty"`
+	Quote                 *PaymentQuoteResponse    `json:"quote,omitempty"`
+	ExternalReference     string                   `json:"external_reference,omitempty"`
+	Metadata              json.RawMessage          `json:"metadata,omitempty" swaggertype:"object"`
+	SuccessURL            string                   `json:"success_url,omitempty"`
+	CancelURL             string                   `json:"cancel_url,omitempty"`
+	ExpiresAt             string                   `json:"expires_at,omitempty"`
+	CreatedAt             string                   `json:"created_at"`
+	UpdatedAt             string                   `json:"updated_at"`
+}
+
+// PaymentQuoteResponse is the exchange rate a payment's amount was quoted at. ExpiresIn counts down
+// the seconds left until the lock ends and the payment is re-quoted or expired.
+type PaymentQuoteResponse struct {
+	Rate         float64 `json:"rate"`    // USD price of one unit of the payment currency
+	FXRate       float64 `json:"fx_rate"` // USD value of one unit of the fiat currency
+	Source       string  `json:"source"`
+	QuotedAt     string  `json:"quoted_at"`
+	ExpiresAt    string  `json:"expires_at"`
+	ExpiresIn    int64   `json:"expires_in"`
+	RequoteCount int     `json:"requote_count"`
+}
+
+type ResolveLatePaymentRequest struct {
+	Accept bool `json:"accept"` // true completes the payment with what was received, false expires it again
+}
+
+type PaymentDepositResponse struct {
+	TxHash        string              `json:"tx_hash"`
+	Amount        float64             `json:"amount"`
+	Confirmations int64               `json:"confirmations"`
+	Status        model.DepositStatus `json:"status"`
+}
+
+// PaymentEvent is the snapshot of a payment pushed to the clients streaming its updates from
+// /api/v1/payments/{id}/events. A new one is sent whenever any of its fields change.
+type PaymentEvent struct {
+	PaymentId             string              `json:"payment_id"`
+	Status                model.PaymentStatus `json:"status"`
+	CurrencyCode          string              `json:"currency_code"`
+	Amount                float64             `json:"amount"` // still due, the remaining amount once partially paid
+	TotalAmount           float64             `json:"total_amount"`
+	PaidAmount            float64             `json:"paid_amount"`
+	RemainingAmount       float64             `json:"remaining_amount"`
+	Confirmations         int64               `json:"confirmations"`
+	RequiredConfirmations int64               `json:"required_confirmations"`
+	ExpiresAt             string              `json:"expires_at,omitempty"`
 }
@@ -4,3 +4,20 @@ type PriceResponse struct {
 	Symbol string `json:"symbol"`
 	Price  string `json:"price"`
 }
+
+// CoinGeckoPriceResponse is keyed by coin id, then by fiat currency.
+type CoinGeckoPriceResponse map[string]map[string]float64
+
+type KrakenTickerResponse struct {
+	Error  []string `json:"error"`
+	Result map[string]struct {
+		LastTrade []string `json:"c"` // [price, lot volume]
+	} `json:"result"`
+}
+
+// FrankfurterRatesResponse is the latest endpoint of the Frankfurter (ECB) FX API.
+type FrankfurterRatesResponse struct {
+	Base  string             `json:"base"`
+	Date  string             `json:"date"`
+	Rates map[string]float64 `json:"rates"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+import "github.com/thebytearray/BytePayments/model"
+
+type CreateRefundRequest struct {
+	PaymentID string  `json:"payment_id" validate:"required"`
+	Amount    float64 `json:"amount" validate:"required,gt=0"`
+}
+
+// RefundAddressRequest is posted from the signed refund link, Expires and Signature are the
+// query parameters of the link.
+type RefundAddressRequest struct {
+	Address   string `json:"address" validate:"required"`
+	Expires   int64  `json:"expires" validate:"required"`
+	Signature string `json:"signature" validate:"required"`
+}
+
+type RefundResponse struct {
+	RefundID     string             `json:"refund_id"`
+	PaymentID    string             `json:"payment_id"`
+	CurrencyCode string             `json:"currency_code"`
+	Amount       float64            `json:"amount"`
+	Status       model.RefundStatus `json:"status"`
+	ToAddress    string             `json:"to_address"`
+}
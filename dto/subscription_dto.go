@@ -0,0 +1,21 @@
+package dto
+
+import "github.com/thebytearray/BytePayments/model"
+
+// EntitlementResponse tells whether an email currently has an active subscription, to the given
+// plan when one was asked for.
+type EntitlementResponse struct {
+	Email         string                 `json:"email"`
+	PlanID        string                 `json:"plan_id,omitempty"`
+	Active        bool                   `json:"active"`
+	Subscriptions []SubscriptionResponse `json:"subscriptions"`
+}
+
+type SubscriptionResponse struct {
+	SubscriptionID string                   `json:"subscription_id"`
+	PlanID         string                   `json:"plan_id"`
+	PlanName       string                   `json:"plan_name"`
+	Status         model.SubscriptionStatus `json:"status"`
+	StartsAt       string                   `json:"starts_at"`
+	EndsAt         string                   `json:"ends_at"`
+}
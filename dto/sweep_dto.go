@@ -0,0 +1,31 @@
+package dto
+
+// SweepPlan is a dry run of the sweep policy over the wallets of completed, unswept payments.
+type SweepPlan struct {
+	Policy      string          `json:"policy"`
+	Schedule    string          `json:"schedule,omitempty"`
+	Wallets     []SweepPlanItem `json:"wallets"`
+	TotalFeeSun int64           `json:"total_fee_sun"`
+}
+
+type SweepPlanItem struct {
+	WalletAddress   string         `json:"wallet_address"`
+	CurrencyCode    string         `json:"currency_code"`
+	PaymentIDs      []string       `json:"payment_ids"`
+	Balance         float64        `json:"balance"`
+	Amount          float64        `json:"amount"` // credited to the payments, the rest of the balance stays
+	Transferable    float64        `json:"transferable"`
+	Threshold       float64        `json:"threshold"`
+	RuleID          string         `json:"rule_id,omitempty"` // empty when everything goes to the hot wallet
+	Legs            []SweepPlanLeg `json:"legs,omitempty"`
+	EstimatedFeeSun int64          `json:"estimated_fee_sun"`
+	Sweep           bool           `json:"sweep"`
+	Reason          string         `json:"reason,omitempty"` // why the wallet is left alone
+}
+
+type SweepPlanLeg struct {
+	DestinationID string  `json:"destination_id,omitempty"`
+	Address       string  `json:"address"`
+	Percent       float64 `json:"percent"`
+	Amount        float64 `json:"amount"`
+}
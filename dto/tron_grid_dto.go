@@ -7,4 +7,59 @@ type AccountResourceRequest struct {
 type AccountResourceResponse struct {
 	FreeNetUsed  int64 `json:"freeNetUsed"`
 	FreeNetLimit int64 `json:"freeNetLimit"`
+	NetUsed      int64 `json:"NetUsed"`
+	NetLimit     int64 `json:"NetLimit"`
+	EnergyUsed   int64 `json:"EnergyUsed"`
+	EnergyLimit  int64 `json:"EnergyLimit"`
+}
+
+// TronGridMeta is the paging info TronGrid v1 list endpoints return.
+type TronGridMeta struct {
+	Fingerprint string `json:"fingerprint"`
+}
+
+type TronGridTransactionsResponse struct {
+	Data    []TronGridTransaction `json:"data"`
+	Success bool                  `json:"success"`
+	Meta    TronGridMeta          `json:"meta"`
+}
+
+type TronGridTransaction struct {
+	TxID           string `json:"txID"`
+	BlockNumber    int64  `json:"blockNumber"`
+	BlockTimestamp int64  `json:"block_timestamp"`
+	Ret            []struct {
+		ContractRet string `json:"contractRet"`
+	} `json:"ret"`
+	RawData struct {
+		Contract []struct {
+			Type      string `json:"type"`
+			Parameter struct {
+				Value struct {
+					Amount       int64  `json:"amount"`
+					OwnerAddress string `json:"owner_address"`
+					ToAddress    string `json:"to_address"`
+				} `json:"value"`
+			} `json:"parameter"`
+		} `json:"contract"`
+	} `json:"raw_data"`
+}
+
+type TronGridTRC20TransfersResponse struct {
+	Data    []TronGridTRC20Transfer `json:"data"`
+	Success bool                    `json:"success"`
+	Meta    TronGridMeta            `json:"meta"`
+}
+
+type TronGridTRC20Transfer struct {
+	TransactionID  string `json:"transaction_id"`
+	BlockTimestamp int64  `json:"block_timestamp"`
+	From           string `json:"from"`
+	To             string `json:"to"`
+	Type           string `json:"type"`
+	Value          string `json:"value"`
+	TokenInfo      struct {
+		Address  string `json:"address"`
+		Decimals int64  `json:"decimals"`
+	} `json:"token_info"`
 }
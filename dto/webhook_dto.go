@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"encoding/json"
+
+	"github.com/thebytearray/BytePayments/model"
+)
+
+type CreateWebhookEndpointRequest struct {
+	URL      string   `json:"url" validate:"required,url"`
+	Events   []string `json:"events"`
+	APIKeyID string   `json:"api_key_id"` // merchant API key whose payments the endpoint receives
+}
+
+type UpdateWebhookEndpointRequest struct {
+	URL     string   `json:"url" validate:"required,url"`
+	Events  []string `json:"events"`
+	Enabled bool     `json:"enabled"`
+}
+
+// WebhookEndpointSecretResponse is only returned on creation, the secret can't be read again.
+type WebhookEndpointSecretResponse struct {
+	model.WebhookEndpoint
+	Secret string `json:"secret"`
+}
+
+// WebhookPayload is the JSON body POSTed to webhook endpoints.
+type WebhookPayload struct {
+	ID        string             `json:"id"`
+	Event     model.WebhookEvent `json:"event"`
+	CreatedAt string             `json:"created_at"`
+	Data      WebhookPaymentData `json:"data"`
+}
+
+type WebhookPaymentData struct {
+	PaymentId         string                   `json:"payment_id"`
+	Status            model.PaymentStatus      `json:"status"`
+	SweepStatus       model.PaymentSweepStatus `json:"sweep_status,omitempty"`
+	PlanId            string                   `json:"plan_id"`
+	SubscriptionID    string                   `json:"subscription_id,omitempty"` // set on renewal invoices
+	Email             string                   `json:"email"`
+	CurrencyCode      string                   `json:"currency_code"`
+	Amount            float64                  `json:"amount"`
+	AmountUSD         float64                  `json:"amount_usd"`
+	FiatCurrency      string                   `json:"fiat_currency"`
+	FiatAmount        float64                  `json:"fiat_amount"`
+	CouponCode        string                   `json:"coupon_code,omitempty"`
+	OriginalAmount    float64                  `json:"original_amount,omitempty"`
+	DiscountAmount    float64                  `json:"discount_amount,omitempty"`
+	QuoteRate         float64                  `json:"quote_rate"`
+	QuoteSource       string                   `json:"quote_source"`
+	QuoteExpiresAt    string                   `json:"quote_expires_at,omitempty"`
+	ExpiresAt         string                   `json:"expires_at,omitempty"`
+	PaidAmount        float64                  `json:"paid_amount"`
+	RemainingAmount   float64                  `json:"remaining_amount"`
+	WalletAddress     string                   `json:"wallet_address"`
+	ExternalReference string                   `json:"external_reference,omitempty"`
+	Metadata          json.RawMessage          `json:"metadata,omitempty" swaggertype:"object"`
+	SuccessURL        string                   `json:"success_url,omitempty"`
+	CancelURL         string                   `json:"cancel_url,omitempty"`
+	CreatedAt         string                   `json:"created_at"`
+	UpdatedAt         string                   `json:"updated_at"`
+}
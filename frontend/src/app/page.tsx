@@ -13,7 +13,7 @@ import { Separator } from "@/components/ui/separator"
 import { ThemeToggle } from "@/components/theme-toggle"
 import { PlanCard } from "@/components/plan-card"
 import { InputOTP, InputOTPGroup, InputOTPSlot } from "@/components/ui/input-otp"
-import { apiClient, Plan, Currency } from "@/lib/api"
+import { apiClient, Plan, Currency, planPrice } from "@/lib/api"
 
 export default function HomePage() {
   const router = useRouter()
@@ -248,7 +248,7 @@ export default function HomePage() {
                         </div>
                         <div className="text-right">
                           <div className="text-lg font-bold text-blue-600 dark:text-blue-400">
-                            ${plan.price_usd}
+                            {planPrice(plan).amount} {planPrice(plan).currency}
                           </div>
                           <div className="text-xs text-muted-foreground">
                             {plan.duration_days} days
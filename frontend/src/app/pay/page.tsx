@@ -37,6 +37,7 @@ function PaymentPageContent() {
 
   const intervalRef = React.useRef<NodeJS.Timeout | null>(null)
   const statusCheckRef = React.useRef<NodeJS.Timeout | null>(null)
+  const eventsRef = React.useRef<EventSource | null>(null)
 
   React.useEffect(() => {
     if (!paymentId) {
@@ -49,6 +50,7 @@ function PaymentPageContent() {
     return () => {
       if (intervalRef.current) clearInterval(intervalRef.current)
       if (statusCheckRef.current) clearInterval(statusCheckRef.current)
+      if (eventsRef.current) eventsRef.current.close()
     }
     // eslint-disable-next-line react-hooks/exhaustive-deps
   }, [paymentId])
@@ -101,29 +103,52 @@ function PaymentPageContent() {
     }, 1000)
   }
 
-  const startStatusChecking = () => {
-    if (statusCheckRef.current) clearInterval(statusCheckRef.current)
+  const refreshStatus = async () => {
+    if (!paymentId) return
     
-    statusCheckRef.current = setInterval(async () => {
-      if (!paymentId) return
+    try {
+      const response = await apiClient.getPaymentStatus(paymentId)
       
-      try {
-        const response = await apiClient.getPaymentStatus(paymentId)
+      if (response.status === "ok" && response.data) {
+        const newStatus = response.data.status.toLowerCase()
+        
+        previousStatus.current = newStatus
+        setPaymentData(response.data)
         
-        if (response.status === "ok" && response.data) {
-          const newStatus = response.data.status.toLowerCase()
-          
-          previousStatus.current = newStatus
-          setPaymentData(response.data)
-          
-          if (["completed", "cancelled", "expired"].includes(newStatus)) {
-            if (statusCheckRef.current) clearInterval(statusCheckRef.current)
-          }
+        if (["completed", "cancelled", "expired"].includes(newStatus)) {
+          if (statusCheckRef.current) clearInterval(statusCheckRef.current)
+          if (eventsRef.current) eventsRef.current.close()
         }
-      } catch (error) {
-        console.error("Error checking payment status:", error)
       }
-    }, 10000) // Check every 10 seconds
+    } catch (error) {
+      console.error("Error checking payment status:", error)
+    }
+  }
+
+  const startPolling = () => {
+    if (statusCheckRef.current) clearInterval(statusCheckRef.current)
+    statusCheckRef.current = setInterval(refreshStatus, 10000) // Check every 10 seconds
+  }
+
+  const startStatusChecking = () => {
+    if (!paymentId) return
+    if (typeof EventSource === "undefined") {
+      startPolling()
+      return
+    }
+
+    // the stream pushes a snapshot on every change, the full status is only fetched then
+    if (eventsRef.current) eventsRef.current.close()
+    const events = apiClient.paymentEvents(paymentId)
+    events.onmessage = () => {
+      refreshStatus()
+    }
+    events.onerror = () => {
+      if (events.readyState === EventSource.CLOSED && !statusCheckRef.current) {
+        startPolling()
+      }
+    }
+    eventsRef.current = events
   }
 
   const checkIfExpired = async () => {
@@ -190,6 +215,13 @@ function PaymentPageContent() {
             Awaiting Payment
           </Badge>
         )
+      case "partially_paid":
+        return (
+          <Badge variant="secondary" className="bg-amber-100 text-amber-800 dark:bg-amber-900/20 dark:text-amber-400">
+            <AlertCircle className="w-3 h-3 mr-1" />
+            Partially Paid
+          </Badge>
+        )
       case "completed":
         return (
           <Badge variant="secondary" className="bg-green-100 text-green-800 dark:bg-green-900/20 dark:text-green-400">
@@ -0,0 +1,215 @@
+"use client"
+
+import * as React from "react"
+import { useSearchParams } from "next/navigation"
+import { AlertCircle, CheckCircle, Clock, Loader2, Send } from "lucide-react"
+import { toast } from "sonner"
+
+import { Button } from "@/components/ui/button"
+import { Card, CardContent, CardHeader } from "@/components/ui/card"
+import { Badge } from "@/components/ui/badge"
+import { Input } from "@/components/ui/input"
+import { Label } from "@/components/ui/label"
+import { Alert, AlertDescription } from "@/components/ui/alert"
+
+import { ThemeToggle } from "@/components/theme-toggle"
+import { apiClient, RefundData } from "@/lib/api"
+
+function RefundPageContent() {
+  const searchParams = useSearchParams()
+  const refundId = searchParams.get("id")
+  const expires = Number(searchParams.get("expires"))
+  const signature = searchParams.get("signature")
+
+  const [refundData, setRefundData] = React.useState<RefundData | null>(null)
+  const [address, setAddress] = React.useState("")
+  const [isLoading, setIsLoading] = React.useState(true)
+  const [isSubmitting, setIsSubmitting] = React.useState(false)
+
+  React.useEffect(() => {
+    if (!refundId || !expires || !signature) {
+      setIsLoading(false)
+      return
+    }
+
+    loadRefundData()
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [refundId])
+
+  const loadRefundData = async () => {
+    if (!refundId || !signature) return
+
+    try {
+      const response = await apiClient.getRefund(refundId, expires, signature)
+
+      if (response.status === "ok" && response.data) {
+        setRefundData(response.data)
+        setAddress(response.data.to_address)
+      }
+    } catch (error) {
+      console.error("Error loading refund data:", error)
+    } finally {
+      setIsLoading(false)
+    }
+  }
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault()
+    if (!refundId || !signature) return
+
+    setIsSubmitting(true)
+    try {
+      const response = await apiClient.submitRefundAddress(refundId, address.trim(), expires, signature)
+
+      if (response.status === "ok" && response.data) {
+        setRefundData(response.data)
+        toast.success("Refund address saved")
+      } else {
+        toast.error(response.message || "Failed to save refund address")
+      }
+    } catch (error) {
+      console.error("Error saving refund address:", error)
+      toast.error("Failed to save refund address")
+    } finally {
+      setIsSubmitting(false)
+    }
+  }
+
+  const getStatusBadge = (status: string) => {
+    switch (status) {
+      case "awaiting_address":
+        return (
+          <Badge variant="secondary" className="bg-amber-100 text-amber-800 dark:bg-amber-900/20 dark:text-amber-400">
+            <Clock className="w-3 h-3 mr-1" />
+            Awaiting Address
+          </Badge>
+        )
+      case "requested":
+      case "approved":
+        return (
+          <Badge variant="secondary" className="bg-blue-100 text-blue-800 dark:bg-blue-900/20 dark:text-blue-400">
+            <Clock className="w-3 h-3 mr-1" />
+            Being Reviewed
+          </Badge>
+        )
+      case "sent":
+      case "confirmed":
+        return (
+          <Badge variant="secondary" className="bg-green-100 text-green-800 dark:bg-green-900/20 dark:text-green-400">
+            <CheckCircle className="w-3 h-3 mr-1" />
+            Refund Sent
+          </Badge>
+        )
+      default:
+        return <Badge variant="outline">{status}</Badge>
+    }
+  }
+
+  if (isLoading) {
+    return (
+      <div className="min-h-screen bg-background flex items-center justify-center">
+        <Loader2 className="h-8 w-8 animate-spin" />
+      </div>
+    )
+  }
+
+  if (!refundData) {
+    return (
+      <div className="min-h-screen bg-background flex items-center justify-center p-4">
+        <Card className="max-w-md">
+          <CardContent className="p-8 text-center">
+            <div className="w-16 h-16 bg-red-100 dark:bg-red-900/20 rounded-full flex items-center justify-center mx-auto mb-4">
+              <AlertCircle className="w-8 h-8 text-red-500 dark:text-red-400" />
+            </div>
+            <h2 className="text-xl font-bold mb-2">Link Not Valid</h2>
+            <p className="text-muted-foreground">
+              This refund link is invalid or has expired. Please contact support to get a new one.
+            </p>
+          </CardContent>
+        </Card>
+      </div>
+    )
+  }
+
+  const canEdit = ["awaiting_address", "requested"].includes(refundData.status)
+
+  return (
+    <div className="min-h-screen bg-background">
+      {/* Minimal Header */}
+      <div className="border-b bg-card">
+        <div className="max-w-2xl mx-auto px-4 py-4 flex items-center justify-between">
+          <div className="text-lg font-semibold">Byte Payments</div>
+          <ThemeToggle />
+        </div>
+      </div>
+
+      <div className="max-w-2xl mx-auto p-4 py-8">
+        <Card>
+          <CardHeader className="text-center pb-6">
+            <div className="flex items-center justify-center gap-2 mb-2">
+              <div className="text-2xl font-bold text-blue-600 dark:text-blue-400">
+                {refundData.amount} {refundData.currency_code}
+              </div>
+              {getStatusBadge(refundData.status)}
+            </div>
+            <p className="text-muted-foreground text-sm">
+              Refund for payment {refundData.payment_id}
+            </p>
+          </CardHeader>
+
+          <CardContent className="space-y-6">
+            {canEdit ? (
+              <form onSubmit={handleSubmit} className="space-y-4">
+                <div className="space-y-2">
+                  <Label htmlFor="address">TRON Address</Label>
+                  <Input
+                    id="address"
+                    placeholder="T..."
+                    value={address}
+                    onChange={(e) => setAddress(e.target.value)}
+                    className="font-mono"
+                    required
+                  />
+                </div>
+                <Button type="submit" disabled={isSubmitting || !address.trim()} className="w-full">
+                  {isSubmitting ? (
+                    <Loader2 className="mr-2 h-4 w-4 animate-spin" />
+                  ) : (
+                    <Send className="mr-2 h-4 w-4" />
+                  )}
+                  {refundData.status === "requested" ? "Update Address" : "Request Refund"}
+                </Button>
+              </form>
+            ) : (
+              <div className="bg-muted/20 rounded-lg p-4 space-y-2">
+                <div className="flex justify-between text-sm">
+                  <span className="text-muted-foreground">Refund Address</span>
+                  <span className="font-mono break-all">{refundData.to_address}</span>
+                </div>
+              </div>
+            )}
+
+            <Alert>
+              <AlertCircle className="h-4 w-4" />
+              <AlertDescription className="text-sm">
+                The refund is sent on the TRON network once it&apos;s approved. Double check the address, transfers can&apos;t be reversed.
+              </AlertDescription>
+            </Alert>
+          </CardContent>
+        </Card>
+      </div>
+    </div>
+  )
+}
+
+export default function RefundPage() {
+  return (
+    <React.Suspense fallback={
+      <div className="min-h-screen bg-background flex items-center justify-center">
+        <Loader2 className="h-8 w-8 animate-spin" />
+      </div>
+    }>
+      <RefundPageContent />
+    </React.Suspense>
+  )
+}
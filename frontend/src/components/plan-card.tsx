@@ -4,7 +4,7 @@ import * as React from "react"
 import { Check } from "lucide-react"
 import { Card, CardContent } from "@/components/ui/card"
 import { cn } from "@/lib/utils"
-import { Plan } from "@/lib/api"
+import { Plan, planPrice } from "@/lib/api"
 
 interface PlanCardProps {
   plan: Plan
@@ -30,10 +30,10 @@ export function PlanCard({ plan, isSelected, onClick, className }: PlanCardProps
           </h3>
           <div className="mb-3">
             <span className="text-2xl font-bold text-blue-600 dark:text-blue-400">
-              ${plan.price_usd}
+              {planPrice(plan).amount}
             </span>
             <span className="text-gray-500 dark:text-gray-400 text-sm ml-1">
-              USD
+              {planPrice(plan).currency}
             </span>
           </div>
           <p className="text-gray-600 dark:text-gray-400 text-sm mb-3">
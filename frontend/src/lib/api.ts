@@ -5,6 +5,23 @@ export interface Plan {
   description: string;
   price_usd: number;
   duration_days: number;
+  currency?: string; // ISO-4217 currency the plan is priced in, USD when missing
+  prices?: PlanPrice[];
+}
+
+export interface PlanPrice {
+  currency: string;
+  amount: number;
+}
+
+// The plan's price in its own currency.
+export function planPrice(plan: Plan): PlanPrice {
+  const currency = plan.currency || 'USD';
+  if (currency === 'USD') {
+    return { currency, amount: plan.price_usd };
+  }
+  const price = plan.prices?.find((p) => p.currency === currency);
+  return { currency, amount: price ? price.amount : plan.price_usd };
 }
 
 export interface Currency {
@@ -134,6 +151,15 @@ export interface UpdateCurrencyRequest {
   enabled: boolean;
 }
 
+export interface RefundData {
+  refund_id: string;
+  payment_id: string;
+  currency_code: string;
+  amount: number;
+  status: string;
+  to_address: string;
+}
+
 export interface ChangePasswordRequest {
   old_password: string;
   new_password: string;
@@ -238,6 +264,33 @@ class ApiClient {
     return this.request<PaymentData>(`/api/v1/payments/${paymentId}/status`);
   }
 
+  // Server-Sent Events stream pushing a snapshot whenever the payment changes
+  paymentEvents(paymentId: string): EventSource {
+    return new EventSource(`${API_BASE_URL}/api/v1/payments/${paymentId}/events`);
+  }
+
+  async getRefund(
+    refundId: string,
+    expires: number,
+    signature: string,
+  ): Promise<ApiResponse<RefundData>> {
+    return this.request<RefundData>(
+      `/api/v1/refunds/${refundId}?expires=${expires}&signature=${encodeURIComponent(signature)}`,
+    );
+  }
+
+  async submitRefundAddress(
+    refundId: string,
+    address: string,
+    expires: number,
+    signature: string,
+  ): Promise<ApiResponse<RefundData>> {
+    return this.request<RefundData>(`/api/v1/refunds/${refundId}/address`, {
+      method: "POST",
+      body: JSON.stringify({ address, expires, signature }),
+    });
+  }
+
   async cancelPayment(paymentId: string): Promise<ApiResponse<null>> {
     return this.request<null>(`/api/v1/payments/${paymentId}/cancel`, {
       method: "PATCH",
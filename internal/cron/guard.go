@@ -0,0 +1,33 @@
+package cron
+
+import (
+	"log"
+	"sync"
+)
+
+// jobGuard keeps the jobs sharing it from overlapping, a run is skipped while the previous one is
+// still busy.
+type jobGuard struct {
+	mu      sync.Mutex
+	running bool
+}
+
+// run runs job unless another job of the guard is still busy.
+func (g *jobGuard) run(name string, job func()) {
+	g.mu.Lock()
+	if g.running {
+		log.Printf("Previous %s still running, skipping this run", name)
+		g.mu.Unlock()
+		return
+	}
+	g.running = true
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		g.running = false
+		g.mu.Unlock()
+	}()
+
+	job()
+}
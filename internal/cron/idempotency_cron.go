@@ -0,0 +1,22 @@
+package cron
+
+import (
+	"github.com/robfig/cron/v3"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+)
+
+var idempotencyJobs jobGuard
+
+// NewIdempotencyCron purges the stored Idempotency-Key responses once they can't be replayed anymore.
+func NewIdempotencyCron() {
+	c := cron.New()
+	c.AddFunc("@hourly", func() {
+		idempotencyJobs.run("idempotency key purge", func() {
+			idempotencyService := service.NewIdempotencyService(repository.NewIdempotencyRepository(database.DB))
+			idempotencyService.PurgeExpired()
+		})
+	})
+	c.Start()
+}
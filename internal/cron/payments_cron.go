@@ -2,7 +2,6 @@ package cron
 
 import (
 	"log"
-	"sync"
 	"time"
 
 	"github.com/robfig/cron/v3"
@@ -11,41 +10,40 @@ import (
 	"github.com/thebytearray/BytePayments/service"
 )
 
-var (
-	mu        sync.Mutex
-	isRunning bool
-)
-
-func safeProcessPendingPayments() {
-	mu.Lock()
-	if isRunning {
-		log.Println("Previous job still running, skipping this run")
-		mu.Unlock()
-		return
-	}
-	isRunning = true
-	mu.Unlock()
-
-	start := time.Now()
-	log.Println("Started processing payments at:", start.Format(time.RFC3339))
+// paymentJobs all credit transfers into deposit wallets and must not record the same transfer
+// twice.
+var paymentJobs jobGuard
 
-	defer func() {
-		duration := time.Since(start)
-		log.Println("Finished processing in:", duration)
+// safeRunPaymentJob runs job unless another payment job is still busy.
+func safeRunPaymentJob(name string, job func()) {
+	paymentJobs.run("payment job", func() {
+		start := time.Now()
+		log.Printf("Started %s at: %s", name, start.Format(time.RFC3339))
 
-		mu.Lock()
-		isRunning = false
-		mu.Unlock()
-	}()
+		defer func() {
+			log.Println("Finished processing in:", time.Since(start))
+		}()
 
-	paymentService := service.NewPaymentService(repository.NewPaymentRepository(database.DB))
-	paymentService.ProcessPendingPayments()
+		job()
+	})
 }
 
 func NewPaymentCron() {
 	c := cron.New()
 	c.AddFunc("@every 30s", func() {
-		safeProcessPendingPayments()
+		safeRunPaymentJob("processing payments", func() {
+			paymentService := service.NewPaymentService(repository.NewPaymentRepository(database.DB))
+			paymentService.ProcessPendingPayments()
+			// after the pending payments, so a deposit on a reused wallet goes to the newest payment
+			paymentService.ProcessLatePayments()
+		})
+	})
+	// checks the balance of every idle wallet, so it runs less often
+	c.AddFunc("@every 10m", func() {
+		safeRunPaymentJob("scanning for orphaned deposits", func() {
+			orphanService := service.NewOrphanService(repository.NewOrphanRepository(database.DB))
+			orphanService.ScanOrphanedDeposits()
+		})
 	})
 	c.Start()
 }
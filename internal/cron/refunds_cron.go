@@ -0,0 +1,21 @@
+package cron
+
+import (
+	"github.com/robfig/cron/v3"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+)
+
+// refundJobs keeps an approved refund from being sent twice by overlapping runs.
+var refundJobs jobGuard
+
+func NewRefundCron() {
+	c := cron.New()
+	c.AddFunc("@every 30s", func() {
+		refundJobs.run("refund job", func() {
+			service.NewRefundService(repository.NewRefundRepository(database.DB)).ProcessRefunds()
+		})
+	})
+	c.Start()
+}
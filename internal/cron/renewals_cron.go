@@ -0,0 +1,21 @@
+package cron
+
+import (
+	"github.com/robfig/cron/v3"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+)
+
+// renewalJobs keeps a subscription from being invoiced twice by overlapping runs.
+var renewalJobs jobGuard
+
+func NewRenewalCron() {
+	c := cron.New()
+	c.AddFunc("@every 10m", func() {
+		renewalJobs.run("renewal job", func() {
+			service.NewRenewalService(repository.NewSubscriptionRepository(database.DB)).ProcessRenewals()
+		})
+	})
+	c.Start()
+}
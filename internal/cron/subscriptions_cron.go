@@ -0,0 +1,21 @@
+package cron
+
+import (
+	"github.com/robfig/cron/v3"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+)
+
+// subscriptionJobs keeps a customer from being reminded twice by overlapping runs.
+var subscriptionJobs jobGuard
+
+func NewSubscriptionCron() {
+	c := cron.New()
+	c.AddFunc("@every 10m", func() {
+		subscriptionJobs.run("subscription job", func() {
+			service.NewSubscriptionService(repository.NewSubscriptionRepository(database.DB)).ProcessSubscriptions()
+		})
+	})
+	c.Start()
+}
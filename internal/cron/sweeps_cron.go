@@ -0,0 +1,39 @@
+package cron
+
+import (
+	"log"
+
+	"github.com/robfig/cron/v3"
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+)
+
+// sweepJobs keeps sending and verifying sweeps apart, running both at once could move the same
+// wallet twice.
+var sweepJobs jobGuard
+
+func safeRunSweepJob(job func(service.SweepService)) {
+	sweepJobs.run("sweep job", func() {
+		job(service.NewSweepService(repository.NewSweepRepository(database.DB)))
+	})
+}
+
+func NewSweepCron() {
+	c := cron.New()
+	c.AddFunc("@every 30s", func() {
+		safeRunSweepJob(service.SweepService.ProcessPendingSweeps)
+	})
+
+	spec := "@every 30s"
+	if config.Cfg.SWEEP_POLICY == service.SweepPolicySchedule {
+		spec = config.Cfg.SWEEP_SCHEDULE
+	}
+	if _, err := c.AddFunc(spec, func() {
+		safeRunSweepJob(service.SweepService.SweepUnsweptPayments)
+	}); err != nil {
+		log.Printf("Invalid SWEEP_SCHEDULE %q, payments won't be swept: %v", spec, err)
+	}
+	c.Start()
+}
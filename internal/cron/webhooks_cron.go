@@ -0,0 +1,22 @@
+package cron
+
+import (
+	"github.com/robfig/cron/v3"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/repository"
+	"github.com/thebytearray/BytePayments/service"
+)
+
+// webhookJobs keeps a delivery from being sent twice by overlapping runs.
+var webhookJobs jobGuard
+
+func NewWebhookCron() {
+	c := cron.New()
+	c.AddFunc("@every 10s", func() {
+		webhookJobs.run("webhook job", func() {
+			webhookService := service.NewWebhookService(repository.NewWebhookRepository(database.DB))
+			webhookService.ProcessDueDeliveries()
+		})
+	})
+	c.Start()
+}
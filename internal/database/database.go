@@ -48,7 +48,7 @@ func Connect() {
 	//
 	//
 	//
-	err = DB.AutoMigrate(&model.Currency{}, &model.Payment{}, &model.Plan{}, &model.Wallet{}, &model.Admin{})
+	err = DB.AutoMigrate(&model.Currency{}, &model.Payment{}, &model.Plan{}, &model.PlanPrice{}, &model.Wallet{}, &model.Admin{}, &model.TopUp{}, &model.WebhookEndpoint{}, &model.WebhookDelivery{}, &model.Deposit{}, &model.Sweep{}, &model.DestinationWallet{}, &model.SweepRule{}, &model.SweepSplit{}, &model.OrphanDeposit{}, &model.Refund{}, &model.Subscription{}, &model.SubscriptionPeriod{}, &model.APIKey{}, &model.IdempotencyKey{}, &model.Invoice{}, &model.InvoiceLineItem{}, &model.Coupon{})
 	if err != nil {
 		log.Printf("Failed to automigrate database, %v", err)
 	}
@@ -92,6 +92,14 @@ func SeedDatabase() {
 			ContractAddr: "",
 			Enabled:      true,
 		},
+		{
+			Code:         "USDT",
+			Name:         "Tether USD",
+			Network:      "TRC20",
+			IsToken:      true,
+			ContractAddr: "TR7NHqjeKQxGTCi8q8ZY4pL8otSzgjLj6t", // mainnet contract, use the shasta one for testing
+			Enabled:      true,
+		},
 	}
 	plansRes := DB.Create(plans)
 	currenciesRes := DB.Create(&currencies)
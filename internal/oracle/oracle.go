@@ -0,0 +1,249 @@
+package oracle
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgraph-io/ristretto"
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/internal/database"
+)
+
+var (
+	ErrNoPrice          = errors.New("no price source returned a quote")
+	ErrSourcesDisagree  = errors.New("price sources disagree")
+	ErrUnsupportedAsset = errors.New("asset not supported by price source")
+)
+
+// How long the last accepted price is kept around for when every source is down. FX reference
+// rates only move once a day, so the last one stays usable for longer.
+const (
+	lastGoodTTL   = 10 * time.Minute
+	fxLastGoodTTL = 24 * time.Hour
+)
+
+// Quote is the USD price of one unit of Symbol.
+type Quote struct {
+	Symbol    string    `json:"symbol"`
+	PriceUSD  float64   `json:"price_usd"`
+	Source    string    `json:"source"` // provider name, or "median(a,b,c)" for aggregated quotes
+	FetchedAt time.Time `json:"fetched_at"`
+	Stale     bool      `json:"stale"` // served from the last good price because no source answered
+}
+
+// PriceOracle returns the USD price of an asset, or of one unit of a fiat currency for the FX
+// oracle.
+type PriceOracle interface {
+	Price(symbol string) (Quote, error)
+}
+
+// PriceProvider is a single price source the oracle aggregates.
+type PriceProvider interface {
+	Name() string
+	Price(symbol string) (float64, error)
+}
+
+type medianOracle struct {
+	providers    []PriceProvider
+	maxDeviation float64 // percent away from the median a quote may be before it is rejected
+	cacheTTL     time.Duration
+	lastGoodTTL  time.Duration
+	cachePrefix  string // keeps crypto prices and FX rates apart in the shared cache
+	cache        *ristretto.Cache
+}
+
+// NewPriceOracle builds the oracle from the PRICE_SOURCES config.
+func NewPriceOracle() PriceOracle {
+	client := &http.Client{Timeout: time.Duration(config.Cfg.PRICE_TIMEOUT_SECONDS) * time.Second}
+
+	var providers []PriceProvider
+	for _, name := range strings.Split(config.Cfg.PRICE_SOURCES, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "binance":
+			providers = append(providers, NewBinanceProvider(client, config.Cfg.BINANCE_API_URL))
+		case "coingecko":
+			providers = append(providers, NewCoinGeckoProvider(client, config.Cfg.COINGECKO_API_URL))
+		case "kraken":
+			providers = append(providers, NewKrakenProvider(client, config.Cfg.KRAKEN_API_URL))
+		case "static":
+			providers = append(providers, NewStaticProvider(config.Cfg.STATIC_PRICES))
+		case "":
+		default:
+			log.Printf("Unknown price source %q, ignoring it", name)
+		}
+	}
+
+	return &medianOracle{
+		providers:    providers,
+		maxDeviation: config.Cfg.PRICE_MAX_DEVIATION_PERCENT,
+		cacheTTL:     time.Duration(config.Cfg.PRICE_CACHE_TTL_SECONDS) * time.Second,
+		lastGoodTTL:  lastGoodTTL,
+		cachePrefix:  "price:",
+		cache:        database.Cache,
+	}
+}
+
+// NewFXOracle builds the oracle of fiat exchange rates from the FX_SOURCES config, its quotes are
+// the USD value of one unit of the currency.
+func NewFXOracle() PriceOracle {
+	client := &http.Client{Timeout: time.Duration(config.Cfg.PRICE_TIMEOUT_SECONDS) * time.Second}
+
+	var providers []PriceProvider
+	for _, name := range strings.Split(config.Cfg.FX_SOURCES, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "frankfurter":
+			providers = append(providers, NewFrankfurterProvider(client, config.Cfg.FRANKFURTER_API_URL))
+		case "kraken":
+			providers = append(providers, NewKrakenProvider(client, config.Cfg.KRAKEN_API_URL))
+		case "static":
+			providers = append(providers, NewStaticProvider(config.Cfg.STATIC_FX_RATES))
+		case "":
+		default:
+			log.Printf("Unknown FX source %q, ignoring it", name)
+		}
+	}
+
+	return &medianOracle{
+		providers:    providers,
+		maxDeviation: config.Cfg.PRICE_MAX_DEVIATION_PERCENT,
+		cacheTTL:     time.Duration(config.Cfg.FX_CACHE_TTL_SECONDS) * time.Second,
+		lastGoodTTL:  fxLastGoodTTL,
+		cachePrefix:  "fx:",
+		cache:        database.Cache,
+	}
+}
+
+func (o *medianOracle) Price(symbol string) (Quote, error) {
+	symbol = strings.ToUpper(symbol)
+
+	if cached, ok := o.cache.Get(o.cacheKey(symbol)); ok {
+		return cached.(Quote), nil
+	}
+
+	quote, err := o.aggregate(symbol)
+	if errors.Is(err, ErrNoPrice) {
+		// every source is down, a recent price is better than refusing the payment
+		if last, ok := o.cache.Get(o.lastGoodKey(symbol)); ok {
+			stale := last.(Quote)
+			stale.Stale = true
+			log.Printf("No price source answered for %s, using the last good price from %s", symbol, stale.FetchedAt.Format(time.RFC3339))
+			return stale, nil
+		}
+	}
+	if err != nil {
+		return Quote{}, err
+	}
+
+	o.cache.SetWithTTL(o.cacheKey(symbol), quote, 1, o.cacheTTL)
+	o.cache.SetWithTTL(o.lastGoodKey(symbol), quote, 1, o.lastGoodTTL)
+	return quote, nil
+}
+
+type sourcePrice struct {
+	source string
+	price  float64
+}
+
+// aggregate asks every provider at once and returns the median of the quotes that agree with it.
+func (o *medianOracle) aggregate(symbol string) (Quote, error) {
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		prices []sourcePrice
+	)
+	for _, provider := range o.providers {
+		wg.Add(1)
+		go func(p PriceProvider) {
+			defer wg.Done()
+			price, err := p.Price(symbol)
+			if err != nil {
+				if !errors.Is(err, ErrUnsupportedAsset) {
+					log.Printf("Price source %s failed for %s: %v", p.Name(), symbol, err)
+				}
+				return
+			}
+			if price <= 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+				log.Printf("Price source %s returned an invalid price for %s: %v", p.Name(), symbol, price)
+				return
+			}
+			mu.Lock()
+			prices = append(prices, sourcePrice{p.Name(), price})
+			mu.Unlock()
+		}(provider)
+	}
+	wg.Wait()
+
+	if len(prices) == 0 {
+		return Quote{}, fmt.Errorf("%w for %s", ErrNoPrice, symbol)
+	}
+
+	mid := median(prices)
+
+	// drop the outliers, then require most of the answering sources to still agree
+	var accepted []sourcePrice
+	for _, p := range prices {
+		if math.Abs(p.price-mid)/mid*100 <= o.maxDeviation {
+			accepted = append(accepted, p)
+		} else {
+			log.Printf("Rejected %s price %v for %s, median is %v", p.source, p.price, symbol, mid)
+		}
+	}
+	if len(accepted)*2 <= len(prices) {
+		return Quote{}, fmt.Errorf("%w on %s by more than %g%%: %s", ErrSourcesDisagree, symbol, o.maxDeviation, describe(prices))
+	}
+
+	names := make([]string, len(accepted))
+	for i, p := range accepted {
+		names[i] = p.source
+	}
+	sort.Strings(names)
+
+	source := names[0]
+	if len(accepted) > 1 {
+		source = "median(" + strings.Join(names, ",") + ")"
+	}
+
+	return Quote{
+		Symbol:    symbol,
+		PriceUSD:  median(accepted),
+		Source:    source,
+		FetchedAt: time.Now(),
+	}, nil
+}
+
+func median(prices []sourcePrice) float64 {
+	values := make([]float64, len(prices))
+	for i, p := range prices {
+		values[i] = p.price
+	}
+	sort.Float64s(values)
+
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+func describe(prices []sourcePrice) string {
+	parts := make([]string, len(prices))
+	for i, p := range prices {
+		parts[i] = fmt.Sprintf("%s=%v", p.source, p.price)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (o *medianOracle) cacheKey(symbol string) string {
+	return o.cachePrefix + symbol
+}
+
+func (o *medianOracle) lastGoodKey(symbol string) string {
+	return o.cachePrefix + "last:" + symbol
+}
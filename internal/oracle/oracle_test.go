@@ -0,0 +1,128 @@
+package oracle
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeProvider answers with a fixed price, or err.
+type fakeProvider struct {
+	name  string
+	price float64
+	err   error
+}
+
+func (p fakeProvider) Name() string { return p.name }
+
+func (p fakeProvider) Price(symbol string) (float64, error) {
+	return p.price, p.err
+}
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices []float64
+		want   float64
+	}{
+		{"single", []float64{0.12}, 0.12},
+		{"odd count", []float64{3, 1, 2}, 2},
+		{"even count averages the middle", []float64{4, 1, 3, 2}, 2.5},
+		{"outlier doesn't move it", []float64{0.12, 0.121, 5}, 0.121},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prices := make([]sourcePrice, len(tt.prices))
+			for i, p := range tt.prices {
+				prices[i] = sourcePrice{source: "s", price: p}
+			}
+			if got := median(prices); got != tt.want {
+				t.Errorf("median(%v) = %v, want %v", tt.prices, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	down := errors.New("timeout")
+	tests := []struct {
+		name       string
+		providers  []PriceProvider
+		wantPrice  float64
+		wantSource string
+		wantErr    error
+	}{
+		{
+			name:       "single source",
+			providers:  []PriceProvider{fakeProvider{name: "binance", price: 0.12}},
+			wantPrice:  0.12,
+			wantSource: "binance",
+		},
+		{
+			name: "median of agreeing sources",
+			providers: []PriceProvider{
+				fakeProvider{name: "kraken", price: 0.121},
+				fakeProvider{name: "binance", price: 0.12},
+				fakeProvider{name: "coingecko", price: 0.122},
+			},
+			wantPrice:  0.121,
+			wantSource: "median(binance,coingecko,kraken)",
+		},
+		{
+			name: "outlier rejected",
+			providers: []PriceProvider{
+				fakeProvider{name: "binance", price: 0.12},
+				fakeProvider{name: "coingecko", price: 0.1202},
+				fakeProvider{name: "kraken", price: 0.2},
+			},
+			wantPrice:  0.1201,
+			wantSource: "median(binance,coingecko)",
+		},
+		{
+			name: "failed and invalid sources ignored",
+			providers: []PriceProvider{
+				fakeProvider{name: "binance", price: 0.12},
+				fakeProvider{name: "coingecko", err: down},
+				fakeProvider{name: "kraken", price: -1},
+			},
+			wantPrice:  0.12,
+			wantSource: "binance",
+		},
+		{
+			name: "no majority agrees",
+			providers: []PriceProvider{
+				fakeProvider{name: "binance", price: 0.1},
+				fakeProvider{name: "kraken", price: 0.2},
+			},
+			wantErr: ErrSourcesDisagree,
+		},
+		{
+			name: "every source down",
+			providers: []PriceProvider{
+				fakeProvider{name: "binance", err: down},
+				fakeProvider{name: "kraken", err: ErrUnsupportedAsset},
+			},
+			wantErr: ErrNoPrice,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &medianOracle{providers: tt.providers, maxDeviation: 2}
+			quote, err := o.aggregate("trx")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("aggregate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("aggregate() error = %v", err)
+			}
+			if quote.PriceUSD != tt.wantPrice || quote.Source != tt.wantSource {
+				t.Errorf("aggregate() = %v from %s, want %v from %s", quote.PriceUSD, quote.Source, tt.wantPrice, tt.wantSource)
+			}
+			if quote.Symbol != "trx" {
+				t.Errorf("aggregate() symbol = %s, want trx", quote.Symbol)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package oracle
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/thebytearray/BytePayments/dto"
+)
+
+// coinGeckoIDs maps our symbols to CoinGecko coin ids.
+var coinGeckoIDs = map[string]string{
+	"TRX":  "tron",
+	"USDT": "tether",
+}
+
+type binanceProvider struct {
+	client *http.Client
+	apiURL string
+}
+
+// NewBinanceProvider prices against USDT on the ticker endpoint in apiURL, its symbol query
+// parameter is replaced with the requested pair.
+func NewBinanceProvider(client *http.Client, apiURL string) PriceProvider {
+	return &binanceProvider{client, apiURL}
+}
+
+func (p *binanceProvider) Name() string {
+	return "binance"
+}
+
+func (p *binanceProvider) Price(symbol string) (float64, error) {
+	if symbol == "USDT" {
+		return 0, ErrUnsupportedAsset
+	}
+
+	u, err := url.Parse(p.apiURL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Binance api url: %w", err)
+	}
+	query := u.Query()
+	query.Set("symbol", symbol+"USDT")
+	u.RawQuery = query.Encode()
+
+	var priceResp dto.PriceResponse
+	if err := getJSON(p.client, u.String(), &priceResp); err != nil {
+		return 0, err
+	}
+
+	price, err := strconv.ParseFloat(priceResp.Price, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid price format: %w", err)
+	}
+	return price, nil
+}
+
+type coinGeckoProvider struct {
+	client *http.Client
+	apiURL string
+}
+
+// NewCoinGeckoProvider uses the simple/price endpoint at apiURL.
+func NewCoinGeckoProvider(client *http.Client, apiURL string) PriceProvider {
+	return &coinGeckoProvider{client, apiURL}
+}
+
+func (p *coinGeckoProvider) Name() string {
+	return "coingecko"
+}
+
+func (p *coinGeckoProvider) Price(symbol string) (float64, error) {
+	id, ok := coinGeckoIDs[symbol]
+	if !ok {
+		return 0, ErrUnsupportedAsset
+	}
+
+	u, err := url.Parse(p.apiURL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid CoinGecko api url: %w", err)
+	}
+	query := u.Query()
+	query.Set("ids", id)
+	query.Set("vs_currencies", "usd")
+	u.RawQuery = query.Encode()
+
+	var priceResp dto.CoinGeckoPriceResponse
+	if err := getJSON(p.client, u.String(), &priceResp); err != nil {
+		return 0, err
+	}
+
+	price, ok := priceResp[id]["usd"]
+	if !ok {
+		return 0, fmt.Errorf("no usd price for %s in CoinGecko response", id)
+	}
+	return price, nil
+}
+
+type krakenProvider struct {
+	client *http.Client
+	apiURL string
+}
+
+// NewKrakenProvider uses the public Ticker endpoint at apiURL.
+func NewKrakenProvider(client *http.Client, apiURL string) PriceProvider {
+	return &krakenProvider{client, apiURL}
+}
+
+func (p *krakenProvider) Name() string {
+	return "kraken"
+}
+
+func (p *krakenProvider) Price(symbol string) (float64, error) {
+	u, err := url.Parse(p.apiURL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Kraken api url: %w", err)
+	}
+	query := u.Query()
+	query.Set("pair", symbol+"USD")
+	u.RawQuery = query.Encode()
+
+	var tickerResp dto.KrakenTickerResponse
+	if err := getJSON(p.client, u.String(), &tickerResp); err != nil {
+		return 0, err
+	}
+	if len(tickerResp.Error) > 0 {
+		return 0, fmt.Errorf("kraken error: %s", strings.Join(tickerResp.Error, ", "))
+	}
+
+	// the result is keyed by Kraken's own pair name, which is not always the one we asked for
+	for _, ticker := range tickerResp.Result {
+		if len(ticker.LastTrade) == 0 {
+			break
+		}
+		price, err := strconv.ParseFloat(ticker.LastTrade[0], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid price format: %w", err)
+		}
+		return price, nil
+	}
+	return 0, fmt.Errorf("no %sUSD ticker in Kraken response", symbol)
+}
+
+type frankfurterProvider struct {
+	client *http.Client
+	apiURL string
+}
+
+// NewFrankfurterProvider serves the ECB reference rates of fiat currencies from the latest
+// endpoint at apiURL. The rates are published once a working day.
+func NewFrankfurterProvider(client *http.Client, apiURL string) PriceProvider {
+	return &frankfurterProvider{client, apiURL}
+}
+
+func (p *frankfurterProvider) Name() string {
+	return "frankfurter"
+}
+
+func (p *frankfurterProvider) Price(symbol string) (float64, error) {
+	u, err := url.Parse(p.apiURL)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Frankfurter api url: %w", err)
+	}
+	query := u.Query()
+	query.Set("from", symbol)
+	query.Set("to", "USD")
+	u.RawQuery = query.Encode()
+
+	var ratesResp dto.FrankfurterRatesResponse
+	if err := getJSON(p.client, u.String(), &ratesResp); err != nil {
+		return 0, err
+	}
+
+	rate, ok := ratesResp.Rates["USD"]
+	if !ok {
+		return 0, fmt.Errorf("no USD rate for %s in Frankfurter response", symbol)
+	}
+	return rate, nil
+}
+
+type staticProvider struct {
+	prices map[string]float64
+}
+
+// NewStaticProvider serves manually configured prices, given as "TRX=0.25,USDT=1".
+func NewStaticProvider(spec string) PriceProvider {
+	prices := make(map[string]float64)
+	for _, pair := range strings.Split(spec, ",") {
+		symbol, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok {
+			continue
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			continue
+		}
+		prices[strings.ToUpper(strings.TrimSpace(symbol))] = price
+	}
+	return &staticProvider{prices}
+}
+
+func (p *staticProvider) Name() string {
+	return "static"
+}
+
+func (p *staticProvider) Price(symbol string) (float64, error) {
+	price, ok := p.prices[symbol]
+	if !ok {
+		return 0, ErrUnsupportedAsset
+	}
+	return price, nil
+}
+
+func getJSON(client *http.Client, url string, out any) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to fetch price: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("non-200 response: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package pubsub
+
+import (
+	"bytes"
+	"sync"
+)
+
+// subscriberBuffer is how many messages a slow subscriber can fall behind before new ones are
+// dropped for it. Every message is a full snapshot, so a dropped one is made up by the next.
+const subscriberBuffer = 8
+
+// Broker fans messages out to the subscribers of a topic within this process. Publishing the same
+// message twice in a row to a topic is a no-op, so publishers don't have to track what changed.
+type Broker struct {
+	mu     sync.Mutex
+	topics map[string]*topic
+}
+
+type topic struct {
+	subscribers map[chan []byte]struct{}
+	last        []byte
+}
+
+// Payments carries the status updates of payments, keyed by payment id.
+var Payments = NewBroker()
+
+func NewBroker() *Broker {
+	return &Broker{topics: make(map[string]*topic)}
+}
+
+// Subscribe returns a channel receiving the messages published to key and a function that ends
+// the subscription and closes the channel.
+func (b *Broker) Subscribe(key string) (<-chan []byte, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[key]
+	if !ok {
+		t = &topic{subscribers: make(map[chan []byte]struct{})}
+		b.topics[key] = t
+	}
+	ch := make(chan []byte, subscriberBuffer)
+	t.subscribers[ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			delete(t.subscribers, ch)
+			close(ch)
+			// the last message is only kept while someone listens
+			if len(t.subscribers) == 0 && b.topics[key] == t {
+				delete(b.topics, key)
+			}
+		})
+	}
+}
+
+// HasSubscribers reports whether anyone listens to key, letting publishers skip building a
+// message nobody will receive.
+func (b *Broker) HasSubscribers(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.topics[key]
+	return ok
+}
+
+// Publish sends msg to the subscribers of key. It never blocks, a subscriber whose buffer is full
+// misses the message.
+func (b *Broker) Publish(key string, msg []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t, ok := b.topics[key]
+	if !ok || bytes.Equal(t.last, msg) {
+		return
+	}
+	t.last = msg
+	for ch := range t.subscribers {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package tron
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheByteArray/go-tron-sdk/pkg/client"
+	"github.com/TheByteArray/go-tron-sdk/pkg/proto/core"
+)
+
+const (
+	// trc20TxSizeBytes is the bandwidth a signed TRC20 transfer consumes
+	trc20TxSizeBytes = int64(350)
+	// defaultEnergyPriceSun is used when the node doesn't report energy prices
+	defaultEnergyPriceSun = int64(420)
+	sunPerBandwidthUnit   = int64(1000)
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction not found on chain yet")
+	ErrTransactionFailed   = errors.New("transaction failed on chain")
+)
+
+// TRC20TransferCost describes the resources a TRC20 transfer needs and what the sender already has.
+type TRC20TransferCost struct {
+	EnergyRequired     int64
+	EnergyAvailable    int64
+	BandwidthRequired  int64
+	BandwidthAvailable int64
+	EnergyPriceSun     int64
+}
+
+// MissingEnergy is the energy the sender has to burn TRX for.
+func (c TRC20TransferCost) MissingEnergy() int64 {
+	return max(c.EnergyRequired-c.EnergyAvailable, 0)
+}
+
+// MissingBandwidth is the bandwidth the sender has to burn TRX for.
+func (c TRC20TransferCost) MissingBandwidth() int64 {
+	return max(c.BandwidthRequired-c.BandwidthAvailable, 0)
+}
+
+// FeeSun is the TRX (in sun) the transfer burns on top of the sender's own resources.
+func (c TRC20TransferCost) FeeSun() int64 {
+	return c.MissingEnergy()*c.EnergyPriceSun + c.MissingBandwidth()*sunPerBandwidthUnit
+}
+
+// EstimateTRC20TransferCost dry-runs a token transfer to find the energy it needs and
+// compares it with the energy and bandwidth the sending wallet already has.
+func EstimateTRC20TransferCost(c *client.GrpcClient, from, to, contractAddr string, amount float64) (TRC20TransferCost, error) {
+	decimals, err := GetTokenDecimals(c, contractAddr)
+	if err != nil {
+		return TRC20TransferCost{}, err
+	}
+
+	params := fmt.Sprintf(`[{"address":"%s"},{"uint256":"%s"}]`, to, AmountToTokenUnits(amount, decimals).String())
+	tx, err := c.TriggerConstantContract(from, contractAddr, "transfer(address,uint256)", params)
+	if err != nil {
+		return TRC20TransferCost{}, fmt.Errorf("failed to estimate energy: %w", err)
+	}
+	if tx.Result != nil && tx.Result.Code > 0 {
+		return TRC20TransferCost{}, fmt.Errorf("failed to estimate energy: %s", string(tx.Result.Message))
+	}
+
+	res, err := getAccountResource(from)
+	if err != nil {
+		return TRC20TransferCost{}, err
+	}
+
+	return TRC20TransferCost{
+		EnergyRequired:     tx.EnergyUsed,
+		EnergyAvailable:    max(res.EnergyLimit-res.EnergyUsed, 0),
+		BandwidthRequired:  trc20TxSizeBytes,
+		BandwidthAvailable: max(res.FreeNetLimit-res.FreeNetUsed, 0) + max(res.NetLimit-res.NetUsed, 0),
+		EnergyPriceSun:     GetEnergyPriceSun(c),
+	}, nil
+}
+
+// GetEnergyPriceSun returns the current network price of one energy unit in sun.
+func GetEnergyPriceSun(c *client.GrpcClient) int64 {
+	prices, err := c.GetEnergyPrices()
+	if err != nil || prices.Prices == "" {
+		return defaultEnergyPriceSun
+	}
+
+	// prices come as "timestamp:price,timestamp:price", the last one is current
+	entries := strings.Split(prices.Prices, ",")
+	parts := strings.Split(entries[len(entries)-1], ":")
+	price, err := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+	if err != nil || price <= 0 {
+		return defaultEnergyPriceSun
+	}
+	return price
+}
+
+// DelegateEnergy delegates enough of the owner's staked TRX to give receiver the requested energy.
+// It returns the transaction ID and the staked balance in sun that was delegated.
+func DelegateEnergy(c *client.GrpcClient, owner, receiver string, energy int64, privateKey string) (string, int64, error) {
+	res, err := c.GetAccountResource(owner)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get owner resources: %w", err)
+	}
+	if res.TotalEnergyLimit == 0 {
+		return "", 0, fmt.Errorf("network reported no energy limit")
+	}
+
+	// energy per staked TRX is TotalEnergyLimit / TotalEnergyWeight
+	balanceSun := int64(math.Ceil(float64(energy)*float64(res.TotalEnergyWeight)/float64(res.TotalEnergyLimit))) * 1_000_000
+
+	tx, err := c.DelegateResource(owner, receiver, core.ResourceCode_ENERGY, balanceSun, false, 0)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create delegate transaction: %w", err)
+	}
+	if tx.Result != nil && tx.Result.Code > 0 {
+		return "", 0, fmt.Errorf("failed to create delegate transaction: %s", string(tx.Result.Message))
+	}
+
+	txID, err := signAndBroadcast(c, tx, privateKey)
+	return txID, balanceSun, err
+}
+
+// UndelegateEnergy takes back energy previously delegated with DelegateEnergy.
+func UndelegateEnergy(c *client.GrpcClient, owner, receiver string, balanceSun int64, privateKey string) (string, error) {
+	tx, err := c.UnDelegateResource(owner, receiver, core.ResourceCode_ENERGY, balanceSun)
+	if err != nil {
+		return "", fmt.Errorf("failed to create undelegate transaction: %w", err)
+	}
+	if tx.Result != nil && tx.Result.Code > 0 {
+		return "", fmt.Errorf("failed to create undelegate transaction: %s", string(tx.Result.Message))
+	}
+
+	return signAndBroadcast(c, tx, privateKey)
+}
+
+// GetTransactionInfo returns the on-chain info of a transaction, ErrTransactionNotFound while it
+// isn't in a block yet and ErrTransactionFailed when it was included but reverted.
+func GetTransactionInfo(c *client.GrpcClient, txID string) (*core.TransactionInfo, error) {
+	info, err := c.GetTransactionInfoByID(txID)
+	if err != nil || info.BlockNumber == 0 {
+		return nil, ErrTransactionNotFound
+	}
+
+	if info.Result == core.TransactionInfo_FAILED {
+		return info, fmt.Errorf("%w: %s", ErrTransactionFailed, string(info.ResMessage))
+	}
+
+	// plain transfers have no contract result, contract calls must report SUCCESS
+	if info.Receipt != nil && info.Receipt.Result != core.Transaction_Result_DEFAULT && info.Receipt.Result != core.Transaction_Result_SUCCESS {
+		return info, fmt.Errorf("%w: %s", ErrTransactionFailed, info.Receipt.Result.String())
+	}
+
+	return info, nil
+}
+
+// WaitForTransaction polls until the transaction is in a block, failed, or the timeout passes.
+func WaitForTransaction(c *client.GrpcClient, txID string, timeout time.Duration) (*core.TransactionInfo, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		info, err := GetTransactionInfo(c, txID)
+		if !errors.Is(err, ErrTransactionNotFound) {
+			return info, err
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		// a new block is produced every 3 seconds
+		time.Sleep(3 * time.Second)
+	}
+}
@@ -0,0 +1,189 @@
+package tron
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheByteArray/go-tron-sdk/pkg/address"
+	"github.com/TheByteArray/go-tron-sdk/pkg/client"
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/dto"
+)
+
+const (
+	tronGridPageSize = 200
+	// safety cap so a busy address can't keep the processor paging forever
+	tronGridMaxPages = 10
+)
+
+var tronGridClient = &http.Client{Timeout: 15 * time.Second}
+
+// IncomingTransfer is a transfer of TRX or a TRC20 token into a wallet.
+type IncomingTransfer struct {
+	TxHash      string
+	From        string
+	Amount      float64
+	BlockNumber int64
+	Timestamp   time.Time
+}
+
+// ListIncomingTRXTransfers returns successful TRX transfers into addr made at or after since.
+func ListIncomingTRXTransfers(addr string, since time.Time) ([]IncomingTransfer, error) {
+	walletAddr, err := address.Base58ToAddress(addr)
+	if err != nil {
+		return nil, ErrInvalidAddress
+	}
+
+	query := url.Values{}
+	query.Set("only_to", "true")
+	query.Set("min_timestamp", strconv.FormatInt(since.UnixMilli(), 10))
+
+	var transfers []IncomingTransfer
+	err = tronGridPages(fmt.Sprintf("/v1/accounts/%s/transactions", addr), query, func(body []byte) (string, error) {
+		var res dto.TronGridTransactionsResponse
+		if err := json.Unmarshal(body, &res); err != nil {
+			return "", fmt.Errorf("failed to decode transactions: %w", err)
+		}
+
+		for _, tx := range res.Data {
+			if len(tx.RawData.Contract) == 0 || len(tx.Ret) == 0 || tx.Ret[0].ContractRet != "SUCCESS" {
+				continue
+			}
+			contract := tx.RawData.Contract[0]
+			if contract.Type != "TransferContract" || !strings.EqualFold(contract.Parameter.Value.ToAddress, walletAddr.Hex()[2:]) {
+				continue
+			}
+
+			transfers = append(transfers, IncomingTransfer{
+				TxHash:      tx.TxID,
+				From:        address.HexToAddress(contract.Parameter.Value.OwnerAddress).String(),
+				Amount:      SunToTrx(contract.Parameter.Value.Amount),
+				BlockNumber: tx.BlockNumber,
+				Timestamp:   time.UnixMilli(tx.BlockTimestamp),
+			})
+		}
+		return res.Meta.Fingerprint, nil
+	})
+
+	return transfers, err
+}
+
+// ListIncomingTRC20Transfers returns token transfers of contractAddr into addr made at or after since.
+// TronGrid doesn't report block numbers for token transfers, so they are read from the node; transfers
+// whose block can't be found yet are left out and picked up on a later call.
+func ListIncomingTRC20Transfers(c *client.GrpcClient, addr, contractAddr string, since time.Time) ([]IncomingTransfer, error) {
+	query := url.Values{}
+	query.Set("only_to", "true")
+	query.Set("contract_address", contractAddr)
+	query.Set("min_timestamp", strconv.FormatInt(since.UnixMilli(), 10))
+
+	var transfers []IncomingTransfer
+	err := tronGridPages(fmt.Sprintf("/v1/accounts/%s/transactions/trc20", addr), query, func(body []byte) (string, error) {
+		var res dto.TronGridTRC20TransfersResponse
+		if err := json.Unmarshal(body, &res); err != nil {
+			return "", fmt.Errorf("failed to decode token transfers: %w", err)
+		}
+
+		for _, tr := range res.Data {
+			if tr.Type != "Transfer" || tr.To != addr {
+				continue
+			}
+
+			value, ok := new(big.Int).SetString(tr.Value, 10)
+			if !ok {
+				continue
+			}
+
+			// skips transfers not in a block yet and reverted calls that never moved funds
+			info, err := GetTransactionInfo(c, tr.TransactionID)
+			if err != nil {
+				continue
+			}
+
+			transfers = append(transfers, IncomingTransfer{
+				TxHash:      tr.TransactionID,
+				From:        tr.From,
+				Amount:      TokenUnitsToAmount(value, tr.TokenInfo.Decimals),
+				BlockNumber: info.BlockNumber,
+				Timestamp:   time.UnixMilli(tr.BlockTimestamp),
+			})
+		}
+		return res.Meta.Fingerprint, nil
+	})
+
+	return transfers, err
+}
+
+// tronGridPages GETs a TronGrid v1 list endpoint and hands every page to handle, which
+// returns the fingerprint of the next page or "" when there are no more.
+func tronGridPages(path string, query url.Values, handle func(body []byte) (string, error)) error {
+	baseURL, err := tronGridURLForEnv(config.Cfg.TRON_GRID_BASE_URL_MAINNET, config.Cfg.TRON_GRID_BASE_URL_TESTNET)
+	if err != nil {
+		return err
+	}
+
+	query.Set("limit", strconv.Itoa(tronGridPageSize))
+	query.Set("order_by", "block_timestamp,asc")
+
+	for page := 0; page < tronGridMaxPages; page++ {
+		req, err := http.NewRequest(http.MethodGet, baseURL+path+"?"+query.Encode(), nil)
+		if err != nil {
+			return fmt.Errorf("failed to build request: %w", err)
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("TRON-PRO-API-KEY", config.Cfg.TRON_GRID_API_KEY)
+
+		resp, err := tronGridClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("API request failed: %w", err)
+		}
+
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("TronGrid error (%d): %s", resp.StatusCode, string(body))
+		}
+
+		fingerprint, err := handle(body)
+		if err != nil {
+			return err
+		}
+		if fingerprint == "" {
+			return nil
+		}
+		query.Set("fingerprint", fingerprint)
+	}
+
+	return nil
+}
+
+// GetCurrentBlockNumber returns the number of the latest block the node has seen.
+func GetCurrentBlockNumber(c *client.GrpcClient) (int64, error) {
+	block, err := c.GetNowBlock()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest block: %w", err)
+	}
+	if block.GetBlockHeader().GetRawData() == nil {
+		return 0, fmt.Errorf("latest block has no header")
+	}
+	return block.GetBlockHeader().GetRawData().GetNumber(), nil
+}
+
+// Confirmations is the number of blocks from blockNumber up to and including currentBlock.
+func Confirmations(blockNumber, currentBlock int64) int64 {
+	if blockNumber <= 0 || currentBlock < blockNumber {
+		return 0
+	}
+	return currentBlock - blockNumber + 1
+}
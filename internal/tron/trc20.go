@@ -0,0 +1,90 @@
+package tron
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"sync"
+
+	"github.com/TheByteArray/go-tron-sdk/pkg/address"
+	"github.com/TheByteArray/go-tron-sdk/pkg/client"
+)
+
+// TRC20FeeLimitSun is the maximum amount of TRX (in sun) a token transfer may burn for energy.
+const TRC20FeeLimitSun = int64(30_000_000)
+
+// token decimals never change for a deployed contract, so keep them around
+var tokenDecimals sync.Map
+
+// GetTokenDecimals returns the decimals of a TRC20 contract.
+func GetTokenDecimals(c *client.GrpcClient, contractAddr string) (int64, error) {
+	if d, ok := tokenDecimals.Load(contractAddr); ok {
+		return d.(int64), nil
+	}
+
+	decimals, err := c.TRC20GetDecimals(contractAddr)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get token decimals: %w", err)
+	}
+
+	d := decimals.Int64()
+	tokenDecimals.Store(contractAddr, d)
+	return d, nil
+}
+
+// CheckTRC20Balance returns the token balance of addr by calling balanceOf on the contract.
+func CheckTRC20Balance(c *client.GrpcClient, addr, contractAddr string) (float64, error) {
+	if _, err := address.Base58ToAddress(addr); err != nil {
+		return float64(0), fmt.Errorf("invalid TRON address: %w", err)
+	}
+
+	decimals, err := GetTokenDecimals(c, contractAddr)
+	if err != nil {
+		return float64(0), err
+	}
+
+	balance, err := c.TRC20ContractBalance(addr, contractAddr)
+	if err != nil {
+		return float64(0), fmt.Errorf("failed to get token balance: %w", err)
+	}
+
+	return TokenUnitsToAmount(balance, decimals), nil
+}
+
+// SendTRC20 transfers amount tokens from one address to another with a TRC20 transfer call.
+func SendTRC20(c *client.GrpcClient, from, to, contractAddr string, amount float64, privateKey string) (string, error) {
+	fromAddr, err := address.Base58ToAddress(from)
+	if err != nil {
+		return "", fmt.Errorf("invalid from address: %w", err)
+	}
+	toAddr, err := address.Base58ToAddress(to)
+	if err != nil {
+		return "", fmt.Errorf("invalid to address: %w", err)
+	}
+
+	decimals, err := GetTokenDecimals(c, contractAddr)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := c.TRC20Send(fromAddr.String(), toAddr.String(), contractAddr, AmountToTokenUnits(amount, decimals), TRC20FeeLimitSun)
+	if err != nil {
+		return "", fmt.Errorf("failed to create token transfer transaction: %w", err)
+	}
+
+	return signAndBroadcast(c, tx, privateKey)
+}
+
+// AmountToTokenUnits converts a human readable token amount to the contract's base units.
+func AmountToTokenUnits(amount float64, decimals int64) *big.Int {
+	scaled := new(big.Float).Mul(big.NewFloat(amount), new(big.Float).SetFloat64(math.Pow10(int(decimals))))
+	// round to the nearest unit so float noise like 9.999999 doesn't lose a unit
+	units, _ := scaled.Add(scaled, big.NewFloat(0.5)).Int(nil)
+	return units
+}
+
+// TokenUnitsToAmount converts contract base units to a human readable token amount.
+func TokenUnitsToAmount(units *big.Int, decimals int64) float64 {
+	amount, _ := new(big.Float).Quo(new(big.Float).SetInt(units), new(big.Float).SetFloat64(math.Pow10(int(decimals)))).Float64()
+	return amount
+}
@@ -0,0 +1,51 @@
+package tron
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestAmountToTokenUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		decimals int64
+		want     string
+	}{
+		{"whole amount", 10, 6, "10000000"},
+		{"cents", 12.34, 6, "12340000"},
+		{"float noise rounds up", 0.1 + 0.2, 6, "300000"},
+		{"just below a unit keeps it", 9.999999, 6, "9999999"},
+		{"below half a unit rounds down", 1.0000004, 6, "1000000"},
+		{"above half a unit rounds up", 1.0000006, 6, "1000001"},
+		{"zero", 0, 6, "0"},
+		{"no decimals", 42, 0, "42"},
+		{"18 decimals", 1.5, 18, "1500000000000000000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := AmountToTokenUnits(tt.amount, tt.decimals)
+			if got.String() != tt.want {
+				t.Errorf("AmountToTokenUnits(%v, %d) = %s, want %s", tt.amount, tt.decimals, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenUnitsToAmount(t *testing.T) {
+	tests := []struct {
+		units    int64
+		decimals int64
+		want     float64
+	}{
+		{10000000, 6, 10},
+		{12340000, 6, 12.34},
+		{1, 6, 0.000001},
+		{0, 6, 0},
+	}
+	for _, tt := range tests {
+		if got := TokenUnitsToAmount(big.NewInt(tt.units), tt.decimals); got != tt.want {
+			t.Errorf("TokenUnitsToAmount(%d, %d) = %v, want %v", tt.units, tt.decimals, got, tt.want)
+		}
+	}
+}
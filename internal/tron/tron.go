@@ -9,7 +9,6 @@ import (
 	"io"
 	"math"
 	"net/http"
-	"strconv"
 	"strings"
 
 	"github.com/TheByteArray/go-tron-sdk/pkg/address"
@@ -26,6 +25,28 @@ var (
 	ErrInsufficientBalance = errors.New("insufficient balance to cover transaction fee")
 )
 
+const (
+	// trxTxSizeBytes is the bandwidth a signed TRX transfer consumes
+	trxTxSizeBytes = int64(300)
+	// trxTransferEnergyFeeSun is a rough allowance for the energy side of a TRX transfer
+	trxTransferEnergyFeeSun = int64(5_000) // ~0.005 TRX
+	// trxTransferBufferSun is left on top of the fee to make sure the transfer goes through
+	trxTransferBufferSun = int64(1_000) // 0.001 TRX
+)
+
+// ValidateAddress checks that addr is a base58 TRON address.
+func ValidateAddress(addr string) error {
+	if _, err := address.Base58ToAddress(addr); err != nil || !strings.HasPrefix(addr, "T") {
+		return ErrInvalidAddress
+	}
+	return nil
+}
+
+// MaxTRXTransferFeeSun is what a TRX transfer costs a wallet that has no bandwidth left.
+func MaxTRXTransferFeeSun() int64 {
+	return trxTxSizeBytes*sunPerBandwidthUnit + trxTransferEnergyFeeSun + trxTransferBufferSun
+}
+
 func CheckBalance(c *client.GrpcClient, addr string) (float64, error) {
 	tronAddr, err := address.Base58ToAddress(addr)
 	if err != nil {
@@ -58,13 +79,6 @@ func SendTRX(c *client.GrpcClient, from, to string, amountTRX float64, privateKe
 	// Convert TRX to sun (1 TRX = 1,000,000 sun)
 	amountSun := int64(amountTRX * 1_000_000)
 
-	// Parse the private key
-	btcecPrivKey, err := keys.GetPrivateKeyFromHex(privateKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse private key: %w", err)
-	}
-	ecdsaPrivKey := btcecPrivKey.ToECDSA()
-
 	// Convert from and to addresses
 	fromAddr, err := address.Base58ToAddress(from)
 	if err != nil {
@@ -81,6 +95,18 @@ func SendTRX(c *client.GrpcClient, from, to string, amountTRX float64, privateKe
 		return "", fmt.Errorf("failed to create transfer transaction: %w", err)
 	}
 
+	return signAndBroadcast(c, tx, privateKey)
+}
+
+// signAndBroadcast signs tx with the hex private key, broadcasts it and returns the transaction ID.
+func signAndBroadcast(c *client.GrpcClient, tx *api.TransactionExtention, privateKey string) (string, error) {
+	// Parse the private key
+	btcecPrivKey, err := keys.GetPrivateKeyFromHex(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse private key: %w", err)
+	}
+	ecdsaPrivKey := btcecPrivKey.ToECDSA()
+
 	// Sign the transaction
 	signedTx, err := transaction.SignTransactionECDSA(tx.Transaction, ecdsaPrivKey)
 	if err != nil {
@@ -100,61 +126,75 @@ func SendTRX(c *client.GrpcClient, from, to string, amountTRX float64, privateKe
 	return hex.EncodeToString(tx.Txid), nil
 }
 
-func ConvertUSDToTRX(usdAmount float64) (float64, error) {
-	// Fetch current TRX/USDT price from Binance
-	resp, err := http.Get(config.Cfg.BINANCE_API_URL)
+func GetTransferableAmount(walletAddress string, balanceTRX float64) (float64, error) {
+	res, err := getAccountResource(walletAddress)
 	if err != nil {
-		return 0, fmt.Errorf("failed to fetch price: %w", err)
+		return 0, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("non-200 response from Binance: %d", resp.StatusCode)
+	balanceSun := int64(balanceTRX * 1_000_000)
+
+	// free daily bandwidth plus any bandwidth staked for or delegated to the wallet
+	freeBandwidth := max(res.FreeNetLimit-res.FreeNetUsed, 0) + max(res.NetLimit-res.NetUsed, 0)
+
+	chargeableBandwidth := trxTxSizeBytes - freeBandwidth
+	if chargeableBandwidth < 0 {
+		chargeableBandwidth = 0
 	}
 
-	var priceResp dto.PriceResponse
-	if err := json.NewDecoder(resp.Body).Decode(&priceResp); err != nil {
-		return 0, fmt.Errorf("failed to decode response: %w", err)
+	// Calculate bandwidth fee
+	bandwidthFeeSun := chargeableBandwidth * sunPerBandwidthUnit
+
+	// Energy fee estimation (for simple TRX transfer, energy usage is minimal)
+	energyFeeSun := trxTransferEnergyFeeSun
+
+	// Total transaction fee (exact calculation to leave 0 balance)
+	totalFeeSun := bandwidthFeeSun + energyFeeSun
+
+	// Add minimal buffer only to ensure transaction success
+	totalCostSun := totalFeeSun + trxTransferBufferSun
+
+	if balanceSun <= totalCostSun {
+		return 0, ErrInsufficientBalance
 	}
 
-	// Convert string price to float
-	price, err := strconv.ParseFloat(priceResp.Price, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid price format: %w", err)
+	// Calculate transferable amount to leave exactly 0 balance after transaction
+	transferableSun := balanceSun - totalCostSun
+
+	if transferableSun <= 0 {
+		return 0, ErrInsufficientBalance
 	}
 
-	// Calculate and return TRX amount
-	trxAmount := usdAmount / price
-	return trxAmount, nil
+	// Convert back to TRX with proper precision handling
+	transferableTRX := float64(transferableSun) / 1_000_000
+
+	// Round down to 6 decimal places to match TRX precision
+	// This ensures we transfer the maximum possible amount while leaving ~0 balance
+	return math.Floor(transferableTRX*1e6) / 1e6, nil
 }
 
-func GetTransferableAmount(walletAddress string, balanceTRX float64) (float64, error) {
+// getAccountResource fetches the bandwidth and energy of a wallet from TronGrid.
+func getAccountResource(walletAddress string) (dto.AccountResourceResponse, error) {
 	if !strings.HasPrefix(walletAddress, "T") {
-		return 0, ErrInvalidAddress
+		return dto.AccountResourceResponse{}, ErrInvalidAddress
 	}
 
 	apiKey := config.Cfg.TRON_GRID_API_KEY
-	env := strings.ToLower(config.Cfg.APP_ENV)
 
-	var tronGridURL string
-	switch env {
-	case "production":
-		tronGridURL = config.Cfg.TRON_GRID_API_URL_MAINNET
-	case "development", "staging", "test":
-		tronGridURL = config.Cfg.TRON_GRID_API_URL_TESTNET
-	default:
-		return 0, fmt.Errorf("unsupported APP_ENV: %s", config.Cfg.APP_ENV)
+	tronGridURL, err := tronGridURLForEnv(config.Cfg.TRON_GRID_API_URL_MAINNET, config.Cfg.TRON_GRID_API_URL_TESTNET)
+	if err != nil {
+		return dto.AccountResourceResponse{}, err
 	}
 
 	reqBody := dto.AccountResourceRequest{Address: walletAddress}
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return 0, fmt.Errorf("failed to encode request: %w", err)
+		return dto.AccountResourceResponse{}, fmt.Errorf("failed to encode request: %w", err)
 	}
 
 	req, err := http.NewRequest("POST", tronGridURL, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return 0, fmt.Errorf("failed to build request: %w", err)
+		return dto.AccountResourceResponse{}, fmt.Errorf("failed to build request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -163,68 +203,33 @@ func GetTransferableAmount(walletAddress string, balanceTRX float64) (float64, e
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return 0, fmt.Errorf("API request failed: %w", err)
+		return dto.AccountResourceResponse{}, fmt.Errorf("API request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("TronGrid error (%d): %s", resp.StatusCode, string(bodyBytes))
+		return dto.AccountResourceResponse{}, fmt.Errorf("TronGrid error (%d): %s", resp.StatusCode, string(bodyBytes))
 	}
 
 	var res dto.AccountResourceResponse
 	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
-		return 0, fmt.Errorf("failed to decode response: %w", err)
-	}
-
-	balanceSun := int64(balanceTRX * 1_000_000)
-
-	const (
-		trxTxSizeBytes      = int64(300)
-		sunPerBandwidthUnit = int64(1000)
-	)
-
-	freeBandwidth := res.FreeNetLimit - res.FreeNetUsed
-	if freeBandwidth < 0 {
-		freeBandwidth = 0
-	}
-
-	chargeableBandwidth := trxTxSizeBytes - freeBandwidth
-	if chargeableBandwidth < 0 {
-		chargeableBandwidth = 0
-	}
-
-	// Calculate bandwidth fee
-	bandwidthFeeSun := chargeableBandwidth * sunPerBandwidthUnit
-
-	// Energy fee estimation (for simple TRX transfer, energy usage is minimal)
-	energyFeeSun := int64(5_000) // ~0.005 TRX for energy costs
-
-	// Total transaction fee (exact calculation to leave 0 balance)
-	totalFeeSun := bandwidthFeeSun + energyFeeSun
-
-	// Add minimal buffer only to ensure transaction success
-	const minimalBuffer = int64(1_000) // 0.001 TRX minimal buffer
-
-	totalCostSun := totalFeeSun + minimalBuffer
-
-	if balanceSun <= totalCostSun {
-		return 0, ErrInsufficientBalance
+		return dto.AccountResourceResponse{}, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	// Calculate transferable amount to leave exactly 0 balance after transaction
-	transferableSun := balanceSun - totalCostSun
+	return res, nil
+}
 
-	if transferableSun <= 0 {
-		return 0, ErrInsufficientBalance
+// tronGridURLForEnv picks the mainnet or testnet TronGrid URL for the current APP_ENV.
+func tronGridURLForEnv(mainnet, testnet string) (string, error) {
+	switch strings.ToLower(config.Cfg.APP_ENV) {
+	case "production":
+		return mainnet, nil
+	case "development", "staging", "test":
+		return testnet, nil
+	default:
+		return "", fmt.Errorf("unsupported APP_ENV: %s", config.Cfg.APP_ENV)
 	}
-
-	// Convert back to TRX with proper precision handling
-	transferableTRX := float64(transferableSun) / 1_000_000
-
-	// Round down to 6 decimal places to match TRX precision
-	// This ensures we transfer the maximum possible amount while leaving ~0 balance
-	return math.Floor(transferableTRX*1e6) / 1e6, nil
 }
 
 func TrxToSun(trx float64) int64 {
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+type APIKeyScope string
+
+const (
+	ScopePaymentsCreate    APIKeyScope = "payments:create"
+	ScopePaymentsRead      APIKeyScope = "payments:read"
+	ScopeRefundsWrite      APIKeyScope = "refunds:write"
+	ScopeSubscriptionsRead APIKeyScope = "subscriptions:read"
+)
+
+// APIKey authenticates a merchant server against the public API. Only the SHA-256 of the key is
+// stored, Prefix is its first characters so admins can tell keys apart. Rotating a key replaces
+// the secret under the same record, revoking it keeps the record for the audit trail.
+type APIKey struct {
+	ID         string     `gorm:"type:char(27);primaryKey" json:"id"`
+	Name       string     `gorm:"size:100;not null" json:"name"`
+	Prefix     string     `gorm:"size:20;not null" json:"prefix"`
+	KeyHash    string     `gorm:"size:64;uniqueIndex;not null" json:"-"`
+	Scopes     string     `gorm:"type:text" json:"scopes"` // comma separated
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RotatedAt  *time.Time `json:"rotated_at"`
+	RevokedAt  *time.Time `gorm:"index" json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
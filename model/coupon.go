@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+type CouponType string
+
+const (
+	CouponPercent CouponType = "percent" // Value percent off the price
+	CouponFixed   CouponType = "fixed"   // Value off the price, in Currency
+)
+
+// Coupon is a discount code customers enter when they create a payment for a plan. A payment made
+// with it is a redemption, cancelled and expired payments give theirs back. The payment keeps the
+// price before the discount and the discount itself, so editing a coupon never changes them.
+type Coupon struct {
+	ID             string     `gorm:"type:char(27);primaryKey" json:"id"`
+	Code           string     `gorm:"size:50;uniqueIndex;not null" json:"code"` // upper-case, customers may type it in any case
+	Description    string     `gorm:"size:255" json:"description"`
+	Type           CouponType `gorm:"type:varchar(10);not null" json:"type"`
+	Value          float64    `gorm:"not null" json:"value"`
+	Currency       string     `gorm:"type:char(3)" json:"currency"` // of fixed discounts, converted to the currency a payment is quoted in
+	PlanIDs        string     `gorm:"type:text" json:"plan_ids"`    // comma separated, empty for every plan
+	StartsAt       *time.Time `json:"starts_at"`
+	EndsAt         *time.Time `json:"ends_at"`
+	MaxRedemptions int        `gorm:"default:0" json:"max_redemptions"` // 0 is unlimited
+	PerEmailLimit  int        `gorm:"default:0" json:"per_email_limit"` // redemptions per customer email, 0 is unlimited
+	Enabled        bool       `gorm:"default:true" json:"enabled"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
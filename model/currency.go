@@ -7,6 +7,12 @@ type Currency struct {
 	IsToken      bool   `gorm:"default:false" json:"is_token"`
 	ContractAddr string `gorm:"size:50" json:"contract_addr"`
 	Enabled      bool   `gorm:"default:true" json:"enabled"`
+	// blocks a deposit has to be buried under before it counts, TRON solidifies a block after 19
+	Confirmations int64 `gorm:"default:19" json:"confirmations"`
+	// balance a deposit wallet needs before the "threshold" sweep policy sweeps it
+	SweepThreshold float64 `gorm:"default:0" json:"sweep_threshold"`
+	// minutes a payment in the currency stays open, 0 uses PAYMENT_EXPIRY_MINUTES
+	ExpiryMinutes int64 `gorm:"default:0" json:"expiry_minutes"`
 	// For compatibility
 	Symbol   string `gorm:"-" json:"symbol"`
 	IsActive bool   `gorm:"-" json:"is_active"`
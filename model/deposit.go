@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+type DepositStatus string
+
+const (
+	DepositDetected   DepositStatus = "detected"
+	DepositConfirming DepositStatus = "confirming"
+	DepositConfirmed  DepositStatus = "confirmed"
+)
+
+// Deposit is an on-chain transfer into a deposit wallet that was credited to a payment.
+type Deposit struct {
+	ID             string        `gorm:"type:char(27);primaryKey" json:"id"`
+	PaymentID      string        `gorm:"type:char(27);index;not null" json:"payment_id"`
+	WalletAddress  string        `gorm:"size:50;index;not null" json:"wallet_address"`
+	CurrencyCode   string        `gorm:"size:10;not null" json:"currency_code"`
+	TxHash         string        `gorm:"size:64;uniqueIndex;not null" json:"tx_hash"`
+	FromAddress    string        `gorm:"size:50" json:"from_address"`
+	Amount         float64       `gorm:"not null" json:"amount"`
+	BlockNumber    int64         `gorm:"not null" json:"block_number"`
+	BlockTimestamp time.Time     `json:"block_timestamp"`
+	Confirmations  int64         `gorm:"default:0" json:"confirmations"`
+	Status         DepositStatus `gorm:"type:varchar(20);default:'detected'" json:"status"`
+	// set once the customer was emailed about the shortfall left after this deposit
+	UnderpaymentNotifiedAt *time.Time `json:"underpayment_notified_at"`
+	CreatedAt              time.Time  `json:"created_at"`
+	UpdatedAt              time.Time  `json:"updated_at"`
+}
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// DestinationWallet is a wallet swept funds are sent to, like cold storage or an operations wallet.
+type DestinationWallet struct {
+	ID        string    `gorm:"type:char(27);primaryKey" json:"id"`
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	Address   string    `gorm:"size:50;not null" json:"address"`
+	Enabled   bool      `gorm:"default:true" json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SweepRule decides where the funds of a payment are swept to. A rule matches payments of its
+// currency and plan, an empty CurrencyCode or PlanID matches any. The most specific enabled rule
+// wins, Priority breaks ties. Payments no rule matches go to TRX_HOT_WALLET_ADDRESS.
+type SweepRule struct {
+	ID           string       `gorm:"type:char(27);primaryKey" json:"id"`
+	Name         string       `gorm:"size:100;not null" json:"name"`
+	CurrencyCode string       `gorm:"size:10;index" json:"currency_code"`
+	PlanID       string       `gorm:"type:char(27);index" json:"plan_id"`
+	Priority     int          `gorm:"default:0" json:"priority"`
+	Enabled      bool         `gorm:"default:true" json:"enabled"`
+	Splits       []SweepSplit `gorm:"foreignKey:RuleID;constraint:OnDelete:CASCADE" json:"splits"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// SweepSplit sends Percent of a swept balance to a destination wallet, the splits of a rule add up to 100.
+type SweepSplit struct {
+	ID            string            `gorm:"type:char(27);primaryKey" json:"id"`
+	RuleID        string            `gorm:"type:char(27);index;not null" json:"rule_id"`
+	DestinationID string            `gorm:"type:char(27);index;not null" json:"destination_id"`
+	Destination   DestinationWallet `gorm:"foreignKey:DestinationID" json:"destination"`
+	Percent       float64           `gorm:"not null" json:"percent"`
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// IdempotencyKey is the outcome of a request sent with an Idempotency-Key header, replayed to
+// retries of the same request until ExpiresAt. StatusCode stays 0 while the first request runs.
+type IdempotencyKey struct {
+	ID          string    `gorm:"type:char(64);primaryKey" json:"id"` // SHA-256 of the caller's API key and the header
+	Method      string    `gorm:"size:10;not null" json:"method"`
+	Path        string    `gorm:"size:255;not null" json:"path"`
+	RequestHash string    `gorm:"size:64;not null" json:"request_hash"` // SHA-256 of method, path and body
+	StatusCode  int       `json:"status_code"`
+	Response    string    `gorm:"type:mediumtext" json:"response"`
+	ExpiresAt   time.Time `gorm:"index" json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
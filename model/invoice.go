@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Invoice is a one-off payment for an arbitrary amount instead of a plan. It is paid through its
+// payment like any other, the invoice only records what is being sold. Amount is the total of its
+// line items in Currency, or the amount given when it has none.
+type Invoice struct {
+	ID          string            `gorm:"type:char(27);primaryKey" json:"id"`
+	PaymentID   string            `gorm:"type:char(27);uniqueIndex;not null" json:"payment_id"`
+	Email       string            `gorm:"size:255;index;not null" json:"email"`
+	Description string            `gorm:"type:text" json:"description"`
+	Currency    string            `gorm:"type:char(3);not null;default:'USD'" json:"currency"` // ISO-4217
+	Amount      float64           `gorm:"not null" json:"amount"`
+	AmountUSD   float64           `gorm:"not null" json:"amount_usd"` // Amount at the FX rate it was issued at
+	LineItems   []InvoiceLineItem `gorm:"foreignKey:InvoiceID" json:"line_items"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// InvoiceLineItem is priced in the currency of its invoice.
+type InvoiceLineItem struct {
+	ID          string  `gorm:"type:char(27);primaryKey" json:"id"`
+	InvoiceID   string  `gorm:"type:char(27);index;not null" json:"invoice_id"`
+	Position    int     `gorm:"not null" json:"position"`
+	Description string  `gorm:"size:255;not null" json:"description"`
+	Quantity    float64 `gorm:"not null" json:"quantity"`
+	UnitPrice   float64 `gorm:"not null" json:"unit_price"`
+	Amount      float64 `gorm:"not null" json:"amount"`
+}
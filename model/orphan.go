@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+type OrphanStatus string
+
+const (
+	OrphanOpen     OrphanStatus = "open" // waiting for an admin to decide what happens to it
+	OrphanAttached OrphanStatus = "attached"
+	OrphanRefunded OrphanStatus = "refunded"
+	OrphanSwept    OrphanStatus = "swept" // swept as unallocated funds
+)
+
+// OrphanDeposit is a transfer into a deposit wallet that no payment was waiting for, found after
+// the wallet's last payment expired, was cancelled or completed. An admin attaches it to a
+// payment, refunds it or sweeps it as unallocated. Refunds and sweeps are sent from the deposit
+// wallet as a sweep batch and SweepStatus follows that batch.
+type OrphanDeposit struct {
+	ID             string             `gorm:"type:char(27);primaryKey" json:"id"`
+	WalletID       string             `gorm:"type:char(27);index;not null" json:"wallet_id"`
+	Wallet         Wallet             `gorm:"foreignKey:WalletID" json:"-"`
+	WalletAddress  string             `gorm:"size:50;index;not null" json:"wallet_address"`
+	CurrencyCode   string             `gorm:"size:10;not null" json:"currency_code"`
+	Currency       Currency           `gorm:"foreignKey:CurrencyCode;references:Code" json:"-"`
+	TxHash         string             `gorm:"size:64;uniqueIndex;not null" json:"tx_hash"`
+	FromAddress    string             `gorm:"size:50" json:"from_address"`
+	Amount         float64            `gorm:"not null" json:"amount"`
+	BlockNumber    int64              `gorm:"not null" json:"block_number"`
+	BlockTimestamp time.Time          `json:"block_timestamp"`
+	LastPaymentID  string             `gorm:"type:char(27);index" json:"last_payment_id"` // latest payment of the wallet when it was found
+	Status         OrphanStatus       `gorm:"type:varchar(20);index;default:'open'" json:"status"`
+	PaymentID      string             `gorm:"type:char(27);index" json:"payment_id"` // payment it was attached to
+	RefundAddress  string             `gorm:"size:50" json:"refund_address"`
+	SweepBatchID   string             `gorm:"type:char(27);index" json:"sweep_batch_id"`
+	SweepStatus    PaymentSweepStatus `gorm:"type:varchar(20)" json:"sweep_status"`
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+}
@@ -9,25 +9,73 @@ const (
 	Completed PaymentStatus = "completed"
 	Cancelled PaymentStatus = "cancelled"
 	Expired   PaymentStatus = "expired"
+	// received less than it's due, stays open for PARTIAL_PAYMENT_GRACE_MINUTES for the rest
+	PartiallyPaid PaymentStatus = "partially_paid"
+	// expired payment that received a deposit afterwards, waiting for an admin to accept or reject it
+	LatePayment PaymentStatus = "late_payment"
+)
+
+type PaymentSweepStatus string
+
+const (
+	PaymentUnswept     PaymentSweepStatus = "unswept"
+	PaymentSweeping    PaymentSweepStatus = "sweeping"
+	PaymentSwept       PaymentSweepStatus = "swept"
+	PaymentSweepFailed PaymentSweepStatus = "sweep_failed" // gave up retrying, needs manual review
 )
 
 type Payment struct {
 	ID     string `gorm:"type:char(27);primaryKey"`
-	PlanID string `gorm:"not null"`          // FK field
+	PlanID string `gorm:"not null"`          // FK field, empty on invoices
 	Plan   Plan   `gorm:"foreignKey:PlanID"` // Assoc
 
+	Invoice *Invoice `gorm:"foreignKey:PaymentID"` // set when the payment is for an invoice instead of a plan
+
 	WalletID string `gorm:"not null"`            // FK field
 	Wallet   Wallet `gorm:"foreignKey:WalletID"` // Assoc
 
 	CurrencyCode string   `gorm:"size:10;not null"`                        // FK field
 	Currency     Currency `gorm:"foreignKey:CurrencyCode;references:Code"` // Assoc
 
-	AmountUSD float64 `gorm:"not null"`
-	AmountTRX float64 `gorm:"not null"`
+	AmountUSD float64 `gorm:"not null"` // USD value of the price, FiatAmount converted at FXRate
+	AmountTRX float64 `gorm:"not null"` // amount due in CurrencyCode units (TRX or TRC20 token)
 	UserEmail string  `gorm:"not null"`
 
-	Status        PaymentStatus `gorm:"type:varchar(20);default:'pending'"` // enum-like string
-	PaidAmountTRX float64       `gorm:"default:0"`
+	// the fiat price the customer was quoted, it stays fixed when the payment is re-quoted
+	FiatCurrency string  `gorm:"type:char(3);not null;default:'USD'"` // ISO-4217
+	FiatAmount   float64 `gorm:"default:0"`                           // 0 on payments made before fiat pricing, AmountUSD is the price
+	FXRate       float64 `gorm:"default:1"`                           // USD value of one FiatCurrency unit
+
+	// coupon the customer redeemed, FiatAmount is OriginalAmount less DiscountAmount
+	CouponID       string  `gorm:"type:char(27);index"`
+	CouponCode     string  `gorm:"size:50"`
+	OriginalAmount float64 `gorm:"default:0"` // fiat price before the discount, 0 without a coupon
+	DiscountAmount float64 `gorm:"default:0"` // in FiatCurrency
+
+	// the exchange rate AmountTRX was quoted at, honoured until QuoteExpiresAt
+	QuoteRate      float64 // USD price of one CurrencyCode unit
+	QuoteSource    string  `gorm:"size:100"`
+	QuotedAt       *time.Time
+	QuoteExpiresAt *time.Time `gorm:"index"`
+	RequoteCount   int        `gorm:"default:0"`
+
+	ExpiresAt *time.Time `gorm:"index"` // end of the payment window, later deposits put the payment under review
+
+	SubscriptionID string `gorm:"type:char(27);index"`                                              // set on renewal invoices, the subscription they renew
+	APIKeyID       string `gorm:"type:char(27);index;uniqueIndex:idx_payment_reference,priority:1"` // merchant API key the payment was created with, empty for customers
+
+	// the merchant's own order context, echoed back in responses and webhooks
+	ExternalReference *string `gorm:"size:100;uniqueIndex:idx_payment_reference,priority:2"` // unique per API key, nil when not given
+	Metadata          string  `gorm:"type:text"`                                             // JSON object
+	SuccessURL        string  `gorm:"size:500"`                                              // overrides CHECKOUT_SUCCESS_URL
+	CancelURL         string  `gorm:"size:500"`                                              // overrides CHECKOUT_CANCEL_URL
+
+	Status          PaymentStatus `gorm:"type:varchar(20);default:'pending'"` // enum-like string
+	PaidAmountTRX   float64       `gorm:"default:0"`
+	RemainingAmount float64       `gorm:"default:0"` // still due in CurrencyCode units, 0 once completed
+
+	SweepStatus  PaymentSweepStatus `gorm:"type:varchar(20);index"` // empty until the payment completes
+	SweepBatchID string             `gorm:"type:char(27);index"`    // sweeps that moved the funds, shared by payments of one wallet
 
 	CreatedAt time.Time
 	UpdatedAt time.Time
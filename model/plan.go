@@ -4,6 +4,20 @@ type Plan struct {
 	ID           string  `gorm:"type:char(27);primaryKey" json:"id"`
 	Name         string  `gorm:"not null" json:"name"`
 	Description  string  `gorm:"type:text" json:"description"`
-	PriceUSD     float64 `gorm:"not null" json:"price_usd"`
+	PriceUSD     float64 `gorm:"not null;default:0" json:"price_usd"` // 0 on plans priced in another currency only
 	DurationDays int64   `gorm:"not null" json:"duration_days"`
+	// minutes a payment for the plan stays open, 0 uses the currency's window
+	ExpiryMinutes int64 `gorm:"default:0" json:"expiry_minutes"`
+	// ISO-4217 currency the plan is priced in, customers are quoted in it unless they ask for another
+	Currency string `gorm:"type:char(3);not null;default:'USD'" json:"currency"`
+	// fixed prices in other currencies, any currency without one is converted from the plan's price
+	Prices []PlanPrice `gorm:"foreignKey:PlanID;constraint:OnDelete:CASCADE" json:"prices"`
+}
+
+// PlanPrice is the price of a plan in one fiat currency, USD prices live in Plan.PriceUSD.
+type PlanPrice struct {
+	ID       string  `gorm:"type:char(27);primaryKey" json:"id"`
+	PlanID   string  `gorm:"type:char(27);not null;uniqueIndex:idx_plan_price_currency" json:"plan_id"`
+	Currency string  `gorm:"type:char(3);not null;uniqueIndex:idx_plan_price_currency" json:"currency"`
+	Amount   float64 `gorm:"not null" json:"amount"`
 }
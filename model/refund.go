@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+type RefundStatus string
+
+const (
+	RefundAwaitingAddress RefundStatus = "awaiting_address" // link sent, the customer hasn't given an address yet
+	RefundRequested       RefundStatus = "requested"        // address given, waiting for an admin to approve it
+	RefundApproved        RefundStatus = "approved"         // waiting for the refund job to send it
+	RefundSent            RefundStatus = "sent"             // broadcast, waiting for its receipt
+	RefundConfirmed       RefundStatus = "confirmed"
+	RefundRejected        RefundStatus = "rejected"
+	RefundFailed          RefundStatus = "failed" // gave up sending, an admin can retry it
+)
+
+type RefundReason string
+
+const (
+	RefundOverpayment RefundReason = "overpayment"
+	RefundCancelled   RefundReason = "cancelled" // the payment was cancelled after funds arrived
+	RefundManual      RefundReason = "manual"    // created by an admin
+)
+
+// Refund sends funds of a payment back to the customer from the hot wallet, to an address the
+// customer gives through a signed link.
+type Refund struct {
+	ID            string       `gorm:"type:char(27);primaryKey" json:"id"`
+	PaymentID     string       `gorm:"type:char(27);index;not null" json:"payment_id"`
+	CurrencyCode  string       `gorm:"size:10;not null" json:"currency_code"`
+	Amount        float64      `gorm:"not null" json:"amount"`
+	Reason        RefundReason `gorm:"type:varchar(20);not null" json:"reason"`
+	Status        RefundStatus `gorm:"type:varchar(20);index;default:'awaiting_address'" json:"status"`
+	ToAddress     string       `gorm:"size:50" json:"to_address"`
+	TxID          string       `gorm:"size:64;index" json:"tx_id"`
+	FeeSun        int64        `json:"fee_sun"` // TRX burnt by the transaction, read from its receipt
+	Attempts      int          `gorm:"default:0" json:"attempts"`
+	LastError     string       `gorm:"type:text" json:"last_error"`
+	LinkExpiresAt time.Time    `json:"link_expires_at"`
+	RequestedAt   *time.Time   `json:"requested_at"`
+	ApprovedAt    *time.Time   `json:"approved_at"`
+	SentAt        *time.Time   `json:"sent_at"`
+	ConfirmedAt   *time.Time   `json:"confirmed_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+type SubscriptionStatus string
+
+const (
+	SubscriptionActive  SubscriptionStatus = "active"
+	SubscriptionExpired SubscriptionStatus = "expired"
+	SubscriptionLapsed  SubscriptionStatus = "lapsed" // ended with its renewal invoice unpaid
+)
+
+// Subscription is the entitlement an email has to a plan, built from its completed payments.
+// Every payment adds a period of the plan's DurationDays, stacked after EndsAt while the
+// subscription is active. StartsAt is the start of the current uninterrupted run, a payment made
+// after the subscription expired starts a new one.
+type Subscription struct {
+	ID             string             `gorm:"type:char(27);primaryKey" json:"id"`
+	Email          string             `gorm:"size:255;not null;uniqueIndex:idx_subscription_email_plan" json:"email"`
+	PlanID         string             `gorm:"type:char(27);not null;uniqueIndex:idx_subscription_email_plan" json:"plan_id"`
+	Plan           Plan               `gorm:"foreignKey:PlanID" json:"plan"`
+	StartsAt       time.Time          `json:"starts_at"`
+	EndsAt         time.Time          `gorm:"index" json:"ends_at"`
+	Status         SubscriptionStatus `gorm:"type:varchar(20);index;default:'active'" json:"status"`
+	ReminderSentAt *time.Time         `json:"reminder_sent_at"` // expiry reminder of the current EndsAt
+	// renewal invoice of the current EndsAt, cleared when a payment extends the subscription
+	RenewalPaymentID string               `gorm:"type:char(27)" json:"renewal_payment_id"`
+	Periods          []SubscriptionPeriod `gorm:"foreignKey:SubscriptionID" json:"periods,omitempty"`
+	CreatedAt        time.Time            `json:"created_at"`
+	UpdatedAt        time.Time            `json:"updated_at"`
+}
+
+// SubscriptionPeriod is the time one payment bought, a payment is only ever credited once.
+type SubscriptionPeriod struct {
+	ID             string    `gorm:"type:char(27);primaryKey" json:"id"`
+	SubscriptionID string    `gorm:"type:char(27);index;not null" json:"subscription_id"`
+	PaymentID      string    `gorm:"type:char(27);uniqueIndex;not null" json:"payment_id"`
+	StartsAt       time.Time `json:"starts_at"`
+	EndsAt         time.Time `json:"ends_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,40 @@
+package model
+
+import "time"
+
+type SweepStatus string
+
+const (
+	SweepPending      SweepStatus = "pending" // broadcast, waiting for its receipt
+	SweepConfirmed    SweepStatus = "confirmed"
+	SweepFailed       SweepStatus = "failed" // reverted or dropped, retried by the sweep job
+	SweepManualReview SweepStatus = "manual_review"
+)
+
+// Sweep records one transfer out of a deposit wallet. A wallet is swept in a batch of sweeps, one
+// per split of the sweep rule that applied (a single one to the hot wallet when no rule matched).
+// A batch can carry the funds of several payments made to the same wallet, PaymentID is the first
+// of them and all of them point to the batch through Payment.SweepBatchID. Sweeps refunding or
+// moving an orphaned deposit carry its OrphanID and the wallet's last payment as PaymentID. A
+// failed sweep is re-sent under the same record, TxID always holds the latest attempt.
+type Sweep struct {
+	ID            string      `gorm:"type:char(27);primaryKey" json:"id"`
+	BatchID       string      `gorm:"type:char(27);index" json:"batch_id"`
+	PaymentID     string      `gorm:"type:char(27);index;not null" json:"payment_id"`
+	OrphanID      string      `gorm:"type:char(27);index" json:"orphan_id"`
+	FromAddress   string      `gorm:"size:50;index;not null" json:"from_address"`
+	ToAddress     string      `gorm:"size:50;not null" json:"to_address"`
+	RuleID        string      `gorm:"type:char(27);index" json:"rule_id"`
+	DestinationID string      `gorm:"type:char(27)" json:"destination_id"`
+	Percent       float64     `json:"percent"` // share of the swept balance, copied from the split at sweep time
+	CurrencyCode  string      `gorm:"size:10;not null" json:"currency_code"`
+	Amount        float64     `gorm:"not null" json:"amount"`
+	FeeSun        int64       `json:"fee_sun"` // TRX burnt by the transaction, read from its receipt
+	TxID          string      `gorm:"size:64;index" json:"tx_id"`
+	Status        SweepStatus `gorm:"type:varchar(20);index;default:'pending'" json:"status"`
+	Attempts      int         `gorm:"default:0" json:"attempts"`
+	LastError     string      `gorm:"type:text" json:"last_error"`
+	ConfirmedAt   *time.Time  `json:"confirmed_at"`
+	CreatedAt     time.Time   `json:"created_at"`
+	UpdatedAt     time.Time   `json:"updated_at"`
+}
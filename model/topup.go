@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+type TopUpStatus string
+
+const (
+	TopUpPending   TopUpStatus = "pending"
+	TopUpConfirmed TopUpStatus = "confirmed"
+	TopUpFailed    TopUpStatus = "failed"
+	TopUpReclaimed TopUpStatus = "reclaimed" // delegated energy taken back after the sweep
+)
+
+type TopUpMethod string
+
+const (
+	TopUpTransfer TopUpMethod = "transfer"
+	TopUpDelegate TopUpMethod = "delegate"
+)
+
+// TopUp records TRX sent (or energy delegated) from the hot wallet to a deposit
+// wallet so it can pay for a TRC20 sweep.
+type TopUp struct {
+	ID                string      `gorm:"type:char(27);primaryKey" json:"id"`
+	PaymentID         string      `gorm:"type:char(27);index;not null" json:"payment_id"`
+	WalletAddress     string      `gorm:"size:50;index;not null" json:"wallet_address"`
+	Method            TopUpMethod `gorm:"type:varchar(20);not null" json:"method"`
+	AmountSun         int64       `gorm:"not null" json:"amount_sun"` // TRX sent, or TRX stake delegated
+	EnergyRequired    int64       `json:"energy_required"`
+	BandwidthRequired int64       `json:"bandwidth_required"`
+	TxID              string      `gorm:"size:64" json:"tx_id"`
+	Status            TopUpStatus `gorm:"type:varchar(20);default:'pending'" json:"status"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+}
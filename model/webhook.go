@@ -0,0 +1,56 @@
+package model
+
+import "time"
+
+type WebhookEvent string
+
+const (
+	PaymentCreatedEvent   WebhookEvent = "payment.created"
+	PaymentUnderpaidEvent WebhookEvent = "payment.underpaid"
+	PaymentCompletedEvent WebhookEvent = "payment.completed"
+	PaymentExpiredEvent   WebhookEvent = "payment.expired"
+	PaymentRequotedEvent  WebhookEvent = "payment.requoted"
+	PaymentLateEvent      WebhookEvent = "payment.late"
+	PaymentCancelledEvent WebhookEvent = "payment.cancelled"
+	PaymentSweptEvent     WebhookEvent = "payment.swept"
+	PaymentRefundedEvent  WebhookEvent = "payment.refunded"
+)
+
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	DeliveryFailed    WebhookDeliveryStatus = "failed"
+)
+
+// WebhookEndpoint is a merchant URL that receives signed payment events. It only receives the
+// events of payments created with its API key, an endpoint without one gets those of payments
+// made without a key.
+type WebhookEndpoint struct {
+	ID        string    `gorm:"type:char(27);primaryKey" json:"id"`
+	APIKeyID  string    `gorm:"type:char(27);index;default:''" json:"api_key_id"`
+	URL       string    `gorm:"size:500;not null" json:"url"`
+	Secret    string    `gorm:"size:100;not null" json:"-"`
+	Events    string    `gorm:"type:text" json:"events"` // comma separated, empty means every event
+	Enabled   bool      `gorm:"default:true" json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one event sent (or to be sent) to one endpoint.
+type WebhookDelivery struct {
+	ID                 string                `gorm:"type:char(27);primaryKey" json:"id"`
+	EndpointID         string                `gorm:"type:char(27);index;not null" json:"endpoint_id"`
+	Event              WebhookEvent          `gorm:"type:varchar(50);not null" json:"event"`
+	PaymentID          string                `gorm:"type:char(27);index" json:"payment_id"`
+	Payload            string                `gorm:"type:text;not null" json:"payload"`
+	Status             WebhookDeliveryStatus `gorm:"type:varchar(20);index;default:'pending'" json:"status"`
+	Attempts           int                   `gorm:"default:0" json:"attempts"`
+	NextAttemptAt      *time.Time            `gorm:"index" json:"next_attempt_at"`
+	LastResponseStatus int                   `json:"last_response_status"`
+	LastError          string                `gorm:"type:text" json:"last_error"`
+	DeliveredAt        *time.Time            `json:"delivered_at"`
+	CreatedAt          time.Time             `json:"created_at"`
+	UpdatedAt          time.Time             `json:"updated_at"`
+}
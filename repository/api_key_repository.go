@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/thebytearray/BytePayments/model"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	CreateAPIKey(key *model.APIKey) error
+	GetAPIKeys() ([]model.APIKey, error)
+	GetAPIKeyByID(id string) (*model.APIKey, error)
+	GetAPIKeyByHash(hash string) (*model.APIKey, error)
+	UpdateAPIKey(key *model.APIKey) error
+	MarkAPIKeyUsed(id string, at time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db}
+}
+
+func (r *apiKeyRepository) CreateAPIKey(key *model.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *apiKeyRepository) GetAPIKeys() ([]model.APIKey, error) {
+	var keys []model.APIKey
+	res := r.db.Order("created_at DESC").Find(&keys)
+	return keys, res.Error
+}
+
+func (r *apiKeyRepository) GetAPIKeyByID(id string) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := r.db.First(&key, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) GetAPIKeyByHash(hash string) (*model.APIKey, error) {
+	var key model.APIKey
+	if err := r.db.First(&key, "key_hash = ?", hash).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) UpdateAPIKey(key *model.APIKey) error {
+	return r.db.Save(key).Error
+}
+
+// MarkAPIKeyUsed only touches last_used_at, so a request never overwrites a concurrent rotation.
+func (r *apiKeyRepository) MarkAPIKeyUsed(id string, at time.Time) error {
+	return r.db.Model(&model.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error
+}
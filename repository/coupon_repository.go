@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"github.com/thebytearray/BytePayments/model"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CouponRepository interface {
+	CreateCoupon(coupon *model.Coupon) error
+	GetCoupons() ([]model.Coupon, error)
+	GetCouponByID(id string) (*model.Coupon, error)
+	GetCouponByCode(code string) (*model.Coupon, error)
+	UpdateCoupon(coupon *model.Coupon) error
+	DeleteCoupon(id string) error
+	CountRedemptions(couponID, email string) (int64, error)
+	GetRedemptions(couponID string) ([]model.Payment, error)
+	RedeemCoupon(couponID string, payment model.Payment, check func(repo CouponRepository, coupon *model.Coupon) error) error
+}
+
+type couponRepository struct {
+	db *gorm.DB
+}
+
+func NewCouponRepository(db *gorm.DB) CouponRepository {
+	return &couponRepository{db}
+}
+
+func (r *couponRepository) CreateCoupon(coupon *model.Coupon) error {
+	return r.db.Create(coupon).Error
+}
+
+func (r *couponRepository) GetCoupons() ([]model.Coupon, error) {
+	var coupons []model.Coupon
+	res := r.db.Order("created_at DESC").Find(&coupons)
+	return coupons, res.Error
+}
+
+func (r *couponRepository) GetCouponByID(id string) (*model.Coupon, error) {
+	var coupon model.Coupon
+	if err := r.db.First(&coupon, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *couponRepository) GetCouponByCode(code string) (*model.Coupon, error) {
+	var coupon model.Coupon
+	if err := r.db.First(&coupon, "code = ?", code).Error; err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *couponRepository) UpdateCoupon(coupon *model.Coupon) error {
+	return r.db.Save(coupon).Error
+}
+
+func (r *couponRepository) DeleteCoupon(id string) error {
+	return r.db.Delete(&model.Coupon{}, "id = ?", id).Error
+}
+
+// CountRedemptions counts the payments made with the coupon that still hold a redemption, those
+// of email only when it's given.
+func (r *couponRepository) CountRedemptions(couponID, email string) (int64, error) {
+	var count int64
+	query := r.db.Model(&model.Payment{}).
+		Where("coupon_id = ? AND status NOT IN ?", couponID, []model.PaymentStatus{model.Cancelled, model.Expired})
+	if email != "" {
+		query = query.Where("user_email = ?", email)
+	}
+	err := query.Count(&count).Error
+	return count, err
+}
+
+func (r *couponRepository) GetRedemptions(couponID string) ([]model.Payment, error) {
+	var payments []model.Payment
+	res := r.db.Where("coupon_id = ?", couponID).Order("created_at DESC").Find(&payments)
+	return payments, res.Error
+}
+
+// RedeemCoupon creates the payment redeeming the coupon with the coupon's row locked, so
+// concurrent redemptions run one after another and check sees every redemption made before. check
+// is given a repository within the transaction.
+func (r *couponRepository) RedeemCoupon(couponID string, payment model.Payment, check func(repo CouponRepository, coupon *model.Coupon) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var coupon model.Coupon
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&coupon, "id = ?", couponID).Error; err != nil {
+			return err
+		}
+		if err := check(&couponRepository{tx}, &coupon); err != nil {
+			return err
+		}
+		return tx.Create(&payment).Error
+	})
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/thebytearray/BytePayments/model"
+	"gorm.io/gorm"
+)
+
+type DepositRepository interface {
+	CreateDeposit(deposit *model.Deposit) error
+	UpdateDeposit(deposit *model.Deposit) error
+	ExistsByTxHash(txHash string) (bool, error)
+	FindDepositsByPaymentId(paymentID string) ([]model.Deposit, error)
+	GetDeposits(paymentID string) ([]model.Deposit, error)
+	MarkUnderpaymentNotified(ids []string, notifiedAt time.Time) error
+}
+
+type depositRepository struct {
+	db *gorm.DB
+}
+
+func NewDepositRepository(db *gorm.DB) DepositRepository {
+	return &depositRepository{db}
+}
+
+func (r *depositRepository) CreateDeposit(deposit *model.Deposit) error {
+	return r.db.Create(deposit).Error
+}
+
+func (r *depositRepository) UpdateDeposit(deposit *model.Deposit) error {
+	return r.db.Save(deposit).Error
+}
+
+func (r *depositRepository) ExistsByTxHash(txHash string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Deposit{}).Where("tx_hash = ?", txHash).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *depositRepository) FindDepositsByPaymentId(paymentID string) ([]model.Deposit, error) {
+	var deposits []model.Deposit
+	res := r.db.Where("payment_id = ?", paymentID).Order("block_number ASC").Find(&deposits)
+	return deposits, res.Error
+}
+
+func (r *depositRepository) GetDeposits(paymentID string) ([]model.Deposit, error) {
+	var deposits []model.Deposit
+	query := r.db.Order("created_at DESC")
+	if paymentID != "" {
+		query = query.Where("payment_id = ?", paymentID)
+	}
+	res := query.Find(&deposits)
+	return deposits, res.Error
+}
+
+func (r *depositRepository) MarkUnderpaymentNotified(ids []string, notifiedAt time.Time) error {
+	return r.db.Model(&model.Deposit{}).Where("id IN ?", ids).Update("underpayment_notified_at", notifiedAt).Error
+}
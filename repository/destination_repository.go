@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"github.com/thebytearray/BytePayments/model"
+	"gorm.io/gorm"
+)
+
+type DestinationRepository interface {
+	CreateDestination(destination *model.DestinationWallet) error
+	GetDestinations() ([]model.DestinationWallet, error)
+	GetDestinationByID(id string) (*model.DestinationWallet, error)
+	UpdateDestination(destination *model.DestinationWallet) error
+	DeleteDestination(id string) error
+	CountSplitsByDestination(destinationID string) (int64, error)
+	CreateRule(rule *model.SweepRule) error
+	GetRules() ([]model.SweepRule, error)
+	GetEnabledRules() ([]model.SweepRule, error)
+	GetRuleByID(id string) (*model.SweepRule, error)
+	UpdateRule(rule *model.SweepRule) error
+	DeleteRule(id string) error
+}
+
+type destinationRepository struct {
+	db *gorm.DB
+}
+
+func NewDestinationRepository(db *gorm.DB) DestinationRepository {
+	return &destinationRepository{db}
+}
+
+func (r *destinationRepository) CreateDestination(destination *model.DestinationWallet) error {
+	return r.db.Create(destination).Error
+}
+
+func (r *destinationRepository) GetDestinations() ([]model.DestinationWallet, error) {
+	var destinations []model.DestinationWallet
+	res := r.db.Order("created_at ASC").Find(&destinations)
+	return destinations, res.Error
+}
+
+func (r *destinationRepository) GetDestinationByID(id string) (*model.DestinationWallet, error) {
+	var destination model.DestinationWallet
+	if err := r.db.First(&destination, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &destination, nil
+}
+
+func (r *destinationRepository) UpdateDestination(destination *model.DestinationWallet) error {
+	return r.db.Save(destination).Error
+}
+
+func (r *destinationRepository) DeleteDestination(id string) error {
+	return r.db.Delete(&model.DestinationWallet{}, "id = ?", id).Error
+}
+
+func (r *destinationRepository) CountSplitsByDestination(destinationID string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.SweepSplit{}).Where("destination_id = ?", destinationID).Count(&count).Error
+	return count, err
+}
+
+func (r *destinationRepository) CreateRule(rule *model.SweepRule) error {
+	return r.db.Create(rule).Error
+}
+
+func (r *destinationRepository) GetRules() ([]model.SweepRule, error) {
+	var rules []model.SweepRule
+	res := r.db.Preload("Splits.Destination").Order("created_at ASC").Find(&rules)
+	return rules, res.Error
+}
+
+func (r *destinationRepository) GetEnabledRules() ([]model.SweepRule, error) {
+	var rules []model.SweepRule
+	res := r.db.Preload("Splits.Destination").Where("enabled = ?", true).Find(&rules)
+	return rules, res.Error
+}
+
+func (r *destinationRepository) GetRuleByID(id string) (*model.SweepRule, error) {
+	var rule model.SweepRule
+	if err := r.db.Preload("Splits.Destination").First(&rule, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// UpdateRule saves the rule and replaces its splits with rule.Splits.
+func (r *destinationRepository) UpdateRule(rule *model.SweepRule) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rule_id = ?", rule.ID).Delete(&model.SweepSplit{}).Error; err != nil {
+			return err
+		}
+		return tx.Save(rule).Error
+	})
+}
+
+func (r *destinationRepository) DeleteRule(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rule_id = ?", id).Delete(&model.SweepSplit{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.SweepRule{}, "id = ?", id).Error
+	})
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/thebytearray/BytePayments/model"
+	"gorm.io/gorm"
+)
+
+type IdempotencyRepository interface {
+	CreateKey(key *model.IdempotencyKey) error
+	GetKeyByID(id string) (*model.IdempotencyKey, error)
+	UpdateKey(key *model.IdempotencyKey) error
+	DeleteKey(id string) error
+	DeleteExpiredKeys(now time.Time) (int64, error)
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db}
+}
+
+func (r *idempotencyRepository) CreateKey(key *model.IdempotencyKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *idempotencyRepository) GetKeyByID(id string) (*model.IdempotencyKey, error) {
+	var key model.IdempotencyKey
+	if err := r.db.First(&key, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *idempotencyRepository) UpdateKey(key *model.IdempotencyKey) error {
+	return r.db.Save(key).Error
+}
+
+func (r *idempotencyRepository) DeleteKey(id string) error {
+	return r.db.Delete(&model.IdempotencyKey{}, "id = ?", id).Error
+}
+
+func (r *idempotencyRepository) DeleteExpiredKeys(now time.Time) (int64, error) {
+	res := r.db.Where("expires_at < ?", now).Delete(&model.IdempotencyKey{})
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"github.com/thebytearray/BytePayments/model"
+	"gorm.io/gorm"
+)
+
+type InvoiceRepository interface {
+	GetInvoices(email string) ([]model.Invoice, error)
+	GetInvoiceByID(id string) (*model.Invoice, error)
+}
+
+type invoiceRepository struct {
+	db *gorm.DB
+}
+
+func NewInvoiceRepository(db *gorm.DB) InvoiceRepository {
+	return &invoiceRepository{db}
+}
+
+// invoices are created along with their payment, see PaymentRepository.CreatePayment
+
+func (r *invoiceRepository) GetInvoices(email string) ([]model.Invoice, error) {
+	var invoices []model.Invoice
+	query := r.db.Preload("LineItems", orderedLineItems).Order("created_at DESC")
+	if email != "" {
+		query = query.Where("email = ?", email)
+	}
+	res := query.Find(&invoices)
+	return invoices, res.Error
+}
+
+func (r *invoiceRepository) GetInvoiceByID(id string) (*model.Invoice, error) {
+	var invoice model.Invoice
+	if err := r.db.Preload("LineItems", orderedLineItems).First(&invoice, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func orderedLineItems(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
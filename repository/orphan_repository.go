@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"github.com/thebytearray/BytePayments/model"
+	"gorm.io/gorm"
+)
+
+type OrphanRepository interface {
+	CreateOrphan(orphan *model.OrphanDeposit) error
+	UpdateOrphan(orphan *model.OrphanDeposit) error
+	GetOrphanByID(id string) (*model.OrphanDeposit, error)
+	GetOrphans(status string) ([]model.OrphanDeposit, error)
+	ExistsByTxHash(txHash string) (bool, error)
+	UpdateSweepStatusBySweepBatchId(batchID string, status model.PaymentSweepStatus) error
+}
+
+type orphanRepository struct {
+	db *gorm.DB
+}
+
+func NewOrphanRepository(db *gorm.DB) OrphanRepository {
+	return &orphanRepository{db}
+}
+
+func (r *orphanRepository) CreateOrphan(orphan *model.OrphanDeposit) error {
+	return r.db.Create(orphan).Error
+}
+
+func (r *orphanRepository) UpdateOrphan(orphan *model.OrphanDeposit) error {
+	return r.db.Omit("Wallet", "Currency").Save(orphan).Error
+}
+
+func (r *orphanRepository) GetOrphanByID(id string) (*model.OrphanDeposit, error) {
+	var orphan model.OrphanDeposit
+	if err := r.db.Preload("Wallet").Preload("Currency").First(&orphan, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &orphan, nil
+}
+
+func (r *orphanRepository) GetOrphans(status string) ([]model.OrphanDeposit, error) {
+	var orphans []model.OrphanDeposit
+	query := r.db.Order("created_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	res := query.Find(&orphans)
+	return orphans, res.Error
+}
+
+func (r *orphanRepository) ExistsByTxHash(txHash string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.OrphanDeposit{}).Where("tx_hash = ?", txHash).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *orphanRepository) UpdateSweepStatusBySweepBatchId(batchID string, status model.PaymentSweepStatus) error {
+	return r.db.Model(&model.OrphanDeposit{}).Where("sweep_batch_id = ?", batchID).Update("sweep_status", status).Error
+}
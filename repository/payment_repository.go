@@ -17,11 +17,28 @@ type PaymentRepository interface {
 	FindPaymentById(id string) (model.Payment, error)
 	UpdatePayment(payment model.Payment) error
 	HasPendingPayment(user_email string) (bool, error)
+	HasPendingPaymentExcept(email string, ids []string) (bool, error)
+	FindPendingRenewalPaymentsByEmail(email string) ([]model.Payment, error)
 	FindAllPendingPayments() ([]model.Payment, error)
 	MarkAsCompletedById(id string, paidAmount float64, completedAt *time.Time) error
 	MarkAsExpiredById(id string) error
+	MarkAsPartiallyPaidById(id string, paidAmount, remainingAmount float64, expiresAt *time.Time) error
+	UpdatePaidAmountById(id string, paidAmount float64) error
+	UpdateQuote(payment model.Payment) error
+	FindLatePaymentCandidates(expiredSince time.Time) ([]model.Payment, error)
+	FindPaymentsByStatus(status model.PaymentStatus) ([]model.Payment, error)
+	MarkAsLateById(id string, paidAmount float64) error
+	FindIdleWalletPayments(lateSince time.Time) ([]model.Payment, error)
+	HasOpenPaymentByWalletId(walletID string) (bool, error)
+	FindPaymentsBySweepStatus(status model.PaymentSweepStatus) ([]model.Payment, error)
+	FindPaymentsBySweepBatchId(batchID string) ([]model.Payment, error)
+	AssignSweepBatchByIds(ids []string, batchID string) error
+	UpdateSweepStatusBySweepBatchId(batchID string, status model.PaymentSweepStatus) error
+	MarkAsUnsweptById(id string) error
+	GetPaymentsByAPIKeyId(apiKeyID, externalReference string) ([]model.Payment, error)
 	// Admin methods
-	GetAllPayments() ([]model.Payment, error)
+	GetAllPayments(apiKeyID, externalReference string) ([]model.Payment, error)
+	ExistsByExternalReference(apiKeyID, externalReference string) (bool, error)
 	DeletePayment(id string) error
 	GetAllWallets() ([]model.Wallet, error)
 	DeleteWallet(id string) error
@@ -35,11 +52,14 @@ func NewPaymentRepository(db *gorm.DB) PaymentRepository {
 	return &paymentRepository{db}
 }
 
+// FindAllPendingPayments returns the payments still waiting for funds, partially paid ones included.
 func (r *paymentRepository) FindAllPendingPayments() ([]model.Payment, error) {
 	var payments []model.Payment
-	err := r.db.Where("status = ? AND created_at >= ?", model.Pending, time.Now().Add(-15*time.Minute)).
+	err := r.db.Where("status IN ?", []model.PaymentStatus{model.Pending, model.PartiallyPaid}).
 		Preload("Wallet").
 		Preload("Plan").
+		Preload("Invoice").
+		Preload("Currency").
 		Find(&payments).Error
 	return payments, err
 }
@@ -48,9 +68,11 @@ func (r *paymentRepository) MarkAsCompletedById(id string, paidAmount float64, c
 	return r.db.Model(&model.Payment{}).
 		Where("id = ?", id).
 		Updates(map[string]any{
-			"status":          model.Completed,
-			"paid_amount_trx": paidAmount,
-			"updated_at":      completedAt,
+			"status":           model.Completed,
+			"sweep_status":     model.PaymentUnswept,
+			"paid_amount_trx":  paidAmount,
+			"remaining_amount": 0,
+			"updated_at":       completedAt,
 		}).Error
 }
 
@@ -58,9 +80,127 @@ func (r *paymentRepository) MarkAsExpiredById(id string) error {
 	return r.db.Model(&model.Payment{}).Where("id = ?", id).Update("status", model.Expired).Error
 }
 
+// MarkAsPartiallyPaidById records what an open payment received so far and moves it to
+// partially_paid, expiresAt is its grace window.
+func (r *paymentRepository) MarkAsPartiallyPaidById(id string, paidAmount, remainingAmount float64, expiresAt *time.Time) error {
+	return r.db.Model(&model.Payment{}).
+		Where("id = ? AND status IN ?", id, []model.PaymentStatus{model.Pending, model.PartiallyPaid}).
+		Updates(map[string]any{
+			"status":           model.PartiallyPaid,
+			"paid_amount_trx":  paidAmount,
+			"remaining_amount": remainingAmount,
+			"expires_at":       expiresAt,
+		}).Error
+}
+
+func (r *paymentRepository) UpdatePaidAmountById(id string, paidAmount float64) error {
+	return r.db.Model(&model.Payment{}).Where("id = ?", id).Update("paid_amount_trx", paidAmount).Error
+}
+
+// UpdateQuote stores a new quote for a pending payment, it is a no-op once the payment left pending.
+func (r *paymentRepository) UpdateQuote(payment model.Payment) error {
+	return r.db.Model(&model.Payment{}).
+		Where("id = ? AND status = ?", payment.ID, model.Pending).
+		Updates(map[string]any{
+			"amount_trx":       payment.AmountTRX,
+			"amount_usd":       payment.AmountUSD,
+			"fx_rate":          payment.FXRate,
+			"remaining_amount": payment.RemainingAmount,
+			"quote_rate":       payment.QuoteRate,
+			"quote_source":     payment.QuoteSource,
+			"quoted_at":        payment.QuotedAt,
+			"quote_expires_at": payment.QuoteExpiresAt,
+			"requote_count":    payment.RequoteCount,
+		}).Error
+}
+
+func (r *paymentRepository) FindPaymentsBySweepStatus(status model.PaymentSweepStatus) ([]model.Payment, error) {
+	var payments []model.Payment
+	// cancelled and expired payments are only swept once they're refunded, see MarkAsUnsweptById
+	res := r.db.Preload("Wallet").Preload("Currency").
+		Where("status IN ? AND sweep_status = ?", []model.PaymentStatus{model.Completed, model.Cancelled, model.Expired}, status).
+		Find(&payments)
+	return payments, res.Error
+}
+
+// FindLatePaymentCandidates returns the payments that expired since expiredSince and are still the
+// latest payment of their wallet, deposits made after a newer payment was created belong to it.
+func (r *paymentRepository) FindLatePaymentCandidates(expiredSince time.Time) ([]model.Payment, error) {
+	var payments []model.Payment
+	err := r.db.Where("status = ? AND expires_at >= ?", model.Expired, expiredSince).
+		Where("NOT EXISTS (?)", r.db.Table("payments AS newer").Select("1").
+			Where("newer.wallet_id = payments.wallet_id AND newer.created_at > payments.created_at")).
+		Preload("Wallet").
+		Preload("Plan").
+		Preload("Invoice").
+		Preload("Currency").
+		Find(&payments).Error
+	return payments, err
+}
+
+// FindIdleWalletPayments returns the latest payment of every wallet that no payment is waiting on
+// anymore: not pending or partially paid, not under late review and not expired since lateSince,
+// where the late payment scan still watches the wallet.
+func (r *paymentRepository) FindIdleWalletPayments(lateSince time.Time) ([]model.Payment, error) {
+	var payments []model.Payment
+	err := r.db.Where("status NOT IN ?", []model.PaymentStatus{model.Pending, model.PartiallyPaid, model.LatePayment}).
+		Where("NOT (status = ? AND expires_at IS NOT NULL AND expires_at >= ?)", model.Expired, lateSince).
+		Where("NOT EXISTS (?)", r.db.Table("payments AS newer").Select("1").
+			Where("newer.wallet_id = payments.wallet_id AND newer.created_at > payments.created_at")).
+		Preload("Wallet").
+		Find(&payments).Error
+	return payments, err
+}
+
+func (r *paymentRepository) HasOpenPaymentByWalletId(walletID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Payment{}).
+		Where("wallet_id = ? AND status IN ?", walletID, []model.PaymentStatus{model.Pending, model.PartiallyPaid, model.LatePayment}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *paymentRepository) FindPaymentsByStatus(status model.PaymentStatus) ([]model.Payment, error) {
+	var payments []model.Payment
+	res := r.db.Preload("Wallet").Preload("Plan").Preload("Invoice").Preload("Currency").Where("status = ?", status).Order("updated_at DESC").Find(&payments)
+	return payments, res.Error
+}
+
+func (r *paymentRepository) MarkAsLateById(id string, paidAmount float64) error {
+	return r.db.Model(&model.Payment{}).
+		Where("id = ? AND status = ?", id, model.Expired).
+		Updates(map[string]any{
+			"status":          model.LatePayment,
+			"paid_amount_trx": paidAmount,
+		}).Error
+}
+
+func (r *paymentRepository) FindPaymentsBySweepBatchId(batchID string) ([]model.Payment, error) {
+	var payments []model.Payment
+	res := r.db.Preload("Wallet").Preload("Currency").Where("sweep_batch_id = ?", batchID).Find(&payments)
+	return payments, res.Error
+}
+
+func (r *paymentRepository) AssignSweepBatchByIds(ids []string, batchID string) error {
+	return r.db.Model(&model.Payment{}).
+		Where("id IN ?", ids).
+		Updates(map[string]any{
+			"sweep_batch_id": batchID,
+			"sweep_status":   model.PaymentSweeping,
+		}).Error
+}
+
+func (r *paymentRepository) UpdateSweepStatusBySweepBatchId(batchID string, status model.PaymentSweepStatus) error {
+	return r.db.Model(&model.Payment{}).Where("sweep_batch_id = ?", batchID).Update("sweep_status", status).Error
+}
+
+func (r *paymentRepository) MarkAsUnsweptById(id string) error {
+	return r.db.Model(&model.Payment{}).Where("id = ?", id).Update("sweep_status", model.PaymentUnswept).Error
+}
+
 func (r *paymentRepository) HasPendingPayment(user_email string) (bool, error) {
 	var count int64
-	err := r.db.Model(&model.Payment{}).Where("user_email = ? AND status = ?", user_email, model.Pending).Count(&count).Error
+	err := r.db.Model(&model.Payment{}).Where("user_email = ? AND status IN ?", user_email, []model.PaymentStatus{model.Pending, model.PartiallyPaid}).Count(&count).Error
 
 	if err != nil {
 		return false, err
@@ -69,6 +209,26 @@ func (r *paymentRepository) HasPendingPayment(user_email string) (bool, error) {
 	return count > 0, err
 }
 
+// HasPendingPaymentExcept is HasPendingPayment leaving the payments with the given ids out.
+func (r *paymentRepository) HasPendingPaymentExcept(email string, ids []string) (bool, error) {
+	if len(ids) == 0 {
+		return r.HasPendingPayment(email)
+	}
+
+	var count int64
+	err := r.db.Model(&model.Payment{}).
+		Where("user_email = ? AND status IN ? AND id NOT IN ?", email, []model.PaymentStatus{model.Pending, model.PartiallyPaid}, ids).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// FindPendingRenewalPaymentsByEmail returns the renewal invoices of email nothing was paid on yet.
+func (r *paymentRepository) FindPendingRenewalPaymentsByEmail(email string) ([]model.Payment, error) {
+	var payments []model.Payment
+	res := r.db.Preload("Wallet").Preload("Plan").Preload("Currency").Where("user_email = ? AND status = ? AND subscription_id <> ''", email, model.Pending).Find(&payments)
+	return payments, res.Error
+}
+
 func (r *paymentRepository) UpdatePayment(payment model.Payment) error {
 	return r.db.Save(&payment).Error
 }
@@ -81,7 +241,7 @@ func (r *paymentRepository) FindWalletByEmail(email string) (model.Wallet, error
 
 func (r *paymentRepository) FindPlanById(id string) (model.Plan, error) {
 	var plan model.Plan
-	res := r.db.Where("id = ?", id).First(&plan)
+	res := r.db.Preload("Prices").Where("id = ?", id).First(&plan)
 
 	return plan, res.Error
 }
@@ -103,18 +263,43 @@ func (r *paymentRepository) CreatePayment(payment model.Payment) error {
 
 func (r *paymentRepository) FindPaymentById(id string) (model.Payment, error) {
 	var payment model.Payment
-	res := r.db.Preload("Wallet").Where("id = ?", id).Find(&payment)
+	res := r.db.Preload("Wallet").Preload("Plan").Preload("Invoice.LineItems", orderedLineItems).Preload("Currency").Where("id = ?", id).Find(&payment)
 	log.Println(payment.CurrencyCode)
 	return payment, res.Error
 }
 
+// GetPaymentsByAPIKeyId returns the payments a merchant created with the API key, newest first,
+// optionally only the one with externalReference.
+func (r *paymentRepository) GetPaymentsByAPIKeyId(apiKeyID, externalReference string) ([]model.Payment, error) {
+	var payments []model.Payment
+	query := r.db.Preload("Wallet").Preload("Plan").Where("api_key_id = ?", apiKeyID).Order("created_at DESC")
+	if externalReference != "" {
+		query = query.Where("external_reference = ?", externalReference)
+	}
+	res := query.Find(&payments)
+	return payments, res.Error
+}
+
 // Admin methods
-func (r *paymentRepository) GetAllPayments() ([]model.Payment, error) {
+
+// GetAllPayments returns every payment, or the one with externalReference. References are only
+// unique per API key, so they're looked up within apiKeyID, empty being the customers' own.
+func (r *paymentRepository) GetAllPayments(apiKeyID, externalReference string) ([]model.Payment, error) {
 	var payments []model.Payment
-	res := r.db.Preload("Wallet").Preload("Plan").Order("created_at DESC").Find(&payments)
+	query := r.db.Preload("Wallet").Preload("Plan").Order("created_at DESC")
+	if externalReference != "" {
+		query = query.Where("api_key_id = ? AND external_reference = ?", apiKeyID, externalReference)
+	}
+	res := query.Find(&payments)
 	return payments, res.Error
 }
 
+func (r *paymentRepository) ExistsByExternalReference(apiKeyID, externalReference string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.Payment{}).Where("api_key_id = ? AND external_reference = ?", apiKeyID, externalReference).Count(&count).Error
+	return count > 0, err
+}
+
 func (r *paymentRepository) DeletePayment(id string) error {
 	return r.db.Delete(&model.Payment{}, "id = ?", id).Error
 }
@@ -22,13 +22,13 @@ func NewPlansRepository(db *gorm.DB) PlanRepository {
 }
 func (r *planRepository) GetPlans() ([]model.Plan, error) {
 	var plans []model.Plan
-	res := r.db.Find(&plans)
+	res := r.db.Preload("Prices").Find(&plans)
 	return plans, res.Error
 }
 
 func (r *planRepository) GetPlanByID(id string) (*model.Plan, error) {
 	var plan model.Plan
-	res := r.db.First(&plan, "id = ?", id)
+	res := r.db.Preload("Prices").First(&plan, "id = ?", id)
 	if res.Error != nil {
 		return nil, res.Error
 	}
@@ -39,8 +39,20 @@ func (r *planRepository) CreatePlan(plan *model.Plan) error {
 	return r.db.Create(plan).Error
 }
 
+// UpdatePlan saves the plan and replaces its fixed prices with plan.Prices.
 func (r *planRepository) UpdatePlan(plan *model.Plan) error {
-	return r.db.Save(plan).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Prices").Save(plan).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&model.PlanPrice{}, "plan_id = ?", plan.ID).Error; err != nil {
+			return err
+		}
+		if len(plan.Prices) == 0 {
+			return nil
+		}
+		return tx.Create(&plan.Prices).Error
+	})
 }
 
 func (r *planRepository) DeletePlan(id string) error {
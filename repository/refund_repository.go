@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"github.com/thebytearray/BytePayments/model"
+	"gorm.io/gorm"
+)
+
+type RefundRepository interface {
+	CreateRefund(refund *model.Refund) error
+	UpdateRefund(refund *model.Refund) error
+	GetRefundByID(id string) (*model.Refund, error)
+	GetRefunds(status, paymentID string) ([]model.Refund, error)
+	FindRefundsByStatus(status model.RefundStatus) ([]model.Refund, error)
+	SumRefundsByPaymentId(paymentID string) (float64, error)
+}
+
+type refundRepository struct {
+	db *gorm.DB
+}
+
+func NewRefundRepository(db *gorm.DB) RefundRepository {
+	return &refundRepository{db}
+}
+
+func (r *refundRepository) CreateRefund(refund *model.Refund) error {
+	return r.db.Create(refund).Error
+}
+
+func (r *refundRepository) UpdateRefund(refund *model.Refund) error {
+	return r.db.Save(refund).Error
+}
+
+func (r *refundRepository) GetRefundByID(id string) (*model.Refund, error) {
+	var refund model.Refund
+	if err := r.db.First(&refund, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+func (r *refundRepository) GetRefunds(status, paymentID string) ([]model.Refund, error) {
+	var refunds []model.Refund
+	query := r.db.Order("created_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if paymentID != "" {
+		query = query.Where("payment_id = ?", paymentID)
+	}
+	res := query.Find(&refunds)
+	return refunds, res.Error
+}
+
+func (r *refundRepository) FindRefundsByStatus(status model.RefundStatus) ([]model.Refund, error) {
+	var refunds []model.Refund
+	res := r.db.Where("status = ?", status).Order("created_at ASC").Find(&refunds)
+	return refunds, res.Error
+}
+
+// SumRefundsByPaymentId returns the amount refunded or still to be refunded for a payment.
+func (r *refundRepository) SumRefundsByPaymentId(paymentID string) (float64, error) {
+	var total float64
+	err := r.db.Model(&model.Refund{}).
+		Where("payment_id = ? AND status <> ?", paymentID, model.RefundRejected).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error
+	return total, err
+}
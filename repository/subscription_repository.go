@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/thebytearray/BytePayments/model"
+	"gorm.io/gorm"
+)
+
+type SubscriptionRepository interface {
+	FindSubscriptionByEmailAndPlan(email, planID string) (*model.Subscription, error)
+	HasPeriodForPayment(paymentID string) (bool, error)
+	SaveSubscriptionPeriod(subscription *model.Subscription, period *model.SubscriptionPeriod) error
+	UpdateSubscription(subscription *model.Subscription) error
+	GetSubscriptions(email, status string) ([]model.Subscription, error)
+	FindActiveSubscriptionsByEmail(email string, at time.Time) ([]model.Subscription, error)
+	FindSubscriptionsEndingBefore(endsBefore time.Time) ([]model.Subscription, error)
+	FindSubscriptionsToRemind(endsBefore time.Time) ([]model.Subscription, error)
+	FindSubscriptionsToRenew(endsBefore time.Time) ([]model.Subscription, error)
+	FindLatestPeriod(subscriptionID string) (*model.SubscriptionPeriod, error)
+	ClearRenewalPayment(paymentID string) error
+}
+
+type subscriptionRepository struct {
+	db *gorm.DB
+}
+
+func NewSubscriptionRepository(db *gorm.DB) SubscriptionRepository {
+	return &subscriptionRepository{db}
+}
+
+func (r *subscriptionRepository) FindSubscriptionByEmailAndPlan(email, planID string) (*model.Subscription, error) {
+	var subscription model.Subscription
+	if err := r.db.First(&subscription, "email = ? AND plan_id = ?", email, planID).Error; err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *subscriptionRepository) HasPeriodForPayment(paymentID string) (bool, error) {
+	var count int64
+	err := r.db.Model(&model.SubscriptionPeriod{}).Where("payment_id = ?", paymentID).Count(&count).Error
+	return count > 0, err
+}
+
+// SaveSubscriptionPeriod saves the subscription and the period extending it in one transaction,
+// the unique payment_id of the period keeps a payment from being credited twice.
+func (r *subscriptionRepository) SaveSubscriptionPeriod(subscription *model.Subscription, period *model.SubscriptionPeriod) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Plan", "Periods").Save(subscription).Error; err != nil {
+			return err
+		}
+		return tx.Create(period).Error
+	})
+}
+
+func (r *subscriptionRepository) UpdateSubscription(subscription *model.Subscription) error {
+	return r.db.Omit("Plan", "Periods").Save(subscription).Error
+}
+
+func (r *subscriptionRepository) GetSubscriptions(email, status string) ([]model.Subscription, error) {
+	var subscriptions []model.Subscription
+	query := r.db.Preload("Plan").Preload("Periods").Order("ends_at DESC")
+	if email != "" {
+		query = query.Where("email = ?", email)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	res := query.Find(&subscriptions)
+	return subscriptions, res.Error
+}
+
+func (r *subscriptionRepository) FindActiveSubscriptionsByEmail(email string, at time.Time) ([]model.Subscription, error) {
+	var subscriptions []model.Subscription
+	res := r.db.Preload("Plan").
+		Where("email = ? AND status = ? AND starts_at <= ? AND ends_at > ?", email, model.SubscriptionActive, at, at).
+		Order("ends_at DESC").
+		Find(&subscriptions)
+	return subscriptions, res.Error
+}
+
+// FindSubscriptionsEndingBefore returns the active subscriptions whose last period ends before endsBefore.
+func (r *subscriptionRepository) FindSubscriptionsEndingBefore(endsBefore time.Time) ([]model.Subscription, error) {
+	var subscriptions []model.Subscription
+	res := r.db.Preload("Plan").
+		Where("status = ? AND ends_at <= ?", model.SubscriptionActive, endsBefore).
+		Find(&subscriptions)
+	return subscriptions, res.Error
+}
+
+// FindSubscriptionsToRemind returns the active subscriptions ending before endsBefore that weren't
+// reminded of it yet.
+func (r *subscriptionRepository) FindSubscriptionsToRemind(endsBefore time.Time) ([]model.Subscription, error) {
+	var subscriptions []model.Subscription
+	res := r.db.Preload("Plan").
+		Where("status = ? AND ends_at <= ? AND reminder_sent_at IS NULL", model.SubscriptionActive, endsBefore).
+		Find(&subscriptions)
+	return subscriptions, res.Error
+}
+
+// FindSubscriptionsToRenew returns the active subscriptions ending before endsBefore that have no
+// renewal invoice yet.
+func (r *subscriptionRepository) FindSubscriptionsToRenew(endsBefore time.Time) ([]model.Subscription, error) {
+	var subscriptions []model.Subscription
+	res := r.db.Preload("Plan").
+		Where("status = ? AND ends_at <= ? AND (renewal_payment_id = '' OR renewal_payment_id IS NULL)", model.SubscriptionActive, endsBefore).
+		Find(&subscriptions)
+	return subscriptions, res.Error
+}
+
+func (r *subscriptionRepository) FindLatestPeriod(subscriptionID string) (*model.SubscriptionPeriod, error) {
+	var period model.SubscriptionPeriod
+	if err := r.db.Where("subscription_id = ?", subscriptionID).Order("ends_at DESC").First(&period).Error; err != nil {
+		return nil, err
+	}
+	return &period, nil
+}
+
+// ClearRenewalPayment detaches the renewal invoice from its subscription, which is invoiced again
+// on the next renewal run.
+func (r *subscriptionRepository) ClearRenewalPayment(paymentID string) error {
+	return r.db.Model(&model.Subscription{}).Where("renewal_payment_id = ?", paymentID).Update("renewal_payment_id", "").Error
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"github.com/thebytearray/BytePayments/model"
+	"gorm.io/gorm"
+)
+
+type SweepRepository interface {
+	CreateSweep(sweep *model.Sweep) error
+	UpdateSweep(sweep *model.Sweep) error
+	GetSweepByID(id string) (*model.Sweep, error)
+	FindSweepsByStatus(status model.SweepStatus) ([]model.Sweep, error)
+	FindSweepsByBatchId(batchID string) ([]model.Sweep, error)
+	GetSweeps(status, paymentID string) ([]model.Sweep, error)
+}
+
+type sweepRepository struct {
+	db *gorm.DB
+}
+
+func NewSweepRepository(db *gorm.DB) SweepRepository {
+	return &sweepRepository{db}
+}
+
+func (r *sweepRepository) CreateSweep(sweep *model.Sweep) error {
+	return r.db.Create(sweep).Error
+}
+
+func (r *sweepRepository) UpdateSweep(sweep *model.Sweep) error {
+	return r.db.Save(sweep).Error
+}
+
+func (r *sweepRepository) GetSweepByID(id string) (*model.Sweep, error) {
+	var sweep model.Sweep
+	if err := r.db.First(&sweep, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &sweep, nil
+}
+
+func (r *sweepRepository) FindSweepsByStatus(status model.SweepStatus) ([]model.Sweep, error) {
+	var sweeps []model.Sweep
+	res := r.db.Where("status = ?", status).Order("created_at ASC").Find(&sweeps)
+	return sweeps, res.Error
+}
+
+func (r *sweepRepository) FindSweepsByBatchId(batchID string) ([]model.Sweep, error) {
+	var sweeps []model.Sweep
+	res := r.db.Where("batch_id = ?", batchID).Order("created_at ASC").Find(&sweeps)
+	return sweeps, res.Error
+}
+
+func (r *sweepRepository) GetSweeps(status, paymentID string) ([]model.Sweep, error) {
+	var sweeps []model.Sweep
+	query := r.db.Order("created_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if paymentID != "" {
+		// a sweep batch can carry the funds of several payments of the same wallet
+		query = query.Where("payment_id = ? OR batch_id IN (?)", paymentID, r.db.Model(&model.Payment{}).Select("sweep_batch_id").Where("id = ?", paymentID))
+	}
+	res := query.Find(&sweeps)
+	return sweeps, res.Error
+}
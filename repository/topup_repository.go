@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"github.com/thebytearray/BytePayments/model"
+	"gorm.io/gorm"
+)
+
+type TopUpRepository interface {
+	CreateTopUp(topUp *model.TopUp) error
+	UpdateTopUp(topUp *model.TopUp) error
+	FindTopUpsByWalletAndStatus(walletAddress string, status model.TopUpStatus) ([]model.TopUp, error)
+	GetAllTopUps() ([]model.TopUp, error)
+}
+
+type topUpRepository struct {
+	db *gorm.DB
+}
+
+func NewTopUpRepository(db *gorm.DB) TopUpRepository {
+	return &topUpRepository{db}
+}
+
+func (r *topUpRepository) CreateTopUp(topUp *model.TopUp) error {
+	return r.db.Create(topUp).Error
+}
+
+func (r *topUpRepository) UpdateTopUp(topUp *model.TopUp) error {
+	return r.db.Save(topUp).Error
+}
+
+func (r *topUpRepository) FindTopUpsByWalletAndStatus(walletAddress string, status model.TopUpStatus) ([]model.TopUp, error) {
+	var topUps []model.TopUp
+	res := r.db.Where("wallet_address = ? AND status = ?", walletAddress, status).Order("created_at ASC").Find(&topUps)
+	return topUps, res.Error
+}
+
+func (r *topUpRepository) GetAllTopUps() ([]model.TopUp, error) {
+	var topUps []model.TopUp
+	res := r.db.Order("created_at DESC").Find(&topUps)
+	return topUps, res.Error
+}
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/thebytearray/BytePayments/model"
+	"gorm.io/gorm"
+)
+
+type WebhookRepository interface {
+	CreateEndpoint(endpoint *model.WebhookEndpoint) error
+	GetEndpoints() ([]model.WebhookEndpoint, error)
+	GetEnabledEndpoints(apiKeyID string) ([]model.WebhookEndpoint, error)
+	GetEndpointByID(id string) (*model.WebhookEndpoint, error)
+	UpdateEndpoint(endpoint *model.WebhookEndpoint) error
+	DeleteEndpoint(id string) error
+	CreateDelivery(delivery *model.WebhookDelivery) error
+	UpdateDelivery(delivery *model.WebhookDelivery) error
+	GetDeliveryByID(id string) (*model.WebhookDelivery, error)
+	GetDeliveries(endpointID string, status string) ([]model.WebhookDelivery, error)
+	FindDueDeliveries(now time.Time, limit int) ([]model.WebhookDelivery, error)
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db}
+}
+
+func (r *webhookRepository) CreateEndpoint(endpoint *model.WebhookEndpoint) error {
+	return r.db.Create(endpoint).Error
+}
+
+func (r *webhookRepository) GetEndpoints() ([]model.WebhookEndpoint, error) {
+	var endpoints []model.WebhookEndpoint
+	res := r.db.Order("created_at DESC").Find(&endpoints)
+	return endpoints, res.Error
+}
+
+// GetEnabledEndpoints returns the enabled endpoints of the API key, or the ones without a key
+// when apiKeyID is empty.
+func (r *webhookRepository) GetEnabledEndpoints(apiKeyID string) ([]model.WebhookEndpoint, error) {
+	var endpoints []model.WebhookEndpoint
+	res := r.db.Where("enabled = ? AND api_key_id = ?", true, apiKeyID).Find(&endpoints)
+	return endpoints, res.Error
+}
+
+func (r *webhookRepository) GetEndpointByID(id string) (*model.WebhookEndpoint, error) {
+	var endpoint model.WebhookEndpoint
+	res := r.db.First(&endpoint, "id = ?", id)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return &endpoint, nil
+}
+
+func (r *webhookRepository) UpdateEndpoint(endpoint *model.WebhookEndpoint) error {
+	return r.db.Save(endpoint).Error
+}
+
+func (r *webhookRepository) DeleteEndpoint(id string) error {
+	return r.db.Delete(&model.WebhookEndpoint{}, "id = ?", id).Error
+}
+
+func (r *webhookRepository) CreateDelivery(delivery *model.WebhookDelivery) error {
+	return r.db.Create(delivery).Error
+}
+
+func (r *webhookRepository) UpdateDelivery(delivery *model.WebhookDelivery) error {
+	return r.db.Save(delivery).Error
+}
+
+func (r *webhookRepository) GetDeliveryByID(id string) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	res := r.db.First(&delivery, "id = ?", id)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return &delivery, nil
+}
+
+func (r *webhookRepository) GetDeliveries(endpointID string, status string) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	query := r.db.Order("created_at DESC")
+	if endpointID != "" {
+		query = query.Where("endpoint_id = ?", endpointID)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	res := query.Find(&deliveries)
+	return deliveries, res.Error
+}
+
+func (r *webhookRepository) FindDueDeliveries(now time.Time, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	res := r.db.Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&deliveries)
+	return deliveries, res.Error
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/gofiber/swagger"
 	"github.com/thebytearray/BytePayments/config"
 	"github.com/thebytearray/BytePayments/controller"
+	"github.com/thebytearray/BytePayments/model"
 )
 
 func NewRouter() *fiber.App {
@@ -14,7 +15,7 @@ func NewRouter() *fiber.App {
 	app.Use(func(c *fiber.Ctx) error {
 		c.Set("Access-Control-Allow-Origin", "*")
 		c.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, PATCH, OPTIONS")
-		c.Set("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization")
+		c.Set("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-API-Key, Idempotency-Key")
 		
 		if c.Method() == "OPTIONS" {
 			return c.SendStatus(200)
@@ -28,11 +29,17 @@ func NewRouter() *fiber.App {
 		app.Get("/swagger/*", swagger.HandlerDefault)
 	}
 	
+	// hosted checkout, small merchants link straight to it
+	app.Get("/checkout/:payment_id", controller.CheckoutHandler)
+	app.Get("/invoices/:id", controller.InvoiceHandler)
+	app.Get("/invoices/:id/pdf", controller.InvoicePDFHandler)
+
 	v1 := app.Group("/api/v1")
 	
 	//verification
 	v1_verification := v1.Group("/verification")
 	{
+		v1_verification.Use(controller.IdempotencyMiddleware(controller.IdempotencyByEmail))
 		v1_verification.Post("/send-code", controller.SendVerificationCodeHandler)
 		v1_verification.Post("/verify-code", controller.VerifyEmailCodeHandler)
 	}
@@ -41,14 +48,34 @@ func NewRouter() *fiber.App {
 	//
 	v1_payments := v1.Group("/payments")
 	{
-		v1_payments.Post("/create", controller.CreatePaymentHandler)
-		v1_payments.Patch("/:id/cancel", controller.CancelPaymentHandler)
+		v1_payments.Post("/create", controller.IdempotencyMiddleware(nil), controller.CreatePaymentHandler)
+		v1_payments.Get("/", controller.APIKeyMiddleware(model.ScopePaymentsRead), controller.GetMerchantPaymentsHandler)
+		v1_payments.Patch("/:id/cancel", controller.IdempotencyMiddleware(controller.IdempotencyByPayment), controller.CancelPaymentHandler)
 		v1_payments.Get("/:id/status", controller.GetPaymentStatusHandler)
+		v1_payments.Get("/:id/events", controller.PaymentEventsHandler)
+	}
+	//refunds, authorised by the signed link emailed to the customer
+	//
+	v1_refunds := v1.Group("/refunds")
+	{
+		v1_refunds.Post("/", controller.IdempotencyMiddleware(nil), controller.APIKeyMiddleware(model.ScopeRefundsWrite), controller.CreateRefundHandler)
+		v1_refunds.Get("/:id", controller.GetRefundHandler)
+		v1_refunds.Post("/:id/address", controller.IdempotencyMiddleware(controller.IdempotencyByRefundLink), controller.SubmitRefundAddressHandler)
+	}
+	//invoices, merchant servers bill arbitrary amounts with an API key
+	//
+	v1_invoices := v1.Group("/invoices")
+	{
+		v1_invoices.Use(controller.IdempotencyMiddleware(nil))
+		v1_invoices.Post("/", controller.APIKeyMiddleware(model.ScopePaymentsCreate), controller.CreateInvoiceHandler)
 	}
 	//plans
 	//
 	v1.Get("/plans", controller.GetPlansHandler)
 	v1.Get("/currencies", controller.GetCurrenciesHandler)
+	//merchant server lookups, authorised by an API key
+	//
+	v1.Get("/entitlements", controller.APIKeyMiddleware(model.ScopeSubscriptionsRead), controller.GetEntitlementHandler)
 
 	//admin routes
 	//
@@ -63,12 +90,60 @@ func NewRouter() *fiber.App {
 		v1_admin.Post("/plans", controller.CreatePlanHandler)
 		v1_admin.Put("/plans/:id", controller.UpdatePlanHandler)
 		v1_admin.Delete("/plans/:id", controller.DeletePlanHandler)
+		v1_admin.Post("/coupons", controller.CreateCouponHandler)
+		v1_admin.Get("/coupons", controller.GetCouponsHandler)
+		v1_admin.Put("/coupons/:id", controller.UpdateCouponHandler)
+		v1_admin.Delete("/coupons/:id", controller.DeleteCouponHandler)
+		v1_admin.Get("/coupons/:id/redemptions", controller.GetCouponRedemptionsHandler)
 		// Payments
 		v1_admin.Get("/payments", controller.GetAllPaymentsHandler)
+		v1_admin.Get("/payments/late", controller.GetLatePaymentsHandler)
 		v1_admin.Delete("/payments/:id", controller.DeletePaymentHandler)
+		v1_admin.Post("/payments/:id/late", controller.ResolveLatePaymentHandler)
+		v1_admin.Post("/invoices", controller.CreateInvoiceHandler)
+		v1_admin.Get("/invoices", controller.GetInvoicesHandler)
+		v1_admin.Get("/deposits", controller.GetDepositsHandler)
+		v1_admin.Get("/orphans", controller.GetOrphansHandler)
+		v1_admin.Post("/orphans/:id/resolve", controller.ResolveOrphanHandler)
+		// Subscriptions
+		v1_admin.Get("/subscriptions", controller.GetSubscriptionsHandler)
+		v1_admin.Get("/entitlements", controller.GetEntitlementHandler)
+		// Refunds
+		v1_admin.Get("/refunds", controller.GetRefundsHandler)
+		v1_admin.Post("/refunds", controller.CreateRefundHandler)
+		v1_admin.Post("/refunds/:id/approve", controller.ApproveRefundHandler)
+		v1_admin.Post("/refunds/:id/reject", controller.RejectRefundHandler)
+		v1_admin.Post("/refunds/:id/retry", controller.RetryRefundHandler)
+		v1_admin.Post("/refunds/:id/link", controller.ResendRefundLinkHandler)
 		// Wallets
 		v1_admin.Get("/wallets", controller.GetAllWalletsHandler)
 		v1_admin.Delete("/wallets/:id", controller.DeleteWalletHandler)
+		v1_admin.Get("/topups", controller.GetAllTopUpsHandler)
+		// Sweeps
+		v1_admin.Get("/sweeps", controller.GetSweepsHandler)
+		v1_admin.Get("/sweeps/plan", controller.GetSweepPlanHandler)
+		v1_admin.Post("/sweeps/:id/retry", controller.RetrySweepHandler)
+		// Destination wallets and sweep rules
+		v1_admin.Post("/destinations", controller.CreateDestinationHandler)
+		v1_admin.Get("/destinations", controller.GetDestinationsHandler)
+		v1_admin.Put("/destinations/:id", controller.UpdateDestinationHandler)
+		v1_admin.Delete("/destinations/:id", controller.DeleteDestinationHandler)
+		v1_admin.Post("/sweep-rules", controller.CreateSweepRuleHandler)
+		v1_admin.Get("/sweep-rules", controller.GetSweepRulesHandler)
+		v1_admin.Put("/sweep-rules/:id", controller.UpdateSweepRuleHandler)
+		v1_admin.Delete("/sweep-rules/:id", controller.DeleteSweepRuleHandler)
+		// Merchant API keys
+		v1_admin.Post("/api-keys", controller.CreateAPIKeyHandler)
+		v1_admin.Get("/api-keys", controller.GetAPIKeysHandler)
+		v1_admin.Post("/api-keys/:id/rotate", controller.RotateAPIKeyHandler)
+		v1_admin.Post("/api-keys/:id/revoke", controller.RevokeAPIKeyHandler)
+		// Webhooks
+		v1_admin.Post("/webhooks", controller.CreateWebhookEndpointHandler)
+		v1_admin.Get("/webhooks", controller.GetWebhookEndpointsHandler)
+		v1_admin.Get("/webhooks/deliveries", controller.GetWebhookDeliveriesHandler)
+		v1_admin.Post("/webhooks/deliveries/:id/replay", controller.ReplayWebhookDeliveryHandler)
+		v1_admin.Put("/webhooks/:id", controller.UpdateWebhookEndpointHandler)
+		v1_admin.Delete("/webhooks/:id", controller.DeleteWebhookEndpointHandler)
 		// Currencies
 		v1_admin.Post("/currencies", controller.CreateCurrencyHandler)
 		v1_admin.Put("/currencies/:code", controller.UpdateCurrencyHandler)
@@ -7,7 +7,7 @@ import (
 
 type AdminManagementService interface {
 	// Payment Management
-	GetAllPayments() ([]model.Payment, error)
+	GetAllPayments(apiKeyID, externalReference string) ([]model.Payment, error)
 	DeletePayment(id string) error
 	// Wallet Management
 	GetAllWallets() ([]model.Wallet, error)
@@ -24,8 +24,8 @@ func NewAdminManagementService(paymentRepo repository.PaymentRepository) AdminMa
 	}
 }
 
-func (s *adminManagementService) GetAllPayments() ([]model.Payment, error) {
-	return s.paymentRepo.GetAllPayments()
+func (s *adminManagementService) GetAllPayments(apiKeyID, externalReference string) ([]model.Payment, error) {
+	return s.paymentRepo.GetAllPayments(apiKeyID, externalReference)
 }
 
 func (s *adminManagementService) DeletePayment(id string) error {
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/util"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	apiKeyPrefix    = "bp_"
+	apiKeyShownLen  = len(apiKeyPrefix) + 8 // characters of the key kept in the clear
+	apiKeyUsedEvery = time.Minute           // last_used_at is written at most this often per key
+)
+
+var (
+	ErrInvalidAPIKey      = errors.New("API key is invalid or has been revoked")
+	ErrAPIKeyMissingScope = errors.New("API key is missing the required scope")
+)
+
+var apiKeyScopes = []model.APIKeyScope{
+	model.ScopePaymentsCreate,
+	model.ScopePaymentsRead,
+	model.ScopeRefundsWrite,
+	model.ScopeSubscriptionsRead,
+}
+
+type APIKeyService interface {
+	CreateAPIKey(req dto.CreateAPIKeyRequest) (dto.APIKeySecretResponse, error)
+	GetAPIKeys() ([]model.APIKey, error)
+	RotateAPIKey(id string) (dto.APIKeySecretResponse, error)
+	RevokeAPIKey(id string) (*model.APIKey, error)
+	Authenticate(key string, scope model.APIKeyScope) (*model.APIKey, error)
+	Identify(secret string) (*model.APIKey, error)
+}
+
+type apiKeyService struct {
+	repo repository.APIKeyRepository
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository) APIKeyService {
+	return &apiKeyService{repo}
+}
+
+func (s *apiKeyService) CreateAPIKey(req dto.CreateAPIKeyRequest) (dto.APIKeySecretResponse, error) {
+	scopes, err := normalizeAPIKeyScopes(req.Scopes)
+	if err != nil {
+		return dto.APIKeySecretResponse{}, err
+	}
+
+	secret, err := generateAPIKey()
+	if err != nil {
+		return dto.APIKeySecretResponse{}, err
+	}
+
+	key := model.APIKey{
+		ID:      util.GenerateUniqueID(),
+		Name:    req.Name,
+		Prefix:  secret[:apiKeyShownLen],
+		KeyHash: hashAPIKey(secret),
+		Scopes:  scopes,
+	}
+	if err := s.repo.CreateAPIKey(&key); err != nil {
+		return dto.APIKeySecretResponse{}, err
+	}
+
+	log.Printf("API key %s (%s) issued with scopes %s", key.ID, key.Prefix, key.Scopes)
+	return dto.APIKeySecretResponse{APIKey: key, Key: secret}, nil
+}
+
+func (s *apiKeyService) GetAPIKeys() ([]model.APIKey, error) {
+	return s.repo.GetAPIKeys()
+}
+
+// RotateAPIKey replaces the secret of a key, keeping its name and scopes. The old secret stops
+// working straight away.
+func (s *apiKeyService) RotateAPIKey(id string) (dto.APIKeySecretResponse, error) {
+	key, err := s.repo.GetAPIKeyByID(id)
+	if err != nil {
+		return dto.APIKeySecretResponse{}, err
+	}
+	if key.RevokedAt != nil {
+		return dto.APIKeySecretResponse{}, fmt.Errorf("API key %s is revoked, issue a new one", key.Prefix)
+	}
+
+	secret, err := generateAPIKey()
+	if err != nil {
+		return dto.APIKeySecretResponse{}, err
+	}
+
+	now := time.Now()
+	key.Prefix = secret[:apiKeyShownLen]
+	key.KeyHash = hashAPIKey(secret)
+	key.RotatedAt = &now
+	if err := s.repo.UpdateAPIKey(key); err != nil {
+		return dto.APIKeySecretResponse{}, err
+	}
+
+	log.Printf("API key %s rotated, now %s", key.ID, key.Prefix)
+	return dto.APIKeySecretResponse{APIKey: *key, Key: secret}, nil
+}
+
+func (s *apiKeyService) RevokeAPIKey(id string) (*model.APIKey, error) {
+	key, err := s.repo.GetAPIKeyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return key, nil
+	}
+
+	now := time.Now()
+	key.RevokedAt = &now
+	if err := s.repo.UpdateAPIKey(key); err != nil {
+		return nil, err
+	}
+
+	log.Printf("API key %s (%s) revoked", key.ID, key.Prefix)
+	return key, nil
+}
+
+// Authenticate returns the key matching the presented secret, checking it is live and carries
+// scope, and records that it was used.
+func (s *apiKeyService) Authenticate(secret string, scope model.APIKeyScope) (*model.APIKey, error) {
+	key, err := s.Identify(secret)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(strings.Split(key.Scopes, ","), string(scope)) {
+		return nil, fmt.Errorf("%w: %s", ErrAPIKeyMissingScope, scope)
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyUsedEvery {
+		if err := s.repo.MarkAPIKeyUsed(key.ID, now); err != nil {
+			log.Printf("Failed to record use of API key %s: %v", key.ID, err)
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
+
+// Identify returns the live key matching the presented secret whatever its scopes, without
+// recording a use.
+func (s *apiKeyService) Identify(secret string) (*model.APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetAPIKeyByHash(hashAPIKey(secret))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	return key, nil
+}
+
+func generateAPIKey() (string, error) {
+	secret, err := util.GenerateRandomHex(24)
+	if err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + secret, nil
+}
+
+// hashAPIKey is a plain SHA-256, the keys are random so there is nothing to slow down guessing of.
+func hashAPIKey(secret string) string {
+	return sha256Hex([]byte(secret))
+}
+
+func normalizeAPIKeyScopes(scopes []string) (string, error) {
+	var normalized []string
+	for _, scope := range scopes {
+		if !slices.Contains(apiKeyScopes, model.APIKeyScope(scope)) {
+			return "", fmt.Errorf("unknown API key scope: %s", scope)
+		}
+		if !slices.Contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	return strings.Join(normalized, ","), nil
+}
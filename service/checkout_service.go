@@ -0,0 +1,133 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/internal/util"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+	"gorm.io/gorm"
+)
+
+type CheckoutService interface {
+	RenderCheckout(paymentID string) ([]byte, error)
+}
+
+type checkoutService struct {
+	repo repository.PaymentRepository
+}
+
+func NewCheckoutService(repo repository.PaymentRepository) CheckoutService {
+	return &checkoutService{repo: repo}
+}
+
+// checkoutView is what the checkout template is rendered with.
+type checkoutView struct {
+	PaymentID     string
+	PlanName      string
+	Status        model.PaymentStatus
+	Amount        float64 // still due, the remaining amount once partially paid
+	AmountUSD     float64
+	Price         string // the fiat price the payment was quoted from, "9.00 EUR"
+	Discount      string // taken off by the coupon, empty without one
+	PaidAmount    float64
+	Currency      string
+	WalletAddress string
+	QrImage       template.URL
+	ExpiresAt     string
+	SuccessURL    string
+	CancelURL     string
+	Theme         checkoutTheme
+}
+
+type checkoutTheme struct {
+	BrandName    string
+	PrimaryColor template.CSS
+	LogoURL      string
+}
+
+// RenderCheckout renders the hosted checkout page of a payment from CHECKOUT_TEMPLATE. The page
+// keeps itself up to date through the payment status API.
+func (s *checkoutService) RenderCheckout(paymentID string) ([]byte, error) {
+	payment, err := s.repo.FindPaymentById(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.ID == "" {
+		return nil, fmt.Errorf("payment %s: %w", paymentID, gorm.ErrRecordNotFound)
+	}
+
+	qrImage, err := util.GenerateQRCodeBase64(paymentQRContent(payment))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create qr : %w", err)
+	}
+
+	amount := payment.AmountTRX
+	if payment.Status == model.PartiallyPaid {
+		amount = payment.RemainingAmount
+	}
+
+	planName := payment.Plan.Name
+	if planName == "" {
+		planName = invoiceTitle(payment.Invoice)
+	}
+
+	view := checkoutView{
+		PaymentID:     payment.ID,
+		PlanName:      planName,
+		Status:        payment.Status,
+		Amount:        amount,
+		AmountUSD:     payment.AmountUSD,
+		Price:         formatFiat(paymentFiat(payment)),
+		Discount:      paymentDiscount(payment),
+		PaidAmount:    payment.PaidAmountTRX,
+		Currency:      currencyLabel(payment),
+		WalletAddress: payment.Wallet.WalletAddress,
+		// GenerateQRCodeBase64 only returns data:image/png URIs
+		QrImage:    template.URL(qrImage),
+		ExpiresAt:  formatExpiresAt(payment),
+		SuccessURL: checkoutRedirect(payment.SuccessURL, config.Cfg.CHECKOUT_SUCCESS_URL, payment),
+		CancelURL:  checkoutRedirect(payment.CancelURL, config.Cfg.CHECKOUT_CANCEL_URL, payment),
+		Theme: checkoutTheme{
+			BrandName:    config.Cfg.CHECKOUT_BRAND_NAME,
+			PrimaryColor: template.CSS(config.Cfg.CHECKOUT_PRIMARY_COLOR),
+			LogoURL:      config.Cfg.CHECKOUT_LOGO_URL,
+		},
+	}
+
+	tmpl, err := template.ParseFiles(config.Cfg.CHECKOUT_TEMPLATE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load checkout template: %w", err)
+	}
+
+	var page bytes.Buffer
+	if err := tmpl.Execute(&page, view); err != nil {
+		return nil, fmt.Errorf("failed to render checkout template: %w", err)
+	}
+	return page.Bytes(), nil
+}
+
+// checkoutRedirect picks the payment's own redirect URL over the configured one and fills its
+// {payment_id} placeholder.
+func checkoutRedirect(url, fallback string, payment model.Payment) string {
+	if url == "" {
+		url = fallback
+	}
+	url = strings.ReplaceAll(url, "{payment_id}", payment.ID)
+	if !isHTTPURL(url) {
+		return ""
+	}
+	return url
+}
+
+// paymentDiscount describes the coupon discount of payment, "SUMMER: -2.00 EUR".
+func paymentDiscount(payment model.Payment) string {
+	if payment.CouponCode == "" {
+		return ""
+	}
+	return payment.CouponCode + ": -" + formatFiat(payment.FiatCurrency, payment.DiscountAmount)
+}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/internal/util"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+	"gorm.io/gorm"
+)
+
+var ErrCouponNotRedeemable = errors.New("coupon can't be redeemed")
+
+type CouponService interface {
+	CreateCoupon(req dto.CreateCouponRequest) (*model.Coupon, error)
+	GetCoupons() ([]dto.CouponResponse, error)
+	UpdateCoupon(id string, req dto.UpdateCouponRequest) (*model.Coupon, error)
+	DeleteCoupon(id string) error
+	GetRedemptionReport(id string) (dto.CouponRedemptionReport, error)
+	Redeemable(code, email, planID string) (*model.Coupon, error)
+	Redeem(coupon *model.Coupon, payment model.Payment) error
+}
+
+type couponService struct {
+	repo        repository.CouponRepository
+	paymentRepo repository.PaymentRepository
+}
+
+func NewCouponService(repo repository.CouponRepository) CouponService {
+	return &couponService{
+		repo:        repo,
+		paymentRepo: repository.NewPaymentRepository(database.DB),
+	}
+}
+
+func (s *couponService) CreateCoupon(req dto.CreateCouponRequest) (*model.Coupon, error) {
+	coupon := model.Coupon{
+		ID:      util.GenerateUniqueID(),
+		Code:    normalizeCouponCode(req.Code),
+		Enabled: true,
+	}
+	if coupon.Code == "" {
+		return nil, errors.New("coupon code is required")
+	}
+	if _, err := s.repo.GetCouponByCode(coupon.Code); err == nil {
+		return nil, fmt.Errorf("coupon %s already exists", coupon.Code)
+	}
+
+	err := s.setTerms(&coupon, dto.UpdateCouponRequest{
+		Description:    req.Description,
+		Type:           req.Type,
+		Value:          req.Value,
+		Currency:       req.Currency,
+		PlanIDs:        req.PlanIDs,
+		StartsAt:       req.StartsAt,
+		EndsAt:         req.EndsAt,
+		MaxRedemptions: req.MaxRedemptions,
+		PerEmailLimit:  req.PerEmailLimit,
+		Enabled:        true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateCoupon(&coupon); err != nil {
+		return nil, err
+	}
+	log.Printf("Coupon %s created, %g %s off", coupon.Code, coupon.Value, couponUnit(coupon))
+	return &coupon, nil
+}
+
+func (s *couponService) GetCoupons() ([]dto.CouponResponse, error) {
+	coupons, err := s.repo.GetCoupons()
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]dto.CouponResponse, 0, len(coupons))
+	for _, coupon := range coupons {
+		response, err := s.couponResponse(coupon)
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// UpdateCoupon changes the terms of a coupon, payments already made with it keep their discount.
+func (s *couponService) UpdateCoupon(id string, req dto.UpdateCouponRequest) (*model.Coupon, error) {
+	coupon, err := s.repo.GetCouponByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.setTerms(coupon, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateCoupon(coupon); err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+// DeleteCoupon deletes a coupon that was never redeemed, redeemed ones are disabled instead so
+// their redemptions can still be reported.
+func (s *couponService) DeleteCoupon(id string) error {
+	payments, err := s.repo.GetRedemptions(id)
+	if err != nil {
+		return err
+	}
+	if len(payments) > 0 {
+		return fmt.Errorf("coupon was redeemed on %d payments, disable it instead", len(payments))
+	}
+	return s.repo.DeleteCoupon(id)
+}
+
+func (s *couponService) GetRedemptionReport(id string) (dto.CouponRedemptionReport, error) {
+	coupon, err := s.repo.GetCouponByID(id)
+	if err != nil {
+		return dto.CouponRedemptionReport{}, err
+	}
+	response, err := s.couponResponse(*coupon)
+	if err != nil {
+		return dto.CouponRedemptionReport{}, err
+	}
+	payments, err := s.repo.GetRedemptions(id)
+	if err != nil {
+		return dto.CouponRedemptionReport{}, err
+	}
+
+	report := dto.CouponRedemptionReport{
+		Coupon:         response,
+		DiscountTotals: make(map[string]float64),
+		RevenueTotals:  make(map[string]float64),
+		Redemptions:    make([]dto.CouponRedemption, 0, len(payments)),
+	}
+	for _, payment := range payments {
+		fiatCurrency, fiatAmount := paymentFiat(payment)
+		report.Redemptions = append(report.Redemptions, dto.CouponRedemption{
+			PaymentID:      payment.ID,
+			Email:          payment.UserEmail,
+			PlanID:         payment.PlanID,
+			Status:         payment.Status,
+			FiatCurrency:   fiatCurrency,
+			OriginalAmount: payment.OriginalAmount,
+			DiscountAmount: payment.DiscountAmount,
+			FiatAmount:     fiatAmount,
+			CreatedAt:      payment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		})
+		if payment.Status == model.Completed {
+			report.Completed++
+			report.DiscountTotals[fiatCurrency] = roundCents(report.DiscountTotals[fiatCurrency] + payment.DiscountAmount)
+			report.RevenueTotals[fiatCurrency] = roundCents(report.RevenueTotals[fiatCurrency] + fiatAmount)
+		}
+	}
+	return report, nil
+}
+
+// Redeemable returns the coupon with code when email may redeem it on a payment for planID now.
+func (s *couponService) Redeemable(code, email, planID string) (*model.Coupon, error) {
+	code = normalizeCouponCode(code)
+	coupon, err := s.repo.GetCouponByCode(code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s is not a valid coupon", ErrCouponNotRedeemable, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	switch {
+	case !coupon.Enabled:
+		return nil, fmt.Errorf("%w: %s is not a valid coupon", ErrCouponNotRedeemable, code)
+	case coupon.StartsAt != nil && now.Before(*coupon.StartsAt):
+		return nil, fmt.Errorf("%w: %s isn't valid yet", ErrCouponNotRedeemable, code)
+	case coupon.EndsAt != nil && now.After(*coupon.EndsAt):
+		return nil, fmt.Errorf("%w: %s has expired", ErrCouponNotRedeemable, code)
+	case coupon.PlanIDs != "" && !slices.Contains(strings.Split(coupon.PlanIDs, ","), planID):
+		return nil, fmt.Errorf("%w: %s doesn't apply to this plan", ErrCouponNotRedeemable, code)
+	}
+
+	if err := checkRedemptionLimits(s.repo, coupon, email); err != nil {
+		return nil, err
+	}
+	return coupon, nil
+}
+
+// Redeem creates payment, which redeems coupon. Redeemable only gives an early answer, the limits
+// are checked again here with the coupon locked so concurrent checkouts can't over-redeem it.
+func (s *couponService) Redeem(coupon *model.Coupon, payment model.Payment) error {
+	return s.repo.RedeemCoupon(coupon.ID, payment, func(repo repository.CouponRepository, locked *model.Coupon) error {
+		if !locked.Enabled {
+			return fmt.Errorf("%w: %s is not a valid coupon", ErrCouponNotRedeemable, locked.Code)
+		}
+		return checkRedemptionLimits(repo, locked, payment.UserEmail)
+	})
+}
+
+// checkRedemptionLimits checks coupon has redemptions left, in total and for email.
+func checkRedemptionLimits(repo repository.CouponRepository, coupon *model.Coupon, email string) error {
+	if coupon.MaxRedemptions > 0 {
+		count, err := repo.CountRedemptions(coupon.ID, "")
+		if err != nil {
+			return err
+		}
+		if count >= int64(coupon.MaxRedemptions) {
+			return fmt.Errorf("%w: %s has been fully redeemed", ErrCouponNotRedeemable, coupon.Code)
+		}
+	}
+	if coupon.PerEmailLimit > 0 {
+		count, err := repo.CountRedemptions(coupon.ID, email)
+		if err != nil {
+			return err
+		}
+		if count >= int64(coupon.PerEmailLimit) {
+			return fmt.Errorf("%w: %s was already used with this email", ErrCouponNotRedeemable, coupon.Code)
+		}
+	}
+	return nil
+}
+
+// setTerms validates the terms in req and sets them on coupon.
+func (s *couponService) setTerms(coupon *model.Coupon, req dto.UpdateCouponRequest) error {
+	couponType := model.CouponType(req.Type)
+	currency := strings.ToUpper(req.Currency)
+	switch couponType {
+	case model.CouponPercent:
+		if req.Value >= 100 {
+			return errors.New("percent coupons must take off less than 100%")
+		}
+		currency = ""
+	case model.CouponFixed:
+		if currency == "" {
+			currency = "USD"
+		}
+	default:
+		return fmt.Errorf("unknown coupon type: %s", req.Type)
+	}
+
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return errors.New("coupon must end after it starts")
+	}
+
+	var planIDs []string
+	for _, planID := range req.PlanIDs {
+		if slices.Contains(planIDs, planID) {
+			continue
+		}
+		if _, err := s.paymentRepo.FindPlanById(planID); err != nil {
+			return fmt.Errorf("plan %s not found: %w", planID, err)
+		}
+		planIDs = append(planIDs, planID)
+	}
+
+	coupon.Description = req.Description
+	coupon.Type = couponType
+	coupon.Value = req.Value
+	coupon.Currency = currency
+	coupon.PlanIDs = strings.Join(planIDs, ",")
+	coupon.StartsAt = req.StartsAt
+	coupon.EndsAt = req.EndsAt
+	coupon.MaxRedemptions = req.MaxRedemptions
+	coupon.PerEmailLimit = req.PerEmailLimit
+	coupon.Enabled = req.Enabled
+	return nil
+}
+
+func (s *couponService) couponResponse(coupon model.Coupon) (dto.CouponResponse, error) {
+	count, err := s.repo.CountRedemptions(coupon.ID, "")
+	if err != nil {
+		return dto.CouponResponse{}, err
+	}
+
+	response := dto.CouponResponse{Coupon: coupon, Redemptions: count}
+	if coupon.MaxRedemptions > 0 {
+		remaining := max(int64(coupon.MaxRedemptions)-count, 0)
+		response.RemainingRedemptions = &remaining
+	}
+	return response, nil
+}
+
+func normalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// couponUnit is what a coupon's value is in, "%" or the currency of fixed discounts.
+func couponUnit(coupon model.Coupon) string {
+	if coupon.Type == model.CouponPercent {
+		return "%"
+	}
+	return coupon.Currency
+}
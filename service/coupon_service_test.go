@@ -0,0 +1,74 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+	"gorm.io/gorm"
+)
+
+// fakePlanRepository only knows the plans it was given, the other methods aren't used by the
+// tests.
+type fakePlanRepository struct {
+	repository.PaymentRepository
+	plans []string
+}
+
+func (r *fakePlanRepository) FindPlanById(id string) (model.Plan, error) {
+	for _, plan := range r.plans {
+		if plan == id {
+			return model.Plan{ID: id}, nil
+		}
+	}
+	return model.Plan{}, gorm.ErrRecordNotFound
+}
+
+func TestSetTerms(t *testing.T) {
+	s := &couponService{paymentRepo: &fakePlanRepository{plans: []string{"basic", "pro"}}}
+	starts := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ends := starts.AddDate(0, 1, 0)
+
+	tests := []struct {
+		name         string
+		req          dto.UpdateCouponRequest
+		wantCurrency string
+		wantPlanIDs  string
+		wantErr      bool
+	}{
+		{"percent", dto.UpdateCouponRequest{Type: "percent", Value: 20}, "", "", false},
+		{"percent drops the currency", dto.UpdateCouponRequest{Type: "percent", Value: 20, Currency: "EUR"}, "", "", false},
+		{"percent of 100 or more", dto.UpdateCouponRequest{Type: "percent", Value: 100}, "", "", true},
+		{"fixed defaults to USD", dto.UpdateCouponRequest{Type: "fixed", Value: 5}, "USD", "", false},
+		{"fixed currency upper-cased", dto.UpdateCouponRequest{Type: "fixed", Value: 5, Currency: "eur"}, "EUR", "", false},
+		{"unknown type", dto.UpdateCouponRequest{Type: "bogo", Value: 1}, "", "", true},
+		{"validity window", dto.UpdateCouponRequest{Type: "percent", Value: 10, StartsAt: &starts, EndsAt: &ends}, "", "", false},
+		{"ends before it starts", dto.UpdateCouponRequest{Type: "percent", Value: 10, StartsAt: &ends, EndsAt: &starts}, "", "", true},
+		{"ends when it starts", dto.UpdateCouponRequest{Type: "percent", Value: 10, StartsAt: &starts, EndsAt: &starts}, "", "", true},
+		{"plans deduplicated", dto.UpdateCouponRequest{Type: "percent", Value: 10, PlanIDs: []string{"pro", "basic", "pro"}}, "", "pro,basic", false},
+		{"unknown plan", dto.UpdateCouponRequest{Type: "percent", Value: 10, PlanIDs: []string{"basic", "gone"}}, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coupon := model.Coupon{Code: "TEST"}
+			err := s.setTerms(&coupon, tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("setTerms() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if coupon.Type != model.CouponType(tt.req.Type) || coupon.Value != tt.req.Value {
+				t.Errorf("setTerms() = %s %v, want %s %v", coupon.Type, coupon.Value, tt.req.Type, tt.req.Value)
+			}
+			if coupon.Currency != tt.wantCurrency {
+				t.Errorf("setTerms() currency = %q, want %q", coupon.Currency, tt.wantCurrency)
+			}
+			if coupon.PlanIDs != tt.wantPlanIDs {
+				t.Errorf("setTerms() plan IDs = %q, want %q", coupon.PlanIDs, tt.wantPlanIDs)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+)
+
+type DepositService interface {
+	GetDeposits(paymentID string) ([]model.Deposit, error)
+}
+
+type depositService struct {
+	repo repository.DepositRepository
+}
+
+func NewDepositService(repo repository.DepositRepository) DepositService {
+	return &depositService{repo}
+}
+
+func (s *depositService) GetDeposits(paymentID string) ([]model.Deposit, error) {
+	return s.repo.GetDeposits(paymentID)
+}
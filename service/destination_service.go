@@ -0,0 +1,252 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/internal/tron"
+	"github.com/thebytearray/BytePayments/internal/util"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+)
+
+type DestinationService interface {
+	CreateDestination(req dto.CreateDestinationRequest) (*model.DestinationWallet, error)
+	GetDestinations() ([]model.DestinationWallet, error)
+	UpdateDestination(id string, req dto.UpdateDestinationRequest) (*model.DestinationWallet, error)
+	DeleteDestination(id string) error
+	CreateRule(req dto.CreateSweepRuleRequest) (*model.SweepRule, error)
+	GetRules() ([]model.SweepRule, error)
+	UpdateRule(id string, req dto.UpdateSweepRuleRequest) (*model.SweepRule, error)
+	DeleteRule(id string) error
+	ResolveRule(payment model.Payment) (*model.SweepRule, error)
+}
+
+type destinationService struct {
+	repo        repository.DestinationRepository
+	paymentRepo repository.PaymentRepository
+}
+
+func NewDestinationService(repo repository.DestinationRepository) DestinationService {
+	return &destinationService{
+		repo:        repo,
+		paymentRepo: repository.NewPaymentRepository(database.DB),
+	}
+}
+
+func (s *destinationService) CreateDestination(req dto.CreateDestinationRequest) (*model.DestinationWallet, error) {
+	if err := tron.ValidateAddress(req.Address); err != nil {
+		return nil, err
+	}
+
+	destination := model.DestinationWallet{
+		ID:      util.GenerateUniqueID(),
+		Name:    req.Name,
+		Address: req.Address,
+		Enabled: true,
+	}
+	if err := s.repo.CreateDestination(&destination); err != nil {
+		return nil, err
+	}
+	return &destination, nil
+}
+
+func (s *destinationService) GetDestinations() ([]model.DestinationWallet, error) {
+	return s.repo.GetDestinations()
+}
+
+func (s *destinationService) UpdateDestination(id string, req dto.UpdateDestinationRequest) (*model.DestinationWallet, error) {
+	destination, err := s.repo.GetDestinationByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tron.ValidateAddress(req.Address); err != nil {
+		return nil, err
+	}
+
+	destination.Name = req.Name
+	destination.Address = req.Address
+	destination.Enabled = req.Enabled
+
+	if err := s.repo.UpdateDestination(destination); err != nil {
+		return nil, err
+	}
+	return destination, nil
+}
+
+func (s *destinationService) DeleteDestination(id string) error {
+	count, err := s.repo.CountSplitsByDestination(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("destination is used by %d sweep rule splits, remove it from the rules first", count)
+	}
+	return s.repo.DeleteDestination(id)
+}
+
+func (s *destinationService) CreateRule(req dto.CreateSweepRuleRequest) (*model.SweepRule, error) {
+	if err := s.validateRuleScope(req.CurrencyCode, req.PlanID); err != nil {
+		return nil, err
+	}
+
+	rule := model.SweepRule{
+		ID:           util.GenerateUniqueID(),
+		Name:         req.Name,
+		CurrencyCode: req.CurrencyCode,
+		PlanID:       req.PlanID,
+		Priority:     req.Priority,
+		Enabled:      true,
+	}
+
+	splits, err := s.buildSplits(rule.ID, req.Splits)
+	if err != nil {
+		return nil, err
+	}
+	rule.Splits = splits
+
+	if err := s.repo.CreateRule(&rule); err != nil {
+		return nil, err
+	}
+	return s.repo.GetRuleByID(rule.ID)
+}
+
+func (s *destinationService) GetRules() ([]model.SweepRule, error) {
+	return s.repo.GetRules()
+}
+
+func (s *destinationService) UpdateRule(id string, req dto.UpdateSweepRuleRequest) (*model.SweepRule, error) {
+	rule, err := s.repo.GetRuleByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validateRuleScope(req.CurrencyCode, req.PlanID); err != nil {
+		return nil, err
+	}
+
+	splits, err := s.buildSplits(rule.ID, req.Splits)
+	if err != nil {
+		return nil, err
+	}
+
+	rule.Name = req.Name
+	rule.CurrencyCode = req.CurrencyCode
+	rule.PlanID = req.PlanID
+	rule.Priority = req.Priority
+	rule.Enabled = req.Enabled
+	rule.Splits = splits
+
+	if err := s.repo.UpdateRule(rule); err != nil {
+		return nil, err
+	}
+	return s.repo.GetRuleByID(rule.ID)
+}
+
+func (s *destinationService) DeleteRule(id string) error {
+	return s.repo.DeleteRule(id)
+}
+
+// ResolveRule returns the sweep rule for payment, or nil when none matches and the funds
+// go to the hot wallet. Rules pointing at a disabled destination are skipped.
+func (s *destinationService) ResolveRule(payment model.Payment) (*model.SweepRule, error) {
+	rules, err := s.repo.GetEnabledRules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sweep rules: %w", err)
+	}
+
+	var best *model.SweepRule
+	bestScore := -1
+	for i := range rules {
+		rule := &rules[i]
+		if rule.CurrencyCode != "" && rule.CurrencyCode != payment.CurrencyCode {
+			continue
+		}
+		if rule.PlanID != "" && rule.PlanID != payment.PlanID {
+			continue
+		}
+		if !usableRule(rule) {
+			log.Printf("Sweep rule %s has a disabled destination, skipping it", rule.ID)
+			continue
+		}
+
+		// a plan match is more specific than a currency match
+		score := 0
+		if rule.PlanID != "" {
+			score += 2
+		}
+		if rule.CurrencyCode != "" {
+			score++
+		}
+
+		if best == nil || score > bestScore || (score == bestScore && rule.Priority > best.Priority) {
+			best = rule
+			bestScore = score
+		}
+	}
+	return best, nil
+}
+
+func (s *destinationService) validateRuleScope(currencyCode, planID string) error {
+	if currencyCode != "" {
+		if _, err := s.paymentRepo.FindCurrencyByCode(currencyCode); err != nil {
+			return fmt.Errorf("currency %s not found: %w", currencyCode, err)
+		}
+	}
+	if planID != "" {
+		if _, err := s.paymentRepo.FindPlanById(planID); err != nil {
+			return fmt.Errorf("plan %s not found: %w", planID, err)
+		}
+	}
+	return nil
+}
+
+func (s *destinationService) buildSplits(ruleID string, reqs []dto.SweepSplitRequest) ([]model.SweepSplit, error) {
+	var total float64
+	seen := make(map[string]bool)
+	splits := make([]model.SweepSplit, 0, len(reqs))
+
+	for _, req := range reqs {
+		if seen[req.DestinationID] {
+			return nil, fmt.Errorf("destination %s is used twice in the same rule", req.DestinationID)
+		}
+		seen[req.DestinationID] = true
+
+		destination, err := s.repo.GetDestinationByID(req.DestinationID)
+		if err != nil {
+			return nil, fmt.Errorf("destination %s not found: %w", req.DestinationID, err)
+		}
+		if !destination.Enabled {
+			return nil, fmt.Errorf("destination %s is disabled", destination.Name)
+		}
+
+		total += req.Percent
+		splits = append(splits, model.SweepSplit{
+			ID:            util.GenerateUniqueID(),
+			RuleID:        ruleID,
+			DestinationID: req.DestinationID,
+			Percent:       req.Percent,
+		})
+	}
+
+	if math.Abs(total-100) > 1e-9 {
+		return nil, fmt.Errorf("split percentages add up to %g, they must add up to 100", total)
+	}
+	return splits, nil
+}
+
+func usableRule(rule *model.SweepRule) bool {
+	if len(rule.Splits) == 0 {
+		return false
+	}
+	for _, split := range rule.Splits {
+		if !split.Destination.Enabled {
+			return false
+		}
+	}
+	return true
+}
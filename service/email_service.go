@@ -1,7 +1,9 @@
 package service
 
 import (
+	"bytes"
 	"fmt"
+	"html"
 	"io/ioutil"
 	"net/smtp"
 	"strings"
@@ -17,6 +19,11 @@ type EmailService interface {
 	SendPaymentCompletionEmail(payment model.Payment, plan model.Plan) error
 	SendUnderpaymentEmail(payment model.Payment, plan model.Plan, remainingAmount float64) error
 	SendOverpaymentEmail(payment model.Payment, plan model.Plan, overpaidAmount float64) error
+	SendRefundLinkEmail(payment model.Payment, refund model.Refund, link string) error
+	SendSubscriptionReminderEmail(subscription model.Subscription) error
+	SendSubscriptionExpiredEmail(subscription model.Subscription) error
+	SendRenewalInvoiceEmail(payment model.Payment, subscription model.Subscription, link string) error
+	SendInvoiceEmail(payment model.Payment, invoice model.Invoice, link string) error
 }
 
 type emailService struct{}
@@ -80,6 +87,9 @@ func (e *emailService) SendPaymentCompletionEmail(payment model.Payment, plan mo
 	
 	// Safety check for plan name
 	planName := plan.Name
+	if planName == "" {
+		planName = html.EscapeString(invoiceTitle(payment.Invoice))
+	}
 	if planName == "" {
 		planName = "Selected Plan" // fallback
 	}
@@ -90,6 +100,7 @@ func (e *emailService) SendPaymentCompletionEmail(payment model.Payment, plan mo
 		"{{AMOUNT_PAID}}":     fmt.Sprintf("%.2f", payment.PaidAmountTRX),
 		"{{USER_EMAIL}}":      payment.UserEmail,
 		"{{COMPLETION_DATE}}": completionDate,
+		"{{CURRENCY}}":        currencyLabel(payment),
 	}
 
 	// Debug logging
@@ -113,10 +124,17 @@ func (e *emailService) SendUnderpaymentEmail(payment model.Payment, plan model.P
 		return fmt.Errorf("failed to load underpayment email template: %w", err)
 	}
 
-	expiryTime := payment.CreatedAt.Add(15 * time.Minute).Format("January 2, 2006 at 3:04 PM MST")
+	expiresAt := payment.CreatedAt.Add(15 * time.Minute)
+	if payment.ExpiresAt != nil {
+		expiresAt = *payment.ExpiresAt
+	}
+	expiryTime := expiresAt.Format("January 2, 2006 at 3:04 PM MST")
 	
 	// Safety checks
 	planName := plan.Name
+	if planName == "" {
+		planName = html.EscapeString(invoiceTitle(payment.Invoice))
+	}
 	if planName == "" {
 		planName = "Selected Plan" // fallback
 	}
@@ -134,6 +152,7 @@ func (e *emailService) SendUnderpaymentEmail(payment model.Payment, plan model.P
 		"{{REMAINING_AMOUNT}}": fmt.Sprintf("%.2f", remainingAmount),
 		"{{WALLET_ADDRESS}}":   walletAddress,
 		"{{EXPIRY_TIME}}":      expiryTime,
+		"{{CURRENCY}}":         currencyLabel(payment),
 	}
 
 	htmlContent := e.replaceTemplateVars(template, replacements)
@@ -158,6 +177,9 @@ func (e *emailService) SendOverpaymentEmail(payment model.Payment, plan model.Pl
 	
 	// Safety check for plan name
 	planName := plan.Name
+	if planName == "" {
+		planName = html.EscapeString(invoiceTitle(payment.Invoice))
+	}
 	if planName == "" {
 		planName = "Selected Plan" // fallback
 	}
@@ -170,6 +192,7 @@ func (e *emailService) SendOverpaymentEmail(payment model.Payment, plan model.Pl
 		"{{USER_EMAIL}}":       payment.UserEmail,
 		"{{COMPLETION_DATE}}":  completionDate,
 		"{{OVERPAID_AMOUNT}}":  fmt.Sprintf("%.2f", overpaidAmount),
+		"{{CURRENCY}}":         currencyLabel(payment),
 	}
 
 	htmlContent := e.replaceTemplateVars(template, replacements)
@@ -184,6 +207,168 @@ func (e *emailService) SendOverpaymentEmail(payment model.Payment, plan model.Pl
 	return em.Send(fmt.Sprintf("%s:%d", config.Cfg.EMAIL_SMTP_HOST, config.Cfg.EMAIL_SMTP_PORT), auth)
 }
 
+func (e *emailService) SendRefundLinkEmail(payment model.Payment, refund model.Refund, link string) error {
+	template, err := e.loadTemplate("static/email_refund_link.html")
+	if err != nil {
+		return fmt.Errorf("failed to load refund link email template: %w", err)
+	}
+
+	replacements := map[string]string{
+		"{{PAYMENT_ID}}":    payment.ID,
+		"{{REFUND_ID}}":     refund.ID,
+		"{{REFUND_AMOUNT}}": fmt.Sprintf("%.6f", refund.Amount),
+		"{{REASON}}":        string(refund.Reason),
+		"{{REFUND_LINK}}":   link,
+		"{{LINK_EXPIRES}}":  refund.LinkExpiresAt.Format("January 2, 2006 at 3:04 PM MST"),
+		"{{CURRENCY}}":      currencyLabel(payment),
+	}
+
+	htmlContent := e.replaceTemplateVars(template, replacements)
+
+	em := email.NewEmail()
+	em.From = fmt.Sprintf("%s <%s>", config.Cfg.EMAIL_FROM_NAME, config.Cfg.EMAIL_FROM_ADDR)
+	em.To = []string{payment.UserEmail}
+	em.Subject = "Refund Address Required - BytePayments"
+	em.HTML = []byte(htmlContent)
+
+	auth := smtp.PlainAuth("", config.Cfg.EMAIL_USERNAME, config.Cfg.EMAIL_PASSWORD, config.Cfg.EMAIL_SMTP_HOST)
+	return em.Send(fmt.Sprintf("%s:%d", config.Cfg.EMAIL_SMTP_HOST, config.Cfg.EMAIL_SMTP_PORT), auth)
+}
+
+func (e *emailService) SendSubscriptionReminderEmail(subscription model.Subscription) error {
+	return e.sendSubscriptionEmail("static/email_subscription_reminder.html", "Your Subscription Is Ending Soon - BytePayments", subscription)
+}
+
+func (e *emailService) SendSubscriptionExpiredEmail(subscription model.Subscription) error {
+	return e.sendSubscriptionEmail("static/email_subscription_expired.html", "Your Subscription Has Expired - BytePayments", subscription)
+}
+
+func (e *emailService) SendRenewalInvoiceEmail(payment model.Payment, subscription model.Subscription, link string) error {
+	template, err := e.loadTemplate("static/email_renewal_invoice.html")
+	if err != nil {
+		return fmt.Errorf("failed to load renewal invoice email template: %w", err)
+	}
+
+	planName := payment.Plan.Name
+	if planName == "" {
+		planName = "Selected Plan" // fallback
+	}
+
+	quoteExpires := ""
+	if payment.QuoteExpiresAt != nil {
+		quoteExpires = payment.QuoteExpiresAt.Format("January 2, 2006 at 3:04 PM MST")
+	}
+
+	replacements := map[string]string{
+		"{{PAYMENT_ID}}":    payment.ID,
+		"{{PLAN_NAME}}":     planName,
+		"{{AMOUNT}}":        fmt.Sprintf("%.6f", payment.AmountTRX),
+		"{{PRICE}}":         formatFiat(paymentFiat(payment)),
+		"{{QUOTE_EXPIRES}}": quoteExpires,
+		"{{ENDS_AT}}":       subscription.EndsAt.Format("January 2, 2006 at 3:04 PM MST"),
+		"{{PAY_LINK}}":      link,
+		"{{CURRENCY}}":      currencyLabel(payment),
+	}
+
+	htmlContent := e.replaceTemplateVars(template, replacements)
+
+	em := email.NewEmail()
+	em.From = fmt.Sprintf("%s <%s>", config.Cfg.EMAIL_FROM_NAME, config.Cfg.EMAIL_FROM_ADDR)
+	em.To = []string{payment.UserEmail}
+	em.Subject = "Renewal Invoice - BytePayments"
+	em.HTML = []byte(htmlContent)
+
+	auth := smtp.PlainAuth("", config.Cfg.EMAIL_USERNAME, config.Cfg.EMAIL_PASSWORD, config.Cfg.EMAIL_SMTP_HOST)
+	return em.Send(fmt.Sprintf("%s:%d", config.Cfg.EMAIL_SMTP_HOST, config.Cfg.EMAIL_SMTP_PORT), auth)
+}
+
+// SendInvoiceEmail sends a new invoice with its PDF attached and a link to pay it.
+func (e *emailService) SendInvoiceEmail(payment model.Payment, invoice model.Invoice, link string) error {
+	template, err := e.loadTemplate("static/email_invoice.html")
+	if err != nil {
+		return fmt.Errorf("failed to load invoice email template: %w", err)
+	}
+
+	pdf, err := invoicePDF(invoice, payment)
+	if err != nil {
+		return err
+	}
+
+	quoteExpires := ""
+	if payment.QuoteExpiresAt != nil {
+		quoteExpires = payment.QuoteExpiresAt.Format("January 2, 2006 at 3:04 PM MST")
+	}
+	expiresAt := ""
+	if payment.ExpiresAt != nil {
+		expiresAt = payment.ExpiresAt.Format("January 2, 2006 at 3:04 PM MST")
+	}
+
+	replacements := map[string]string{
+		"{{PAYMENT_ID}}":    payment.ID,
+		"{{INVOICE_ID}}":    invoice.ID,
+		"{{DESCRIPTION}}":   html.EscapeString(invoiceTitle(&invoice)),
+		"{{AMOUNT}}":        fmt.Sprintf("%.6f", payment.AmountTRX),
+		"{{PRICE}}":         formatFiat(invoice.Currency, invoice.Amount),
+		"{{QUOTE_EXPIRES}}": quoteExpires,
+		"{{EXPIRES_AT}}":    expiresAt,
+		"{{PAY_LINK}}":      link,
+		"{{CURRENCY}}":      currencyLabel(payment),
+	}
+
+	htmlContent := e.replaceTemplateVars(template, replacements)
+
+	em := email.NewEmail()
+	em.From = fmt.Sprintf("%s <%s>", config.Cfg.EMAIL_FROM_NAME, config.Cfg.EMAIL_FROM_ADDR)
+	em.To = []string{invoice.Email}
+	em.Subject = "Invoice " + invoice.ID + " - BytePayments"
+	em.HTML = []byte(htmlContent)
+	if _, err := em.Attach(bytes.NewReader(pdf), "invoice-"+invoice.ID+".pdf", "application/pdf"); err != nil {
+		return fmt.Errorf("failed to attach invoice pdf: %w", err)
+	}
+
+	auth := smtp.PlainAuth("", config.Cfg.EMAIL_USERNAME, config.Cfg.EMAIL_PASSWORD, config.Cfg.EMAIL_SMTP_HOST)
+	return em.Send(fmt.Sprintf("%s:%d", config.Cfg.EMAIL_SMTP_HOST, config.Cfg.EMAIL_SMTP_PORT), auth)
+}
+
+func (e *emailService) sendSubscriptionEmail(templatePath, subject string, subscription model.Subscription) error {
+	template, err := e.loadTemplate(templatePath)
+	if err != nil {
+		return fmt.Errorf("failed to load subscription email template: %w", err)
+	}
+
+	planName := subscription.Plan.Name
+	if planName == "" {
+		planName = "Selected Plan" // fallback
+	}
+
+	replacements := map[string]string{
+		"{{SUBSCRIPTION_ID}}": subscription.ID,
+		"{{PLAN_NAME}}":       planName,
+		"{{USER_EMAIL}}":      subscription.Email,
+		"{{ENDS_AT}}":         subscription.EndsAt.Format("January 2, 2006 at 3:04 PM MST"),
+		"{{RENEW_URL}}":       config.Cfg.APP_URL,
+	}
+
+	htmlContent := e.replaceTemplateVars(template, replacements)
+
+	em := email.NewEmail()
+	em.From = fmt.Sprintf("%s <%s>", config.Cfg.EMAIL_FROM_NAME, config.Cfg.EMAIL_FROM_ADDR)
+	em.To = []string{subscription.Email}
+	em.Subject = subject
+	em.HTML = []byte(htmlContent)
+
+	auth := smtp.PlainAuth("", config.Cfg.EMAIL_USERNAME, config.Cfg.EMAIL_PASSWORD, config.Cfg.EMAIL_SMTP_HOST)
+	return em.Send(fmt.Sprintf("%s:%d", config.Cfg.EMAIL_SMTP_HOST, config.Cfg.EMAIL_SMTP_PORT), auth)
+}
+
+// currencyLabel is the unit amounts are shown in, TRX for payments made before tokens were supported
+func currencyLabel(payment model.Payment) string {
+	if payment.CurrencyCode == "" {
+		return "TRX"
+	}
+	return payment.CurrencyCode
+}
+
 func (e *emailService) loadTemplate(templatePath string) (string, error) {
 	content, err := ioutil.ReadFile(templatePath)
 	if err != nil {
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdempotencyKeyReused   = errors.New("idempotency key was already used with a different request")
+	ErrIdempotencyKeyInFlight = errors.New("a request with this idempotency key is still being processed")
+)
+
+type IdempotencyService interface {
+	Begin(scope, key, method, path string, body []byte) (*model.IdempotencyKey, bool, error)
+	Complete(record *model.IdempotencyKey, statusCode int, response []byte)
+	Release(record *model.IdempotencyKey)
+	PurgeExpired()
+}
+
+type idempotencyService struct {
+	repo repository.IdempotencyRepository
+}
+
+func NewIdempotencyService(repo repository.IdempotencyRepository) IdempotencyService {
+	return &idempotencyService{repo}
+}
+
+// Begin claims key for a request, scope being whatever identifies the caller. It returns the
+// stored record and true when the request was already answered and its response must be replayed,
+// or a fresh record to Complete once this request is done.
+func (s *idempotencyService) Begin(scope, key, method, path string, body []byte) (*model.IdempotencyKey, bool, error) {
+	id := sha256Hex([]byte(scope + "\x00" + key))
+	requestHash := sha256Hex([]byte(method + "\x00" + path + "\x00" + string(body)))
+
+	existing, err := s.repo.GetKeyByID(id)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, err
+	}
+	if existing != nil && time.Now().After(existing.ExpiresAt) {
+		if err := s.repo.DeleteKey(id); err != nil {
+			return nil, false, err
+		}
+		existing = nil
+	}
+	if existing != nil {
+		if existing.RequestHash != requestHash {
+			return nil, false, ErrIdempotencyKeyReused
+		}
+		if existing.StatusCode == 0 {
+			return nil, false, ErrIdempotencyKeyInFlight
+		}
+		return existing, true, nil
+	}
+
+	record := model.IdempotencyKey{
+		ID:          id,
+		Method:      method,
+		Path:        path,
+		RequestHash: requestHash,
+		ExpiresAt:   time.Now().Add(time.Duration(config.Cfg.IDEMPOTENCY_TTL_HOURS) * time.Hour),
+	}
+	if err := s.repo.CreateKey(&record); err != nil {
+		// lost the race against a concurrent request with the same key
+		if _, getErr := s.repo.GetKeyByID(id); getErr == nil {
+			return nil, false, ErrIdempotencyKeyInFlight
+		}
+		return nil, false, fmt.Errorf("failed to store idempotency key: %w", err)
+	}
+	return &record, false, nil
+}
+
+// Complete stores the response of the request for replays. Only successes and rejections of the
+// request itself are stored, anything else may well succeed on a retry so the key is released.
+func (s *idempotencyService) Complete(record *model.IdempotencyKey, statusCode int, response []byte) {
+	if !replayable(statusCode) {
+		s.Release(record)
+		return
+	}
+
+	record.StatusCode = statusCode
+	record.Response = string(response)
+	if err := s.repo.UpdateKey(record); err != nil {
+		log.Printf("Failed to store response of idempotency key %s: %v", record.ID, err)
+	}
+}
+
+func (s *idempotencyService) Release(record *model.IdempotencyKey) {
+	if err := s.repo.DeleteKey(record.ID); err != nil {
+		log.Printf("Failed to release idempotency key %s: %v", record.ID, err)
+	}
+}
+
+func (s *idempotencyService) PurgeExpired() {
+	purged, err := s.repo.DeleteExpiredKeys(time.Now())
+	if err != nil {
+		log.Printf("Failed to purge expired idempotency keys: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("Purged %d expired idempotency keys", purged)
+	}
+}
+
+// replayable reports whether a response of statusCode answers the request for good. 417 and 422,
+// which payment and invoice creation answer every failure with, an unreachable price source
+// included, aren't.
+func replayable(statusCode int) bool {
+	switch {
+	case statusCode >= 200 && statusCode < 300:
+		return true
+	case statusCode == 400, statusCode == 404:
+		return true
+	default:
+		return false
+	}
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
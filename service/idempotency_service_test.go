@@ -0,0 +1,204 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/model"
+	"gorm.io/gorm"
+)
+
+// memoryIdempotencyRepository keeps idempotency keys in a map.
+type memoryIdempotencyRepository struct {
+	keys map[string]model.IdempotencyKey
+}
+
+func newMemoryIdempotencyRepository() *memoryIdempotencyRepository {
+	return &memoryIdempotencyRepository{keys: map[string]model.IdempotencyKey{}}
+}
+
+func (r *memoryIdempotencyRepository) CreateKey(key *model.IdempotencyKey) error {
+	if _, ok := r.keys[key.ID]; ok {
+		return gorm.ErrDuplicatedKey
+	}
+	r.keys[key.ID] = *key
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) GetKeyByID(id string) (*model.IdempotencyKey, error) {
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &key, nil
+}
+
+func (r *memoryIdempotencyRepository) UpdateKey(key *model.IdempotencyKey) error {
+	r.keys[key.ID] = *key
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteKey(id string) error {
+	delete(r.keys, id)
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) DeleteExpiredKeys(now time.Time) (int64, error) {
+	var deleted int64
+	for id, key := range r.keys {
+		if now.After(key.ExpiresAt) {
+			delete(r.keys, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func TestIdempotencyBegin(t *testing.T) {
+	config.Cfg = &config.Config{IDEMPOTENCY_TTL_HOURS: 24}
+	body := []byte(`{"plan_id":"plan1"}`)
+
+	tests := []struct {
+		name       string
+		setup      func(s IdempotencyService)
+		scope      string
+		body       []byte
+		wantReplay bool
+		wantErr    error
+	}{
+		{
+			name:  "first request",
+			setup: func(s IdempotencyService) {},
+			scope: "api_key:1",
+			body:  body,
+		},
+		{
+			name: "retry of an answered request is replayed",
+			setup: func(s IdempotencyService) {
+				record, _, _ := s.Begin("api_key:1", "key", "POST", "/api/v1/payments/create", body)
+				s.Complete(record, 200, []byte(`{"status":"ok"}`))
+			},
+			scope:      "api_key:1",
+			body:       body,
+			wantReplay: true,
+		},
+		{
+			name: "retry while the request is in flight",
+			setup: func(s IdempotencyService) {
+				s.Begin("api_key:1", "key", "POST", "/api/v1/payments/create", body)
+			},
+			scope:   "api_key:1",
+			body:    body,
+			wantErr: ErrIdempotencyKeyInFlight,
+		},
+		{
+			name: "key reused with another body",
+			setup: func(s IdempotencyService) {
+				record, _, _ := s.Begin("api_key:1", "key", "POST", "/api/v1/payments/create", body)
+				s.Complete(record, 200, []byte(`{"status":"ok"}`))
+			},
+			scope:   "api_key:1",
+			body:    []byte(`{"plan_id":"plan2"}`),
+			wantErr: ErrIdempotencyKeyReused,
+		},
+		{
+			name: "same key of another caller",
+			setup: func(s IdempotencyService) {
+				record, _, _ := s.Begin("email:a@example.com", "key", "POST", "/api/v1/payments/create", body)
+				s.Complete(record, 200, []byte(`{"status":"ok"}`))
+			},
+			scope: "email:b@example.com",
+			body:  body,
+		},
+		{
+			name: "released key can be retried",
+			setup: func(s IdempotencyService) {
+				record, _, _ := s.Begin("api_key:1", "key", "POST", "/api/v1/payments/create", body)
+				s.Complete(record, 417, []byte(`{"status":"error"}`))
+			},
+			scope: "api_key:1",
+			body:  body,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewIdempotencyService(newMemoryIdempotencyRepository())
+			tt.setup(s)
+
+			record, replay, err := s.Begin(tt.scope, "key", "POST", "/api/v1/payments/create", tt.body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Begin() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if replay != tt.wantReplay {
+				t.Errorf("Begin() replay = %v, want %v", replay, tt.wantReplay)
+			}
+			if replay && (record.StatusCode != 200 || record.Response != `{"status":"ok"}`) {
+				t.Errorf("Begin() replays %d %s, want the stored response", record.StatusCode, record.Response)
+			}
+		})
+	}
+}
+
+func TestIdempotencyBeginExpired(t *testing.T) {
+	config.Cfg = &config.Config{IDEMPOTENCY_TTL_HOURS: 24}
+	repo := newMemoryIdempotencyRepository()
+	s := NewIdempotencyService(repo)
+
+	record, _, _ := s.Begin("api_key:1", "key", "POST", "/api/v1/refunds", []byte(`{}`))
+	s.Complete(record, 201, []byte(`{"status":"ok"}`))
+	stored := repo.keys[record.ID]
+	stored.ExpiresAt = time.Now().Add(-time.Minute)
+	repo.keys[record.ID] = stored
+
+	record, replay, err := s.Begin("api_key:1", "key", "POST", "/api/v1/refunds", []byte(`{"other":true}`))
+	if err != nil || replay {
+		t.Fatalf("Begin() of an expired key = replay %v, error %v, want a fresh record", replay, err)
+	}
+	if record.StatusCode != 0 {
+		t.Errorf("Begin() of an expired key returned status %d, want a fresh record", record.StatusCode)
+	}
+}
+
+func TestIdempotencyComplete(t *testing.T) {
+	config.Cfg = &config.Config{IDEMPOTENCY_TTL_HOURS: 24}
+
+	tests := []struct {
+		statusCode int
+		wantStored bool
+	}{
+		{200, true},
+		{201, true},
+		{400, true},
+		{404, true},
+		{401, false},
+		{409, false},
+		{417, false},
+		{422, false},
+		{500, false},
+		{503, false},
+	}
+	for _, tt := range tests {
+		repo := newMemoryIdempotencyRepository()
+		s := NewIdempotencyService(repo)
+
+		record, _, err := s.Begin("api_key:1", "key", "POST", "/api/v1/payments/create", []byte(`{}`))
+		if err != nil {
+			t.Fatalf("Begin() error = %v", err)
+		}
+		s.Complete(record, tt.statusCode, []byte(`{}`))
+
+		stored, ok := repo.keys[record.ID]
+		if ok != tt.wantStored {
+			t.Errorf("Complete(%d) stored = %v, want %v", tt.statusCode, ok, tt.wantStored)
+			continue
+		}
+		if ok && stored.StatusCode != tt.statusCode {
+			t.Errorf("Complete(%d) stored status %d", tt.statusCode, stored.StatusCode)
+		}
+	}
+}
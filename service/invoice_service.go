@@ -0,0 +1,286 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/internal/util"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+	"gorm.io/gorm"
+)
+
+type InvoiceService interface {
+	GetInvoices(email string) ([]model.Invoice, error)
+	RenderInvoiceHTML(id string) ([]byte, error)
+	RenderInvoicePDF(id string) ([]byte, error)
+}
+
+type invoiceService struct {
+	repo        repository.InvoiceRepository
+	paymentRepo repository.PaymentRepository
+}
+
+func NewInvoiceService(repo repository.InvoiceRepository) InvoiceService {
+	return &invoiceService{
+		repo:        repo,
+		paymentRepo: repository.NewPaymentRepository(database.DB),
+	}
+}
+
+// invoiceView is what the invoice template is rendered with.
+type invoiceView struct {
+	Invoice       model.Invoice
+	IssuedAt      string
+	Status        string
+	PaymentID     string
+	Amount        float64
+	PaidAmount    float64
+	Currency      string
+	WalletAddress string
+	CheckoutURL   string
+	PDFURL        string
+	Theme         checkoutTheme
+}
+
+func (s *invoiceService) GetInvoices(email string) ([]model.Invoice, error) {
+	return s.repo.GetInvoices(email)
+}
+
+// RenderInvoiceHTML renders the printable invoice page from INVOICE_TEMPLATE, themed like the
+// hosted checkout.
+func (s *invoiceService) RenderInvoiceHTML(id string) ([]byte, error) {
+	invoice, payment, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.ParseFiles(config.Cfg.INVOICE_TEMPLATE)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load invoice template: %w", err)
+	}
+
+	var page bytes.Buffer
+	if err := tmpl.Execute(&page, newInvoiceView(*invoice, payment)); err != nil {
+		return nil, fmt.Errorf("failed to render invoice template: %w", err)
+	}
+	return page.Bytes(), nil
+}
+
+func (s *invoiceService) RenderInvoicePDF(id string) ([]byte, error) {
+	invoice, payment, err := s.load(id)
+	if err != nil {
+		return nil, err
+	}
+	return invoicePDF(*invoice, payment)
+}
+
+func (s *invoiceService) load(id string) (*model.Invoice, model.Payment, error) {
+	invoice, err := s.repo.GetInvoiceByID(id)
+	if err != nil {
+		return nil, model.Payment{}, err
+	}
+	payment, err := s.paymentRepo.FindPaymentById(invoice.PaymentID)
+	if err != nil {
+		return nil, model.Payment{}, err
+	}
+	if payment.ID == "" {
+		return nil, model.Payment{}, fmt.Errorf("payment %s of invoice %s: %w", invoice.PaymentID, invoice.ID, gorm.ErrRecordNotFound)
+	}
+	return invoice, payment, nil
+}
+
+// buildInvoice turns the request into an invoice, its amount is the total of the line items. The
+// USD value is only known once its payment is quoted.
+func buildInvoice(req dto.CreateInvoiceRequest) (*model.Invoice, error) {
+	invoice := model.Invoice{
+		ID:          util.GenerateUniqueID(),
+		Email:       req.Email,
+		Description: req.Description,
+		Currency:    strings.ToUpper(req.Currency),
+	}
+	if invoice.Currency == "" {
+		invoice.Currency = "USD"
+	}
+
+	var total float64
+	for i, item := range req.LineItems {
+		amount := roundCents(item.Quantity * item.UnitPrice)
+		total += amount
+		invoice.LineItems = append(invoice.LineItems, model.InvoiceLineItem{
+			ID:          util.GenerateUniqueID(),
+			InvoiceID:   invoice.ID,
+			Position:    i + 1,
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			Amount:      amount,
+		})
+	}
+	total = roundCents(total)
+
+	switch {
+	case len(invoice.LineItems) == 0 && req.Amount <= 0:
+		return nil, errors.New("an invoice needs line items or an amount")
+	case len(invoice.LineItems) == 0:
+		invoice.Amount = roundCents(req.Amount)
+	case req.Amount > 0 && math.Abs(req.Amount-total) > 0.005:
+		return nil, fmt.Errorf("amount %.2f doesn't match the line items total of %.2f", req.Amount, total)
+	default:
+		invoice.Amount = total
+	}
+	if invoice.Amount < 0.01 {
+		return nil, errors.New("invoice amount must be at least 0.01")
+	}
+	return &invoice, nil
+}
+
+func newInvoiceView(invoice model.Invoice, payment model.Payment) invoiceView {
+	return invoiceView{
+		Invoice:       invoice,
+		IssuedAt:      invoice.CreatedAt.Format("January 2, 2006"),
+		Status:        invoiceStatus(payment),
+		PaymentID:     payment.ID,
+		Amount:        payment.AmountTRX,
+		PaidAmount:    payment.PaidAmountTRX,
+		Currency:      currencyLabel(payment),
+		WalletAddress: payment.Wallet.WalletAddress,
+		CheckoutURL:   "/checkout/" + payment.ID,
+		PDFURL:        invoicePath(invoice.ID) + "/pdf",
+		Theme: checkoutTheme{
+			BrandName:    config.Cfg.CHECKOUT_BRAND_NAME,
+			PrimaryColor: template.CSS(config.Cfg.CHECKOUT_PRIMARY_COLOR),
+			LogoURL:      config.Cfg.CHECKOUT_LOGO_URL,
+		},
+	}
+}
+
+// invoicePDF lays the invoice out on an A4 page with the core fonts, so no font files are needed.
+func invoicePDF(invoice model.Invoice, payment model.Payment) ([]byte, error) {
+	view := newInvoiceView(invoice, payment)
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("") // core fonts are cp1252
+	pdf.SetTitle("Invoice "+invoice.ID, true)
+	pdf.SetMargins(20, 20, 20)
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.CellFormat(100, 10, tr(view.Theme.BrandName), "", 0, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(70, 10, "INVOICE", "", 1, "R", false, 0, "")
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.Ln(4)
+	for _, row := range [][2]string{
+		{"Invoice", invoice.ID},
+		{"Issued", view.IssuedAt},
+		{"Billed to", invoice.Email},
+		{"Status", view.Status},
+	} {
+		pdf.CellFormat(30, 6, row[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(140, 6, tr(row[1]), "", 1, "L", false, 0, "")
+	}
+
+	if invoice.Description != "" {
+		pdf.Ln(4)
+		pdf.MultiCell(170, 5, tr(invoice.Description), "", "L", false)
+	}
+
+	pdf.Ln(6)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(241, 245, 249)
+	pdf.CellFormat(95, 8, "Description", "B", 0, "L", true, 0, "")
+	pdf.CellFormat(20, 8, "Qty", "B", 0, "R", true, 0, "")
+	pdf.CellFormat(27, 8, "Unit price", "B", 0, "R", true, 0, "")
+	pdf.CellFormat(28, 8, "Amount", "B", 1, "R", true, 0, "")
+
+	pdf.SetFont("Helvetica", "", 10)
+	items := invoice.LineItems
+	if len(items) == 0 {
+		description := invoice.Description
+		if description == "" {
+			description = "Invoice " + invoice.ID
+		}
+		items = []model.InvoiceLineItem{{Description: description, Quantity: 1, UnitPrice: invoice.Amount, Amount: invoice.Amount}}
+	}
+	for _, item := range items {
+		pdf.CellFormat(95, 7, tr(item.Description), "", 0, "L", false, 0, "")
+		pdf.CellFormat(20, 7, fmt.Sprintf("%g", item.Quantity), "", 0, "R", false, 0, "")
+		pdf.CellFormat(27, 7, formatFiat(invoice.Currency, item.UnitPrice), "", 0, "R", false, 0, "")
+		pdf.CellFormat(28, 7, formatFiat(invoice.Currency, item.Amount), "", 1, "R", false, 0, "")
+	}
+
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(142, 9, "Total", "T", 0, "R", false, 0, "")
+	pdf.CellFormat(28, 9, formatFiat(invoice.Currency, invoice.Amount), "T", 1, "R", false, 0, "")
+
+	pdf.Ln(8)
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(170, 6, "Payment", "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	for _, row := range [][2]string{
+		{"Payment ID", view.PaymentID},
+		{"Amount", fmt.Sprintf("%.6f %s", view.Amount, view.Currency)},
+		{"Received", fmt.Sprintf("%.6f %s", view.PaidAmount, view.Currency)},
+		{"Network", "TRON"},
+		{"Address", view.WalletAddress},
+	} {
+		pdf.CellFormat(30, 6, row[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(140, 6, tr(row[1]), "", 1, "L", false, 0, "")
+	}
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, fmt.Errorf("failed to render invoice pdf: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// invoiceStatus is the payment status as shown on the invoice.
+func invoiceStatus(payment model.Payment) string {
+	switch payment.Status {
+	case model.Completed:
+		return "Paid"
+	case model.PartiallyPaid:
+		return "Partially paid"
+	case model.Cancelled, model.Expired:
+		return "Void"
+	case model.LatePayment:
+		return "Under review"
+	default:
+		return "Due"
+	}
+}
+
+// invoicePath is where an invoice is rendered, the PDF is at the same path followed by /pdf.
+func invoicePath(id string) string {
+	return "/invoices/" + id
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// invoiceTitle names what an invoice payment is for in emails and on the checkout, where plan
+// payments show their plan.
+func invoiceTitle(invoice *model.Invoice) string {
+	if invoice == nil {
+		return ""
+	}
+	if invoice.Description != "" {
+		return strings.SplitN(invoice.Description, "\n", 2)[0]
+	}
+	if len(invoice.LineItems) > 0 {
+		return invoice.LineItems[0].Description
+	}
+	return "Invoice " + invoice.ID
+}
@@ -0,0 +1,272 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/internal/tron"
+	"github.com/thebytearray/BytePayments/internal/util"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+	"gorm.io/gorm"
+)
+
+// Outcomes an admin can pick for an orphaned deposit.
+const (
+	OrphanActionAttach = "attach" // credit it to a payment made to the same wallet
+	OrphanActionRefund = "refund" // send it back from the deposit wallet
+	OrphanActionSweep  = "sweep"  // sweep it as unallocated funds
+)
+
+type OrphanService interface {
+	ScanOrphanedDeposits()
+	GetOrphans(status string) ([]model.OrphanDeposit, error)
+	ResolveOrphan(id string, req dto.ResolveOrphanRequest) (*model.OrphanDeposit, error)
+}
+
+type orphanService struct {
+	repo           repository.OrphanRepository
+	paymentRepo    repository.PaymentRepository
+	depositRepo    repository.DepositRepository
+	currencyRepo   repository.CurrenciesRepository
+	sweepService   SweepService
+	webhookService WebhookService
+}
+
+func NewOrphanService(repo repository.OrphanRepository) OrphanService {
+	return &orphanService{
+		repo:           repo,
+		paymentRepo:    repository.NewPaymentRepository(database.DB),
+		depositRepo:    repository.NewDepositRepository(database.DB),
+		currencyRepo:   repository.NewCurrenciesRepository(database.DB),
+		sweepService:   NewSweepService(repository.NewSweepRepository(database.DB)),
+		webhookService: NewWebhookService(repository.NewWebhookRepository(database.DB)),
+	}
+}
+
+// ScanOrphanedDeposits looks for funds on the wallets no payment is waiting on and records the
+// transfers that arrived after the wallet's last payment without being credited to it.
+func (s *orphanService) ScanOrphanedDeposits() {
+	lateSince := time.Now().Add(-time.Duration(config.Cfg.LATE_PAYMENT_WINDOW_HOURS) * time.Hour)
+	payments, err := s.paymentRepo.FindIdleWalletPayments(lateSince)
+	if err != nil {
+		log.Println("Error fetching idle wallets : ", err)
+		return
+	}
+
+	currencies, err := s.currencyRepo.GetCurrencies()
+	if err != nil {
+		log.Println("Error fetching currencies : ", err)
+		return
+	}
+
+	for _, payment := range payments {
+		for _, currency := range currencies {
+			if currency.IsToken && currency.ContractAddr == "" {
+				continue
+			}
+			if err := s.scanWallet(payment, currency); err != nil {
+				log.Printf("Failed to scan wallet %s for orphaned %s: %v", payment.Wallet.WalletAddress, currency.Code, err)
+			}
+		}
+	}
+}
+
+// scanWallet records the orphaned transfers of currency into the wallet of last, its latest payment.
+func (s *orphanService) scanWallet(last model.Payment, currency model.Currency) error {
+	wallet := last.Wallet
+
+	// only wallets still holding funds are worth listing the transfers of
+	balance, err := walletBalance(model.Payment{Wallet: wallet, Currency: currency})
+	if err != nil {
+		return fmt.Errorf("failed to check balance: %w", err)
+	}
+	if balance <= 0 {
+		return nil
+	}
+
+	var transfers []tron.IncomingTransfer
+	if currency.IsToken {
+		transfers, err = tron.ListIncomingTRC20Transfers(tron.TRON_CLIENT, wallet.WalletAddress, currency.ContractAddr, last.CreatedAt)
+	} else {
+		transfers, err = tron.ListIncomingTRXTransfers(wallet.WalletAddress, last.CreatedAt)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to list incoming transfers: %w", err)
+	}
+
+	for _, transfer := range transfers {
+		// TRX the hot wallet sent to pay for a token sweep
+		if transfer.From == config.Cfg.TRX_HOT_WALLET_ADDRESS {
+			continue
+		}
+
+		credited, err := s.depositRepo.ExistsByTxHash(transfer.TxHash)
+		if err != nil {
+			return fmt.Errorf("failed to check deposit: %w", err)
+		}
+		known, err := s.repo.ExistsByTxHash(transfer.TxHash)
+		if err != nil {
+			return fmt.Errorf("failed to check orphaned deposit: %w", err)
+		}
+		if credited || known {
+			continue
+		}
+
+		// a payment opened on the wallet since the scan started gets the transfer instead
+		open, err := s.paymentRepo.HasOpenPaymentByWalletId(wallet.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check open payments: %w", err)
+		}
+		if open {
+			return nil
+		}
+
+		orphan := model.OrphanDeposit{
+			ID:             util.GenerateUniqueID(),
+			WalletID:       wallet.ID,
+			WalletAddress:  wallet.WalletAddress,
+			CurrencyCode:   currency.Code,
+			TxHash:         transfer.TxHash,
+			FromAddress:    transfer.From,
+			Amount:         transfer.Amount,
+			BlockNumber:    transfer.BlockNumber,
+			BlockTimestamp: transfer.Timestamp,
+			LastPaymentID:  last.ID,
+			Status:         model.OrphanOpen,
+		}
+		if err := s.repo.CreateOrphan(&orphan); err != nil {
+			return fmt.Errorf("failed to record orphaned deposit: %w", err)
+		}
+		log.Printf("Recorded orphaned deposit %s of %.6f %s into %s", transfer.TxHash, transfer.Amount, currency.Code, wallet.WalletAddress)
+	}
+	return nil
+}
+
+func (s *orphanService) GetOrphans(status string) ([]model.OrphanDeposit, error) {
+	return s.repo.GetOrphans(status)
+}
+
+func (s *orphanService) ResolveOrphan(id string, req dto.ResolveOrphanRequest) (*model.OrphanDeposit, error) {
+	orphan, err := s.repo.GetOrphanByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if orphan.Status != model.OrphanOpen {
+		return nil, fmt.Errorf("orphaned deposit is already %s", orphan.Status)
+	}
+
+	switch req.Action {
+	case OrphanActionAttach:
+		err = s.attach(orphan, req.PaymentID)
+	case OrphanActionRefund:
+		err = s.send(orphan, req.RefundAddress)
+	case OrphanActionSweep:
+		err = s.send(orphan, "")
+	default:
+		err = fmt.Errorf("unknown action %q", req.Action)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return orphan, nil
+}
+
+// attach credits the orphan to a payment made to the same wallet in the same currency. Expired
+// payments go to late review with it, like a late deposit found by the payment job.
+func (s *orphanService) attach(orphan *model.OrphanDeposit, paymentID string) error {
+	payment, err := s.paymentRepo.FindPaymentById(paymentID)
+	if err != nil {
+		return err
+	}
+	if payment.ID == "" {
+		return fmt.Errorf("payment %s: %w", paymentID, gorm.ErrRecordNotFound)
+	}
+	if payment.WalletID != orphan.WalletID || payment.CurrencyCode != orphan.CurrencyCode {
+		return fmt.Errorf("payment %s wasn't made to %s in %s", payment.ID, orphan.WalletAddress, orphan.CurrencyCode)
+	}
+	switch payment.Status {
+	case model.Pending, model.PartiallyPaid, model.Expired, model.LatePayment:
+	default:
+		return fmt.Errorf("payment is %s, only open, expired and late payments can take a deposit", payment.Status)
+	}
+
+	deposit := model.Deposit{
+		ID:             util.GenerateUniqueID(),
+		PaymentID:      payment.ID,
+		WalletAddress:  orphan.WalletAddress,
+		CurrencyCode:   orphan.CurrencyCode,
+		TxHash:         orphan.TxHash,
+		FromAddress:    orphan.FromAddress,
+		Amount:         orphan.Amount,
+		BlockNumber:    orphan.BlockNumber,
+		BlockTimestamp: orphan.BlockTimestamp,
+		Status:         model.DepositDetected,
+	}
+	if err := s.depositRepo.CreateDeposit(&deposit); err != nil {
+		return fmt.Errorf("failed to record deposit: %w", err)
+	}
+
+	orphan.Status = model.OrphanAttached
+	orphan.PaymentID = payment.ID
+	if err := s.repo.UpdateOrphan(orphan); err != nil {
+		return err
+	}
+	log.Printf("Orphaned deposit %s attached to payment %s", orphan.TxHash, payment.ID)
+
+	// open payments pick the deposit up on the next payment run
+	if payment.Status == model.Pending || payment.Status == model.PartiallyPaid {
+		return nil
+	}
+
+	deposits, err := s.depositRepo.FindDepositsByPaymentId(payment.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch deposits: %w", err)
+	}
+	var paid float64
+	for _, d := range deposits {
+		paid += d.Amount
+	}
+
+	if payment.Status == model.LatePayment {
+		return s.paymentRepo.UpdatePaidAmountById(payment.ID, paid)
+	}
+
+	if err := s.paymentRepo.MarkAsLateById(payment.ID, paid); err != nil {
+		return fmt.Errorf("failed to mark payment as late: %w", err)
+	}
+	payment.Status = model.LatePayment
+	payment.PaidAmountTRX = paid
+	s.webhookService.Dispatch(model.PaymentLateEvent, payment)
+	return nil
+}
+
+// send refunds the orphan to refundTo, or sweeps it as unallocated when refundTo is empty.
+func (s *orphanService) send(orphan *model.OrphanDeposit, refundTo string) error {
+	status := model.OrphanSwept
+	if refundTo != "" {
+		if err := tron.ValidateAddress(refundTo); err != nil {
+			return err
+		}
+		status = model.OrphanRefunded
+	}
+
+	batchID, err := s.sweepService.SweepOrphan(*orphan, refundTo)
+	if err != nil {
+		return err
+	}
+
+	orphan.Status = status
+	orphan.RefundAddress = refundTo
+	orphan.SweepBatchID = batchID
+	orphan.SweepStatus = model.PaymentSweeping
+	if err := s.repo.UpdateOrphan(orphan); err != nil {
+		return fmt.Errorf("orphaned deposit sent in sweep batch %s but not updated: %w", batchID, err)
+	}
+	log.Printf("Orphaned deposit %s %s in sweep batch %s", orphan.TxHash, status, batchID)
+	return nil
+}
@@ -1,14 +1,22 @@
 package service
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"math"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/thebytearray/BytePayments/config"
 	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/internal/oracle"
+	"github.com/thebytearray/BytePayments/internal/pubsub"
 	"github.com/thebytearray/BytePayments/internal/tron"
 	"github.com/thebytearray/BytePayments/internal/util"
 	"github.com/thebytearray/BytePayments/model"
@@ -16,19 +24,53 @@ import (
 	"gorm.io/gorm"
 )
 
+// maxMetadataBytes bounds the merchant metadata stored on a payment.
+const maxMetadataBytes = 4096
+
+// Re-quote policies, set with REQUOTE_POLICY.
+const (
+	RequotePolicyRequote = "requote" // give unpaid payments a fresh quote when their lock ends
+	RequotePolicyExpire  = "expire"  // expire unpaid payments when their lock ends
+)
+
 type PaymentService interface {
-	CreatePayment(body dto.CreatePaymentRequest) (dto.PaymentResponse, error)
+	CreatePayment(body dto.CreatePaymentRequest, apiKey *model.APIKey) (dto.PaymentResponse, error)
+	CreateInvoice(req dto.CreateInvoiceRequest, apiKey *model.APIKey) (dto.InvoiceResponse, error)
 	CancelPaymentById(id string) dto.ApiResponse
 	CheckPaymentStatusById(id string) dto.ApiResponse
+	GetPaymentEvent(id string) (dto.PaymentEvent, error)
+	GetMerchantPayments(apiKey *model.APIKey, externalReference string) ([]model.Payment, error)
 	ProcessPendingPayments()
+	ProcessLatePayments()
+	GetLatePayments() ([]model.Payment, error)
+	CreateRenewalPayment(subscription model.Subscription, currencyCode, fiatCurrency string) (model.Payment, error)
+	ResolveLatePayment(id string, accept bool) (model.Payment, error)
 }
 
 type paymentService struct {
-	repo repository.PaymentRepository
+	repo                repository.PaymentRepository
+	depositRepo         repository.DepositRepository
+	orphanRepo          repository.OrphanRepository
+	webhookService      WebhookService
+	refundService       RefundService
+	subscriptionService SubscriptionService
+	couponService       CouponService
+	oracle              oracle.PriceOracle
+	fx                  oracle.PriceOracle
 }
 
 func NewPaymentService(repo repository.PaymentRepository) PaymentService {
-	return &paymentService{repo}
+	return &paymentService{
+		repo:                repo,
+		depositRepo:         repository.NewDepositRepository(database.DB),
+		orphanRepo:          repository.NewOrphanRepository(database.DB),
+		webhookService:      NewWebhookService(repository.NewWebhookRepository(database.DB)),
+		refundService:       NewRefundService(repository.NewRefundRepository(database.DB)),
+		subscriptionService: NewSubscriptionService(repository.NewSubscriptionRepository(database.DB)),
+		couponService:       NewCouponService(repository.NewCouponRepository(database.DB)),
+		oracle:              oracle.NewPriceOracle(),
+		fx:                  oracle.NewFXOracle(),
+	}
 }
 
 func (s *paymentService) ProcessPendingPayments() {
@@ -43,43 +85,31 @@ func (s *paymentService) ProcessPendingPayments() {
 	emailService := NewEmailService()
 
 	for _, p := range payments {
-		//check for expiry
-		if time.Since(p.CreatedAt) > 15*time.Minute {
-			err := s.repo.MarkAsExpiredById(p.ID)
-			if err != nil {
-				log.Println("failed to mark payment as expired", err)
-			}
-			continue
-		}
-
-		//check actual balance?
-		balance, err := tron.CheckBalance(tron.TRON_CLIENT, p.Wallet.WalletAddress)
+		//credit the transfers made to the wallet since the payment was created
+		paid, confirmed, err := s.creditDeposits(p)
 
 		if err != nil {
-			log.Println("Error fetching balance : ", err)
+			log.Println("Error fetching deposits : ", err)
 			continue
 		}
 
 		//parse
-		diff := balance - p.AmountTRX
-		absDiff := math.Abs(diff)
-		receivedRatio := balance / p.AmountTRX
+		sufficient := func(amount float64) bool {
+			return amount/p.AmountTRX >= completionThreshold || math.Abs(amount-p.AmountTRX) <= tolerance
+		}
+		receivedRatio := paid / p.AmountTRX
 
-		if receivedRatio >= completionThreshold || absDiff <= tolerance {
-			// Payment amount is sufficient, but don't mark as completed yet
-			log.Printf("Payment %s has sufficient funds: received %.2f TRX (expected %.2f)", p.ID, balance, p.AmountTRX)
-			
-			// Try to sweep funds first
-			err = s.sweepFunds(p)
-			if err != nil {
-				log.Printf("Failed to sweep funds for payment %s: %v", p.ID, err)
-				// Don't mark as completed, will retry on next cron run
-				continue
-			}
-			
-			// Sweeping successful, now mark as completed
+		if sufficient(paid) && !sufficient(confirmed) {
+			// the money is there but the deposits aren't deep enough in the chain yet
+			log.Printf("Payment %s waiting for confirmations: confirmed %.2f of %.2f %s received", p.ID, confirmed, paid, p.CurrencyCode)
+		} else if sufficient(confirmed) {
+			paid = confirmed
+			log.Printf("Payment %s has sufficient funds: received %.2f %s (expected %.2f)", p.ID, paid, p.CurrencyCode, p.AmountTRX)
+
+			// the payment is paid once its deposits are confirmed, the funds are swept
+			// separately by the sweep job so a sweep problem never holds up the customer
 			now := time.Now()
-			err = s.repo.MarkAsCompletedById(p.ID, balance, &now)
+			err = s.repo.MarkAsCompletedById(p.ID, paid, &now)
 			if err != nil {
 				log.Printf("Failed to mark completed for payment %s: %v", p.ID, err)
 				continue
@@ -87,16 +117,19 @@ func (s *paymentService) ProcessPendingPayments() {
 
 			// Update the payment object with the paid amount for email templates
 			p.Status = model.Completed
-			p.PaidAmountTRX = balance
+			p.SweepStatus = model.PaymentUnswept
+			p.PaidAmountTRX = paid
 			p.UpdatedAt = now
 
-			log.Printf("Payment %s completed and funds swept successfully", p.ID)
+			log.Printf("Payment %s completed", p.ID)
+			s.webhookService.Dispatch(model.PaymentCompletedEvent, p)
+			s.activateSubscription(p)
 			
 			// Determine payment condition and send appropriate email
-			if balance > p.AmountTRX+tolerance {
+			if paid > p.AmountTRX+tolerance {
 				// Overpaid
-				overpaidAmount := balance - p.AmountTRX
-				log.Printf("Payment %s overpaid by %.2f TRX", p.ID, overpaidAmount)
+				overpaidAmount := paid - p.AmountTRX
+				log.Printf("Payment %s overpaid by %.2f %s", p.ID, overpaidAmount, p.CurrencyCode)
 				
 				err = emailService.SendOverpaymentEmail(p, p.Plan, overpaidAmount)
 				if err != nil {
@@ -104,6 +137,10 @@ func (s *paymentService) ProcessPendingPayments() {
 				} else {
 					log.Printf("Overpayment email sent for payment %s", p.ID)
 				}
+
+				if _, err := s.refundService.CreateRefund(p.ID, overpaidAmount, model.RefundOverpayment); err != nil {
+					log.Printf("Failed to open overpayment refund for payment %s: %v", p.ID, err)
+				}
 			} else {
 				// Exact or close enough payment
 				err = emailService.SendPaymentCompletionEmail(p, p.Plan)
@@ -113,65 +150,580 @@ func (s *paymentService) ProcessPendingPayments() {
 					log.Printf("Completion email sent for payment %s", p.ID)
 				}
 			}
-		} else if balance > 0 && balance < p.AmountTRX-tolerance {
-			// Underpaid - check if we haven't already sent an email recently
-			remainingAmount := p.AmountTRX - balance
-			log.Printf("Payment %s underpaid: received %.2f TRX, remaining %.2f TRX", p.ID, balance, remainingAmount)
-			
-			// Only send underpayment email if we have received some payment and haven't sent one recently
-			// You might want to add a field to track when the last email was sent to avoid spam
-			if balance > 0.001 { // Only if they've paid something significant
-				// Create a temporary payment object with the current balance for email
-				tempPayment := p
-				tempPayment.PaidAmountTRX = balance
-				
-				err = emailService.SendUnderpaymentEmail(tempPayment, p.Plan, remainingAmount)
-				if err != nil {
-					log.Printf("Failed to send underpayment email for payment %s: %v", p.ID, err)
-				} else {
-					log.Printf("Underpayment email sent for payment %s", p.ID)
-				}
-			}
+		} else if paymentExpired(p) {
+			s.expirePayment(p)
+		} else if paid == 0 && quoteExpired(p) {
+			// the rate is no longer honoured and nothing was paid at it, partially paid
+			// payments keep their quote until the payment window ends
+			s.handleExpiredQuote(p)
+		} else if paid > 0 && paid < p.AmountTRX-tolerance {
+			s.handlePartialPayment(p, paid)
 		} else {
-			log.Printf("Payment %s still pending: received %.2f TRX (%.2f%% of expected)", p.ID, balance, receivedRatio*100)
+			log.Printf("Payment %s still pending: received %.2f %s (%.2f%% of expected)", p.ID, paid, p.CurrencyCode, receivedRatio*100)
+		}
+
+		s.publishStatus(p.ID)
+	}
+
+}
+
+// ProcessLatePayments watches the wallets of recently expired payments. A transfer arriving
+// after the payment window isn't ignored, the payment is held as late_payment for review.
+func (s *paymentService) ProcessLatePayments() {
+	since := time.Now().Add(-time.Duration(config.Cfg.LATE_PAYMENT_WINDOW_HOURS) * time.Hour)
+	payments, err := s.repo.FindLatePaymentCandidates(since)
+	if err != nil {
+		log.Println("Error fetching expired payments : ", err)
+		return
+	}
+
+	for _, p := range payments {
+		credited, err := s.recordTransfers(p)
+		if err != nil {
+			log.Println("Error fetching deposits : ", err)
+			continue
+		}
+		if credited == 0 {
+			continue
 		}
 
+		paid, _, err := s.depositTotals(p)
+		if err != nil {
+			log.Println("Error fetching deposits : ", err)
+			continue
+		}
+
+		if err := s.repo.MarkAsLateById(p.ID, paid); err != nil {
+			log.Printf("Failed to mark payment %s as late: %v", p.ID, err)
+			continue
+		}
+		p.Status = model.LatePayment
+		p.PaidAmountTRX = paid
+
+		log.Printf("Payment %s received %d deposit(s) after it expired, holding it for review", p.ID, credited)
+		s.webhookService.Dispatch(model.PaymentLateEvent, p)
+		s.publishStatus(p.ID)
 	}
+}
+
+// GetMerchantPayments lists the payments created with apiKey only, a merchant never sees the
+// payments of customers or of other keys. externalReference picks the payment of an order.
+func (s *paymentService) GetMerchantPayments(apiKey *model.APIKey, externalReference string) ([]model.Payment, error) {
+	return s.repo.GetPaymentsByAPIKeyId(apiKey.ID, externalReference)
+}
 
+func (s *paymentService) GetLatePayments() ([]model.Payment, error) {
+	return s.repo.FindPaymentsByStatus(model.LatePayment)
 }
 
-func (s *paymentService) sweepFunds(payment model.Payment) error {
-	// 1. Check wallet balance
-	balance, err := tron.CheckBalance(tron.TRON_CLIENT, payment.Wallet.WalletAddress)
+// ResolveLatePayment settles a payment under late review. Accepting it completes the payment
+// with its confirmed deposits, which are then swept as usual. Rejecting it expires it again and
+// leaves the funds in the deposit wallet.
+func (s *paymentService) ResolveLatePayment(id string, accept bool) (model.Payment, error) {
+	payment, err := s.repo.FindPaymentById(id)
 	if err != nil {
-		return fmt.Errorf("failed to check balance: %w", err)
+		return model.Payment{}, err
+	}
+	if payment.ID == "" {
+		return model.Payment{}, gorm.ErrRecordNotFound
+	}
+	if payment.Status != model.LatePayment {
+		return model.Payment{}, fmt.Errorf("payment is %s, only late payments can be resolved", payment.Status)
 	}
 
-	transferable, err := tron.GetTransferableAmount(payment.Wallet.WalletAddress, balance)
+	if !accept {
+		if err := s.repo.MarkAsExpiredById(payment.ID); err != nil {
+			return model.Payment{}, err
+		}
+		payment.Status = model.Expired
+		log.Printf("Late payment %s rejected", payment.ID)
+		s.publishStatus(payment.ID)
+		return payment, nil
+	}
+
+	paid, confirmed, err := s.depositTotals(payment)
 	if err != nil {
-		return fmt.Errorf("failed to calculate transferable amount: %w", err)
+		return model.Payment{}, err
+	}
+	if confirmed < paid {
+		return model.Payment{}, fmt.Errorf("only %.6f of %.6f %s received is confirmed, wait for the confirmations", confirmed, paid, payment.CurrencyCode)
 	}
 
-	if transferable <= 0 {
-		return fmt.Errorf("no transferable amount available")
+	now := time.Now()
+	if err := s.repo.MarkAsCompletedById(payment.ID, confirmed, &now); err != nil {
+		return model.Payment{}, err
 	}
+	payment.Status = model.Completed
+	payment.SweepStatus = model.PaymentUnswept
+	payment.PaidAmountTRX = confirmed
+	payment.UpdatedAt = now
 
-	mainWalletAddr := config.Cfg.TRX_HOT_WALLET_ADDRESS
-	paymentWalletPrivKey, err := util.AesDecryptPK(payment.Wallet.WalletSecret)
+	log.Printf("Late payment %s accepted with %.6f %s", payment.ID, confirmed, payment.CurrencyCode)
+	s.webhookService.Dispatch(model.PaymentCompletedEvent, payment)
+	s.activateSubscription(payment)
+	s.publishStatus(payment.ID)
+
+	if err := NewEmailService().SendPaymentCompletionEmail(payment, payment.Plan); err != nil {
+		log.Printf("Failed to send completion email for payment %s: %v", payment.ID, err)
+	}
+	return payment, nil
+}
+
+// handlePartialPayment moves an underpaid payment to partially_paid, giving it
+// PARTIAL_PAYMENT_GRACE_MINUTES from its first deposit for the rest. The customer is emailed once
+// for every deposit that still leaves the payment short.
+func (s *paymentService) handlePartialPayment(p model.Payment, paid float64) {
+	remaining := math.Round((p.AmountTRX-paid)*1e6) / 1e6
+
+	expiresAt := p.ExpiresAt
+	if p.Status == model.Pending {
+		grace := time.Now().Add(time.Duration(config.Cfg.PARTIAL_PAYMENT_GRACE_MINUTES) * time.Minute)
+		if expiresAt == nil || expiresAt.Before(grace) {
+			expiresAt = &grace
+		}
+	}
+
+	if err := s.repo.MarkAsPartiallyPaidById(p.ID, paid, remaining, expiresAt); err != nil {
+		log.Printf("Failed to mark payment %s as partially paid: %v", p.ID, err)
+		return
+	}
+	if p.Status == model.Pending {
+		log.Printf("Payment %s partially paid, open until %s for the rest", p.ID, expiresAt.Format("2006-01-02T15:04:05Z07:00"))
+	}
+	p.Status = model.PartiallyPaid
+	p.PaidAmountTRX = paid
+	p.RemainingAmount = remaining
+	p.ExpiresAt = expiresAt
 
+	deposits, err := s.depositRepo.FindDepositsByPaymentId(p.ID)
 	if err != nil {
-		return fmt.Errorf("failed to decrypt wallet key: %w", err)
+		log.Printf("Failed to fetch deposits for payment %s: %v", p.ID, err)
+		return
+	}
+	var unnotified []string
+	for _, deposit := range deposits {
+		if deposit.UnderpaymentNotifiedAt == nil {
+			unnotified = append(unnotified, deposit.ID)
+		}
+	}
+	if len(unnotified) == 0 {
+		log.Printf("Payment %s partially paid: received %.6f %s, remaining %.6f %s", p.ID, paid, p.CurrencyCode, remaining, p.CurrencyCode)
+		return
+	}
+
+	log.Printf("Payment %s underpaid: received %.6f %s, remaining %.6f %s", p.ID, paid, p.CurrencyCode, remaining, p.CurrencyCode)
+	s.webhookService.Dispatch(model.PaymentUnderpaidEvent, p)
+
+	if err := NewEmailService().SendUnderpaymentEmail(p, p.Plan, remaining); err != nil {
+		log.Printf("Failed to send underpayment email for payment %s: %v", p.ID, err)
+	} else {
+		log.Printf("Underpayment email sent for payment %s", p.ID)
+	}
+
+	// marked even when the email failed, a broken mail server must not turn into one email per run
+	if err := s.depositRepo.MarkUnderpaymentNotified(unnotified, time.Now()); err != nil {
+		log.Printf("Failed to record underpayment notification for payment %s: %v", p.ID, err)
 	}
+}
 
-	txID, err := tron.SendTRX(tron.TRON_CLIENT, payment.Wallet.WalletAddress, mainWalletAddr, transferable, paymentWalletPrivKey)
+// activateSubscription grants the customer the plan the completed payment paid for.
+func (s *paymentService) activateSubscription(p model.Payment) {
+	if p.PlanID == "" {
+		return // invoices don't grant a plan
+	}
+	if _, err := s.subscriptionService.ActivateFromPayment(p); err != nil {
+		log.Printf("Failed to activate subscription for payment %s: %v", p.ID, err)
+	}
+}
+
+func (s *paymentService) expirePayment(p model.Payment) {
+	if err := s.repo.MarkAsExpiredById(p.ID); err != nil {
+		log.Println("failed to mark payment as expired", err)
+		return
+	}
+	p.Status = model.Expired
+	s.webhookService.Dispatch(model.PaymentExpiredEvent, p)
+}
+
+// creditDeposits records the transfers into the payment's wallet and returns the total credited
+// to it along with the part that has reached the currency's confirmation depth.
+func (s *paymentService) creditDeposits(payment model.Payment) (float64, float64, error) {
+	if _, err := s.recordTransfers(payment); err != nil {
+		return 0, 0, err
+	}
+	return s.depositTotals(payment)
+}
+
+// recordTransfers records the transfers into the payment's wallet made after the payment was
+// created and returns how many were new.
+func (s *paymentService) recordTransfers(payment model.Payment) (int, error) {
+	var transfers []tron.IncomingTransfer
+	var err error
+	if payment.Currency.IsToken {
+		transfers, err = tron.ListIncomingTRC20Transfers(tron.TRON_CLIENT, payment.Wallet.WalletAddress, payment.Currency.ContractAddr, payment.CreatedAt)
+	} else {
+		transfers, err = tron.ListIncomingTRXTransfers(payment.Wallet.WalletAddress, payment.CreatedAt)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to send trx: %w", err)
+		return 0, fmt.Errorf("failed to list incoming transfers: %w", err)
 	}
+	return s.creditTransfers(payment, transfers)
+}
 
-	log.Printf("Swept %.2f TRX from %s to main wallet. TxID: %s", transferable, payment.Wallet.WalletAddress, txID)
+// creditTransfers credits transfers to the payment as deposits and returns how many were new. A
+// transfer is only ever credited once, so dust and earlier deposits on a reused wallet never count
+// towards a new payment.
+func (s *paymentService) creditTransfers(payment model.Payment, transfers []tron.IncomingTransfer) (int, error) {
+	var recorded int
+
+	for _, transfer := range transfers {
+		if transfer.Timestamp.Before(payment.CreatedAt) {
+			continue
+		}
+		// TRX the hot wallet sent to pay for a token sweep
+		if transfer.From == config.Cfg.TRX_HOT_WALLET_ADDRESS {
+			continue
+		}
+
+		credited, err := s.depositRepo.ExistsByTxHash(transfer.TxHash)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check deposit: %w", err)
+		}
+		// an orphaned transfer is only credited when an admin attaches it
+		orphaned, err := s.orphanRepo.ExistsByTxHash(transfer.TxHash)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check orphaned deposit: %w", err)
+		}
+		if credited || orphaned {
+			continue
+		}
+
+		deposit := model.Deposit{
+			ID:             util.GenerateUniqueID(),
+			PaymentID:      payment.ID,
+			WalletAddress:  payment.Wallet.WalletAddress,
+			CurrencyCode:   payment.CurrencyCode,
+			TxHash:         transfer.TxHash,
+			FromAddress:    transfer.From,
+			Amount:         transfer.Amount,
+			BlockNumber:    transfer.BlockNumber,
+			BlockTimestamp: transfer.Timestamp,
+			Status:         model.DepositDetected,
+		}
+		if err := s.depositRepo.CreateDeposit(&deposit); err != nil {
+			return 0, fmt.Errorf("failed to record deposit: %w", err)
+		}
+		recorded++
+		log.Printf("Credited deposit %s of %.6f %s to payment %s", transfer.TxHash, transfer.Amount, payment.CurrencyCode, payment.ID)
+	}
+	return recorded, nil
+}
+
+// depositTotals returns the total credited to the payment and the part of it that has reached
+// the currency's confirmation depth, updating the confirmations of its deposits on the way.
+func (s *paymentService) depositTotals(payment model.Payment) (float64, float64, error) {
+
+	deposits, err := s.depositRepo.FindDepositsByPaymentId(payment.ID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to fetch deposits: %w", err)
+	}
+
+	if err := s.updateConfirmations(deposits, payment.Currency.Confirmations); err != nil {
+		return 0, 0, err
+	}
+
+	total, confirmed := sumDeposits(deposits)
+	return total, confirmed, nil
+}
+
+// sumDeposits returns the total of deposits and the part of it that is confirmed.
+func sumDeposits(deposits []model.Deposit) (float64, float64) {
+	var total, confirmed float64
+	for _, deposit := range deposits {
+		total += deposit.Amount
+		if deposit.Status == model.DepositConfirmed {
+			confirmed += deposit.Amount
+		}
+	}
+	return total, confirmed
+}
+
+// updateConfirmations moves deposits through detected -> confirming -> confirmed as blocks
+// are added on top of them. Confirmed deposits are final and aren't looked at again.
+func (s *paymentService) updateConfirmations(deposits []model.Deposit, required int64) error {
+	var currentBlock int64
+	for i := range deposits {
+		deposit := &deposits[i]
+		if deposit.Status == model.DepositConfirmed {
+			continue
+		}
+
+		if currentBlock == 0 {
+			var err error
+			currentBlock, err = tron.GetCurrentBlockNumber(tron.TRON_CLIENT)
+			if err != nil {
+				return err
+			}
+		}
+
+		if !confirmDeposit(deposit, currentBlock, required) {
+			continue
+		}
+		if err := s.depositRepo.UpdateDeposit(deposit); err != nil {
+			return fmt.Errorf("failed to update deposit confirmations: %w", err)
+		}
+	}
 	return nil
 }
 
+// confirmDeposit counts the confirmations of deposit at currentBlock and moves it to the matching
+// status. It reports whether anything changed.
+func confirmDeposit(deposit *model.Deposit, currentBlock, required int64) bool {
+	confirmations := tron.Confirmations(deposit.BlockNumber, currentBlock)
+	status := model.DepositDetected
+	switch {
+	case confirmations >= required:
+		status = model.DepositConfirmed
+	case confirmations > 0:
+		status = model.DepositConfirming
+	}
+
+	if confirmations == deposit.Confirmations && status == deposit.Status {
+		return false
+	}
+
+	deposit.Confirmations = confirmations
+	deposit.Status = status
+	return true
+}
+
+// handleExpiredQuote runs once the quote lock of an unpaid payment has ended. The payment gets a
+// fresh quote under REQUOTE_POLICY, otherwise it expires. A payment is never left open on a stale
+// rate, so it also expires when the oracle can't re-quote it.
+func (s *paymentService) handleExpiredQuote(p model.Payment) {
+	// renewal invoices stay open until the subscription ends, whatever the policy
+	renewal := p.SubscriptionID != ""
+	if renewal || (config.Cfg.REQUOTE_POLICY == RequotePolicyRequote && p.RequoteCount < config.Cfg.MAX_REQUOTES) {
+		quote, err := s.requote(&p)
+		if err == nil {
+			if err := s.repo.UpdateQuote(p); err != nil {
+				log.Printf("Failed to re-quote payment %s: %v", p.ID, err)
+				return
+			}
+			log.Printf("Payment %s re-quoted to %.6f %s at %g USD (%s)", p.ID, p.AmountTRX, p.CurrencyCode, quote.PriceUSD, quote.Source)
+			s.webhookService.Dispatch(model.PaymentRequotedEvent, p)
+			return
+		}
+		if renewal {
+			log.Printf("Failed to re-quote renewal payment %s, retrying on the next run: %v", p.ID, err)
+			return
+		}
+		log.Printf("Failed to re-quote payment %s, expiring it: %v", p.ID, err)
+	}
+
+	s.expirePayment(p)
+}
+
+// requote prices the payment again at the current FX and crypto rates, its fiat price stays.
+func (s *paymentService) requote(p *model.Payment) (oracle.Quote, error) {
+	fiatCurrency, fiatAmount := paymentFiat(*p)
+	amountUSD, fxRate, err := s.toUSD(fiatCurrency, fiatAmount)
+	if err != nil {
+		return oracle.Quote{}, err
+	}
+	amount, quote, err := s.quoteAmount(amountUSD, p.Currency)
+	if err != nil {
+		return oracle.Quote{}, err
+	}
+
+	p.RequoteCount++
+	p.AmountUSD = amountUSD
+	p.FXRate = fxRate
+	applyQuote(p, amount, quote)
+	return quote, nil
+}
+
+// toUSD converts amount of the fiat currency to USD at the current FX rate and returns the rate.
+func (s *paymentService) toUSD(fiat string, amount float64) (float64, float64, error) {
+	if fiat == "" || fiat == "USD" {
+		return amount, 1, nil
+	}
+	quote, err := s.fx.Price(fiat)
+	if err != nil {
+		return 0, 0, fmt.Errorf("no %s exchange rate : %w", fiat, err)
+	}
+	return amount * quote.PriceUSD, quote.PriceUSD, nil
+}
+
+// planPrice returns the price of plan in fiat, the plan's own currency when fiat is empty. Without
+// a fixed price in fiat, the plan's own price is converted through USD at the current FX rates.
+func (s *paymentService) planPrice(plan model.Plan, fiat string) (string, float64, error) {
+	base := planCurrency(plan)
+	if fiat == "" {
+		fiat = base
+	}
+	fiat = strings.ToUpper(fiat)
+
+	if amount, ok := fixedPlanPrice(plan, fiat); ok {
+		return fiat, amount, nil
+	}
+	amount, ok := fixedPlanPrice(plan, base)
+	if !ok {
+		return "", 0, fmt.Errorf("plan %s has no %s price", plan.ID, base)
+	}
+
+	converted, err := s.convertFiat(amount, base, fiat)
+	if err != nil {
+		return "", 0, err
+	}
+	return fiat, converted, nil
+}
+
+// convertFiat converts amount from one fiat currency to another through USD, rounded to cents.
+func (s *paymentService) convertFiat(amount float64, from, to string) (float64, error) {
+	if from == to {
+		return amount, nil
+	}
+	usdAmount, _, err := s.toUSD(from, amount)
+	if err != nil {
+		return 0, err
+	}
+	_, fxRate, err := s.toUSD(to, 1)
+	if err != nil {
+		return 0, err
+	}
+	return roundCents(usdAmount / fxRate), nil
+}
+
+// paymentPrice is the fiat price a payment is opened for, after the coupon if one was redeemed.
+type paymentPrice struct {
+	Currency string
+	Amount   float64
+	Coupon   *model.Coupon
+	Discount float64 // taken off the price in Currency, Amount is what's left
+}
+
+// couponDiscount is what coupon takes off amount of fiat. A discount may not cover the whole
+// price, there would be nothing to pay on chain.
+func (s *paymentService) couponDiscount(coupon model.Coupon, fiat string, amount float64) (float64, error) {
+	var discount float64
+	switch coupon.Type {
+	case model.CouponPercent:
+		discount = roundCents(amount * coupon.Value / 100)
+	case model.CouponFixed:
+		converted, err := s.convertFiat(coupon.Value, coupon.Currency, fiat)
+		if err != nil {
+			return 0, fmt.Errorf("failed to convert coupon %s to %s: %w", coupon.Code, fiat, err)
+		}
+		discount = converted
+	}
+
+	if roundCents(amount-discount) < 0.01 {
+		return 0, fmt.Errorf("%w: %s covers the whole price", ErrCouponNotRedeemable, coupon.Code)
+	}
+	return discount, nil
+}
+
+// paymentFiat is the fiat price of payment, payments made before fiat pricing were priced in USD.
+func paymentFiat(payment model.Payment) (string, float64) {
+	if payment.FiatAmount == 0 {
+		return "USD", payment.AmountUSD
+	}
+	return payment.FiatCurrency, payment.FiatAmount
+}
+
+func formatFiat(currency string, amount float64) string {
+	return fmt.Sprintf("%.2f %s", amount, currency)
+}
+
+// quoteAmount converts a USD price to the amount the customer has to send in currency and
+// returns the quote it used.
+func (s *paymentService) quoteAmount(usdAmount float64, currency model.Currency) (float64, oracle.Quote, error) {
+	if currency.IsToken {
+		// the TRC20 tokens we accept (USDT) are USD stablecoins, so they are priced 1:1
+		quote := oracle.Quote{Symbol: currency.Code, PriceUSD: 1, Source: "usd_peg", FetchedAt: time.Now()}
+		return math.Ceil(usdAmount*1e6) / 1e6, quote, nil
+	}
+	quote, err := s.oracle.Price(currency.Code)
+	if err != nil {
+		return 0, oracle.Quote{}, err
+	}
+	return usdAmount / quote.PriceUSD, quote, nil
+}
+
+// applyQuote sets the payment's amount from quote and locks it for QUOTE_LOCK_MINUTES, the lock
+// never outlives the payment itself.
+func applyQuote(payment *model.Payment, amount float64, quote oracle.Quote) {
+	quotedAt := quote.FetchedAt
+	expiresAt := time.Now().Add(time.Duration(config.Cfg.QUOTE_LOCK_MINUTES) * time.Minute)
+	if payment.ExpiresAt != nil && payment.ExpiresAt.Before(expiresAt) {
+		expiresAt = *payment.ExpiresAt
+	}
+
+	payment.AmountTRX = amount
+	payment.QuoteRate = quote.PriceUSD
+	payment.QuoteSource = quote.Source
+	payment.QuotedAt = &quotedAt
+	payment.QuoteExpiresAt = &expiresAt
+	payment.RemainingAmount = amount
+}
+
+// quoteExpired reports whether the payment's quote lock has ended. Payments created before
+// quotes were stored are locked for QUOTE_LOCK_MINUTES from their creation.
+func quoteExpired(payment model.Payment) bool {
+	if payment.QuoteExpiresAt != nil {
+		return time.Now().After(*payment.QuoteExpiresAt)
+	}
+	return time.Since(payment.CreatedAt) > time.Duration(config.Cfg.QUOTE_LOCK_MINUTES)*time.Minute
+}
+
+// expiryWindow is how long a payment stays open: the plan's window, else the currency's, else
+// PAYMENT_EXPIRY_MINUTES.
+func expiryWindow(plan model.Plan, currency model.Currency) time.Duration {
+	minutes := int64(config.Cfg.PAYMENT_EXPIRY_MINUTES)
+	if plan.ExpiryMinutes > 0 {
+		minutes = plan.ExpiryMinutes
+	} else if currency.ExpiryMinutes > 0 {
+		minutes = currency.ExpiryMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// paymentExpired reports whether the payment window has ended. Payments created before
+// expires_at was stored get their window from their creation.
+func paymentExpired(payment model.Payment) bool {
+	if payment.ExpiresAt != nil {
+		return time.Now().After(*payment.ExpiresAt)
+	}
+	return time.Since(payment.CreatedAt) > expiryWindow(payment.Plan, payment.Currency)
+}
+
+func quoteResponse(payment model.Payment) *dto.PaymentQuoteResponse {
+	if payment.QuotedAt == nil || payment.QuoteExpiresAt == nil {
+		return nil
+	}
+
+	var expiresIn int64
+	if payment.Status == model.Pending {
+		expiresIn = max(int64(time.Until(*payment.QuoteExpiresAt).Seconds()), 0)
+	}
+
+	fxRate := payment.FXRate
+	if fxRate == 0 {
+		fxRate = 1
+	}
+
+	return &dto.PaymentQuoteResponse{
+		Rate:         payment.QuoteRate,
+		FXRate:       fxRate,
+		Source:       payment.QuoteSource,
+		QuotedAt:     payment.QuotedAt.Format("2006-01-02T15:04:05Z07:00"),
+		ExpiresAt:    payment.QuoteExpiresAt.Format("2006-01-02T15:04:05Z07:00"),
+		ExpiresIn:    expiresIn,
+		RequoteCount: payment.RequoteCount,
+	}
+}
+
 func (s *paymentService) CheckPaymentStatusById(id string) dto.ApiResponse {
 
 	payment, err := s.repo.FindPaymentById(id)
@@ -180,24 +732,129 @@ func (s *paymentService) CheckPaymentStatusById(id string) dto.ApiResponse {
 		return dto.NewError("Failed to get payment by id.", err)
 	}
 
-	base64Image, err := util.GenerateQRCodeBase64(payment.Wallet.WalletAddress)
+	base64Image, err := util.GenerateQRCodeBase64(paymentQRContent(payment))
 	if err != nil {
 		return dto.NewError("Failed to create qr.", err)
 	}
+	amountDue := payment.AmountTRX
+	if payment.Status == model.PartiallyPaid {
+		amountDue = payment.RemainingAmount
+	}
+
+	deposits, err := s.depositRepo.FindDepositsByPaymentId(payment.ID)
+	if err != nil {
+		return dto.NewError("Failed to get payment deposits.", err)
+	}
 
+	depositResponses := make([]dto.PaymentDepositResponse, 0, len(deposits))
+	for _, deposit := range deposits {
+		depositResponses = append(depositResponses, dto.PaymentDepositResponse{
+			TxHash:        deposit.TxHash,
+			Amount:        deposit.Amount,
+			Confirmations: deposit.Confirmations,
+			Status:        deposit.Status,
+		})
+	}
+
+	fiatCurrency, fiatAmount := paymentFiat(payment)
 	return dto.NewSuccess("Got payment details successfully.", dto.PaymentResponse{
-		PaymentId:        payment.ID,
-		Status:           payment.Status,
-		PlanId:           payment.PlanID,
-		Email:            payment.UserEmail,
-		QrImage:          base64Image,
-		TrxAmount:        payment.AmountTRX,
-		TrxWalletAddress: payment.Wallet.WalletAddress,
-		CreatedAt:        payment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:        payment.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		PaymentId:             payment.ID,
+		Status:                payment.Status,
+		PlanId:                payment.PlanID,
+		InvoiceID:             paymentInvoiceID(payment),
+		Email:                 payment.UserEmail,
+		QrImage:               base64Image,
+		CurrencyCode:          payment.CurrencyCode,
+		FiatCurrency:          fiatCurrency,
+		FiatAmount:            fiatAmount,
+		AmountUSD:             payment.AmountUSD,
+		CouponCode:            payment.CouponCode,
+		OriginalAmount:        payment.OriginalAmount,
+		DiscountAmount:        payment.DiscountAmount,
+		Amount:                amountDue,
+		TrxAmount:             amountDue,
+		TotalAmount:           payment.AmountTRX,
+		PaidAmount:            payment.PaidAmountTRX,
+		RemainingAmount:       payment.RemainingAmount,
+		TrxWalletAddress:      payment.Wallet.WalletAddress,
+		Confirmations:         leastConfirmations(deposits),
+		RequiredConfirmations: payment.Currency.Confirmations,
+		Deposits:              depositResponses,
+		Quote:                 quoteResponse(payment),
+		ExternalReference:     paymentReference(payment),
+		Metadata:              paymentMetadata(payment),
+		SuccessURL:            payment.SuccessURL,
+		CancelURL:             payment.CancelURL,
+		ExpiresAt:             formatExpiresAt(payment),
+		CreatedAt:             payment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:             payment.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	})
 }
 
+// GetPaymentEvent returns the current snapshot of a payment as streamed to its event subscribers.
+func (s *paymentService) GetPaymentEvent(id string) (dto.PaymentEvent, error) {
+	payment, err := s.repo.FindPaymentById(id)
+	if err != nil {
+		return dto.PaymentEvent{}, err
+	}
+	if payment.ID == "" {
+		return dto.PaymentEvent{}, gorm.ErrRecordNotFound
+	}
+
+	deposits, err := s.depositRepo.FindDepositsByPaymentId(payment.ID)
+	if err != nil {
+		return dto.PaymentEvent{}, err
+	}
+
+	amountDue := payment.AmountTRX
+	if payment.Status == model.PartiallyPaid {
+		amountDue = payment.RemainingAmount
+	}
+	return dto.PaymentEvent{
+		PaymentId:             payment.ID,
+		Status:                payment.Status,
+		CurrencyCode:          payment.CurrencyCode,
+		Amount:                amountDue,
+		TotalAmount:           payment.AmountTRX,
+		PaidAmount:            payment.PaidAmountTRX,
+		RemainingAmount:       payment.RemainingAmount,
+		Confirmations:         leastConfirmations(deposits),
+		RequiredConfirmations: payment.Currency.Confirmations,
+		ExpiresAt:             formatExpiresAt(payment),
+	}, nil
+}
+
+// publishStatus pushes the payment's current snapshot to the clients streaming it. The payment is
+// only reloaded when someone listens, and the broker drops snapshots that didn't change.
+func (s *paymentService) publishStatus(id string) {
+	if !pubsub.Payments.HasSubscribers(id) {
+		return
+	}
+	event, err := s.GetPaymentEvent(id)
+	if err != nil {
+		log.Printf("Failed to build status event for payment %s: %v", id, err)
+		return
+	}
+	msg, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode status event for payment %s: %v", id, err)
+		return
+	}
+	pubsub.Payments.Publish(id, msg)
+}
+
+// leastConfirmations returns the confirmations of the payment, which is as confirmed as its least
+// confirmed deposit.
+func leastConfirmations(deposits []model.Deposit) int64 {
+	var confirmations int64
+	for i, deposit := range deposits {
+		if i == 0 || deposit.Confirmations < confirmations {
+			confirmations = deposit.Confirmations
+		}
+	}
+	return confirmations
+}
+
 func (s *paymentService) CancelPaymentById(id string) dto.ApiResponse {
 	// Get the payment with id
 	payment, err := s.repo.FindPaymentById(id)
@@ -221,24 +878,83 @@ func (s *paymentService) CancelPaymentById(id string) dto.ApiResponse {
 		return dto.NewError("Failed to cancel payment", err)
 	}
 
+	s.webhookService.Dispatch(model.PaymentCancelledEvent, payment)
+	s.publishStatus(payment.ID)
+
+	// whatever was already paid goes back to the customer
+	received, _, err := s.depositTotals(payment)
+	if err != nil {
+		log.Printf("Failed to fetch deposits of cancelled payment %s: %v", payment.ID, err)
+	} else if received > 0 {
+		if _, err := s.refundService.CreateRefund(payment.ID, received, model.RefundCancelled); err != nil {
+			log.Printf("Failed to open refund for cancelled payment %s: %v", payment.ID, err)
+		}
+	}
+
 	return dto.NewSuccess("Cancelled payment successfully.", nil)
 }
 
-func (s *paymentService) CreatePayment(body dto.CreatePaymentRequest) (dto.PaymentResponse, error) {
-	// Verify email verification token first
-	verificationService := NewVerificationService()
-	if !verificationService.IsEmailVerified(body.VerificationToken) {
-		return dto.PaymentResponse{}, fmt.Errorf("email verification required. Please verify your email first")
+// supersededRenewals returns the renewal invoices of email nothing was paid on yet, a new purchase
+// cancels them so they don't keep the customer from buying for the whole RENEWAL_INVOICE_DAYS. A
+// renewal shares the customer's deposit wallet with any other payment, so the two can't be open at
+// once. The new purchase wins and the subscription is invoiced again once it's settled. Renewals
+// that received a deposit still have to be settled first.
+func (s *paymentService) supersededRenewals(email string) ([]model.Payment, error) {
+	renewals, err := s.repo.FindPendingRenewalPaymentsByEmail(email)
+	if err != nil {
+		return nil, err
 	}
 
-	hasPendingPayment, err := s.repo.HasPendingPayment(body.Email)
-	if err != nil {
-		return dto.PaymentResponse{}, fmt.Errorf("failed to check pending payment : %w", err)
+	var superseded []model.Payment
+	for _, renewal := range renewals {
+		deposits, err := s.depositRepo.FindDepositsByPaymentId(renewal.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(deposits) == 0 {
+			superseded = append(superseded, renewal)
+		}
 	}
+	return superseded, nil
+}
 
-	if hasPendingPayment {
-		return dto.PaymentResponse{}, fmt.Errorf("please complete or cancel the previous pending payment to create a new one.")
+// supersedeRenewals cancels the renewal invoices supersededRenewals returned and releases their
+// subscriptions.
+func (s *paymentService) supersedeRenewals(renewals []model.Payment) error {
+	for _, renewal := range renewals {
+		renewal.Status = model.Cancelled
+		if err := s.repo.UpdatePayment(renewal); err != nil {
+			return err
+		}
+		if err := s.subscriptionService.ReleaseRenewal(renewal.ID); err != nil {
+			return err
+		}
+		log.Printf("Renewal payment %s of subscription %s cancelled for a new purchase", renewal.ID, renewal.SubscriptionID)
+		s.webhookService.Dispatch(model.PaymentCancelledEvent, renewal)
+		s.publishStatus(renewal.ID)
 	}
+	return nil
+}
+
+// CreatePayment opens a payment for a customer. Customers must have verified their email, payments
+// created with a merchant API key are trusted with the email they were given.
+func (s *paymentService) CreatePayment(body dto.CreatePaymentRequest, apiKey *model.APIKey) (dto.PaymentResponse, error) {
+	// Verify email verification token first
+	if apiKey == nil {
+		verificationService := NewVerificationService()
+		email, verified := verificationService.VerifiedEmail(body.VerificationToken)
+		if !verified {
+			return dto.PaymentResponse{}, fmt.Errorf("email verification required. Please verify your email first")
+		}
+		// the token only vouches for the email it was issued for
+		if normalizeEmail(email) != normalizeEmail(body.Email) {
+			return dto.PaymentResponse{}, fmt.Errorf("email doesn't match the verified email")
+		}
+	} else if body.Email == "" {
+		return dto.PaymentResponse{}, fmt.Errorf("email is required")
+	}
+	body.Email = normalizeEmail(body.Email)
+
 	//get the selected plan
 	plan, err := s.repo.FindPlanById(body.PlanId)
 
@@ -246,97 +962,425 @@ func (s *paymentService) CreatePayment(body dto.CreatePaymentRequest) (dto.Payme
 		return dto.PaymentResponse{}, fmt.Errorf("plan not found : %w", err)
 	}
 
-	//currency
-	currency, err := s.repo.FindCurrencyByCode(body.CurrencyCode)
+	fiatCurrency, fiatAmount, err := s.planPrice(plan, body.FiatCurrency)
+	if err != nil {
+		return dto.PaymentResponse{}, err
+	}
+	price := paymentPrice{Currency: fiatCurrency, Amount: fiatAmount}
+
+	if body.CouponCode != "" {
+		coupon, err := s.couponService.Redeemable(body.CouponCode, body.Email, plan.ID)
+		if err != nil {
+			return dto.PaymentResponse{}, err
+		}
+		discount, err := s.couponDiscount(*coupon, fiatCurrency, fiatAmount)
+		if err != nil {
+			return dto.PaymentResponse{}, err
+		}
+		price.Coupon = coupon
+		price.Discount = discount
+		price.Amount = roundCents(fiatAmount - discount)
+	}
+
+	_, resp, err := s.openPayment(body, apiKey, plan, price, nil)
+	return resp, err
+}
+
+// CreateInvoice bills the customer an arbitrary amount instead of a plan. The invoice is paid
+// through a payment like any other and the customer is emailed it along with a pay link.
+func (s *paymentService) CreateInvoice(req dto.CreateInvoiceRequest, apiKey *model.APIKey) (dto.InvoiceResponse, error) {
+	req.Email = normalizeEmail(req.Email)
+	invoice, err := buildInvoice(req)
+	if err != nil {
+		return dto.InvoiceResponse{}, err
+	}
 
+	body := dto.CreatePaymentRequest{
+		Email:             req.Email,
+		CurrencyCode:      req.CurrencyCode,
+		ExternalReference: req.ExternalReference,
+		Metadata:          req.Metadata,
+		SuccessURL:        req.SuccessURL,
+		CancelURL:         req.CancelURL,
+	}
+	payment, resp, err := s.openPayment(body, apiKey, model.Plan{}, paymentPrice{Currency: invoice.Currency, Amount: invoice.Amount}, invoice)
 	if err != nil {
-		return dto.PaymentResponse{}, fmt.Errorf("curency not found : %w", err)
+		return dto.InvoiceResponse{}, err
 	}
 
-	//convert usd to trx
+	log.Printf("Invoice %s of %s opened for %s with payment %s", invoice.ID, formatFiat(invoice.Currency, invoice.Amount), invoice.Email, payment.ID)
+	if err := NewEmailService().SendInvoiceEmail(payment, *invoice, renewalPayLink(payment)); err != nil {
+		log.Printf("Failed to send invoice email for invoice %s: %v", invoice.ID, err)
+	}
 
-	amountTrx, err := tron.ConvertUSDToTRX(plan.PriceUSD)
+	return dto.InvoiceResponse{
+		Invoice:    *invoice,
+		Payment:    resp,
+		InvoiceURL: invoicePath(invoice.ID),
+		PdfURL:     invoicePath(invoice.ID) + "/pdf",
+	}, nil
+}
 
+// openPayment creates a payment at price for the plan, or for the invoice when one is given, on
+// the customer's deposit wallet.
+func (s *paymentService) openPayment(body dto.CreatePaymentRequest, apiKey *model.APIKey, plan model.Plan, price paymentPrice, invoice *model.Invoice) (model.Payment, dto.PaymentResponse, error) {
+	metadata, err := normalizeMetadata(body.Metadata)
 	if err != nil {
-		return dto.PaymentResponse{}, fmt.Errorf("failed to convert amount to trx : %w", err)
+		return model.Payment{}, dto.PaymentResponse{}, err
+	}
+	for _, redirect := range [][2]string{{"success_url", body.SuccessURL}, {"cancel_url", body.CancelURL}} {
+		if redirect[1] != "" && !isHTTPURL(redirect[1]) {
+			return model.Payment{}, dto.PaymentResponse{}, fmt.Errorf("%s must be an http or https URL", redirect[0])
+		}
 	}
 
-	//check if the wallet for user there or not:9
+	var apiKeyID string
+	if apiKey != nil {
+		apiKeyID = apiKey.ID
+	}
+	var externalReference *string
+	if body.ExternalReference != "" {
+		exists, err := s.repo.ExistsByExternalReference(apiKeyID, body.ExternalReference)
+		if err != nil {
+			return model.Payment{}, dto.PaymentResponse{}, fmt.Errorf("failed to check external reference : %w", err)
+		}
+		if exists {
+			return model.Payment{}, dto.PaymentResponse{}, fmt.Errorf("a payment with external reference %s already exists", body.ExternalReference)
+		}
+		externalReference = &body.ExternalReference
+	}
 
-	wallet, err := s.repo.FindWalletByEmail(body.Email)
+	renewals, err := s.supersededRenewals(body.Email)
+	if err != nil {
+		return model.Payment{}, dto.PaymentResponse{}, fmt.Errorf("failed to check renewal invoices : %w", err)
+	}
+	renewalIDs := make([]string, 0, len(renewals))
+	for _, renewal := range renewals {
+		renewalIDs = append(renewalIDs, renewal.ID)
+	}
 
-	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+	hasPendingPayment, err := s.repo.HasPendingPaymentExcept(body.Email, renewalIDs)
+	if err != nil {
+		return model.Payment{}, dto.PaymentResponse{}, fmt.Errorf("failed to check pending payment : %w", err)
+	}
 
-		return dto.PaymentResponse{}, fmt.Errorf("db error : %w", err)
+	if hasPendingPayment {
+		return model.Payment{}, dto.PaymentResponse{}, fmt.Errorf("please complete or cancel the previous pending payment to create a new one.")
 	}
+	//currency
+	currency, err := s.repo.FindCurrencyByCode(body.CurrencyCode)
 
-	var walletID string
+	if err != nil {
+		return model.Payment{}, dto.PaymentResponse{}, fmt.Errorf("curency not found : %w", err)
+	}
 
-	if wallet.ID != "" { //he already has a wallet
-		walletID = wallet.ID
-	} else {
-		//nope create a new for that guy
-		//
-		walletSecret, walletAddr, err := tron.GenerateWallet()
+	if !currency.Enabled {
+		return model.Payment{}, dto.PaymentResponse{}, fmt.Errorf("currency %s is not enabled", currency.Code)
+	}
 
-		if err != nil {
-			return dto.PaymentResponse{}, fmt.Errorf("wallet generation error : %w", err)
-		}
+	if currency.IsToken && currency.ContractAddr == "" {
+		return model.Payment{}, dto.PaymentResponse{}, fmt.Errorf("currency %s has no contract address configured", currency.Code)
+	}
 
-		encKey, err := util.AesEncryptPK(walletSecret)
+	//convert the price to usd, then to the payment currency
 
-		if err != nil {
-			return dto.PaymentResponse{}, fmt.Errorf("wallet secret encryption failed : %w", err)
-		}
+	amountUSD, fxRate, err := s.toUSD(price.Currency, price.Amount)
+	if err != nil {
+		return model.Payment{}, dto.PaymentResponse{}, fmt.Errorf("failed to convert %s to USD : %w", price.Currency, err)
+	}
 
-		newWallet := model.Wallet{
-			ID:            util.GenerateUniqueID(),
-			Email:         body.Email,
-			WalletAddress: walletAddr,
-			WalletSecret:  encKey,
-		}
+	amountTrx, quote, err := s.quoteAmount(amountUSD, currency)
 
-		err = s.repo.CreateWallet(newWallet)
-		if err != nil {
-			return dto.PaymentResponse{}, fmt.Errorf("failed to create wallet : %w", err)
-		}
+	if err != nil {
+		return model.Payment{}, dto.PaymentResponse{}, fmt.Errorf("failed to convert amount to %s : %w", currency.Code, err)
+	}
 
-		walletID = newWallet.ID
-		wallet = newWallet
+	//check if the wallet for user there or not:9
+
+	wallet, err := s.walletForEmail(body.Email)
+
+	if err != nil {
+		return model.Payment{}, dto.PaymentResponse{}, err
 	}
 
+	walletID := wallet.ID
+
+	now := time.Now()
+	expiresAt := now.Add(expiryWindow(plan, currency))
 	payment := model.Payment{
 		ID:            util.GenerateUniqueID(),
 		PlanID:        plan.ID,
-		AmountUSD:     plan.PriceUSD,
+		AmountUSD:     amountUSD,
+		FiatCurrency:  price.Currency,
+		FiatAmount:    price.Amount,
+		FXRate:        fxRate,
 		WalletID:      walletID,
 		CurrencyCode:  currency.Code,
 		AmountTRX:     amountTrx,
 		UserEmail:     body.Email,
 		Status:        model.Pending,
 		PaidAmountTRX: 0,
+		ExpiresAt:     &expiresAt,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+
+		APIKeyID:          apiKeyID,
+		ExternalReference: externalReference,
+		Metadata:          metadata,
+		SuccessURL:        body.SuccessURL,
+		CancelURL:         body.CancelURL,
+	}
+	if invoice != nil {
+		invoice.PaymentID = payment.ID
+		invoice.AmountUSD = amountUSD
+		payment.Invoice = invoice
+	}
+	if price.Coupon != nil {
+		payment.CouponID = price.Coupon.ID
+		payment.CouponCode = price.Coupon.Code
+		payment.OriginalAmount = roundCents(price.Amount + price.Discount)
+		payment.DiscountAmount = price.Discount
+	}
+	applyQuote(&payment, amountTrx, quote)
+
+	// only now that the purchase is good to go, it must not cancel a renewal and then fail
+	if err := s.supersedeRenewals(renewals); err != nil {
+		return model.Payment{}, dto.PaymentResponse{}, fmt.Errorf("failed to cancel renewal invoice : %w", err)
 	}
 
-	err = s.repo.CreatePayment(payment)
+	if price.Coupon != nil {
+		err = s.couponService.Redeem(price.Coupon, payment)
+	} else {
+		err = s.repo.CreatePayment(payment)
+	}
 
 	if err != nil {
-		return dto.PaymentResponse{}, fmt.Errorf("failed to create payment : %w", err)
+		return model.Payment{}, dto.PaymentResponse{}, fmt.Errorf("failed to create payment : %w", err)
 	}
+
+	payment.Wallet = wallet
+	s.webhookService.Dispatch(model.PaymentCreatedEvent, payment)
 	//generate a qr
 	base64Image, err := util.GenerateQRCodeBase64(wallet.WalletAddress)
 	if err != nil {
-		return dto.PaymentResponse{}, fmt.Errorf("failed to create qr : %w", err)
-	}
-	return dto.PaymentResponse{
-		PaymentId:        payment.ID,
-		Status:           model.Pending,
-		PlanId:           plan.ID,
-		Email:            body.Email,
-		QrImage:          base64Image,
-		TrxAmount:        amountTrx,
-		TrxWalletAddress: wallet.WalletAddress,
-		CreatedAt:        payment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt:        payment.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		return model.Payment{}, dto.PaymentResponse{}, fmt.Errorf("failed to create qr : %w", err)
+	}
+	return payment, dto.PaymentResponse{
+		PaymentId:             payment.ID,
+		Status:                model.Pending,
+		PlanId:                plan.ID,
+		InvoiceID:             paymentInvoiceID(payment),
+		Email:                 body.Email,
+		QrImage:               base64Image,
+		CurrencyCode:          currency.Code,
+		FiatCurrency:          price.Currency,
+		FiatAmount:            price.Amount,
+		AmountUSD:             amountUSD,
+		CouponCode:            payment.CouponCode,
+		OriginalAmount:        payment.OriginalAmount,
+		DiscountAmount:        payment.DiscountAmount,
+		Amount:                amountTrx,
+		TrxAmount:             amountTrx,
+		TotalAmount:           amountTrx,
+		RemainingAmount:       amountTrx,
+		TrxWalletAddress:      wallet.WalletAddress,
+		RequiredConfirmations: currency.Confirmations,
+		Quote:                 quoteResponse(payment),
+		ExternalReference:     body.ExternalReference,
+		Metadata:              paymentMetadata(payment),
+		SuccessURL:            payment.SuccessURL,
+		CancelURL:             payment.CancelURL,
+		ExpiresAt:             formatExpiresAt(payment),
+		CreatedAt:             payment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:             payment.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}, nil
 
 }
+
+// paymentQRContent is what the payment's QR code encodes. Partially paid payments carry the
+// remaining amount so the customer's wallet only asks for what's left.
+func paymentQRContent(payment model.Payment) string {
+	if payment.Status != model.PartiallyPaid {
+		return payment.Wallet.WalletAddress
+	}
+	content := fmt.Sprintf("tron:%s?amount=%s", payment.Wallet.WalletAddress, strconv.FormatFloat(payment.RemainingAmount, 'f', -1, 64))
+	if payment.Currency.IsToken {
+		content += "&token=" + payment.Currency.ContractAddr
+	}
+	return content
+}
+
+// walletForEmail returns the deposit wallet of email, creating it on its first payment.
+func (s *paymentService) walletForEmail(email string) (model.Wallet, error) {
+	wallet, err := s.repo.FindWalletByEmail(email)
+
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+
+		return model.Wallet{}, fmt.Errorf("db error : %w", err)
+	}
+
+	if wallet.ID != "" { //he already has a wallet
+		return wallet, nil
+	}
+
+	//nope create a new for that guy
+	//
+	walletSecret, walletAddr, err := tron.GenerateWallet()
+
+	if err != nil {
+		return model.Wallet{}, fmt.Errorf("wallet generation error : %w", err)
+	}
+
+	encKey, err := util.AesEncryptPK(walletSecret)
+
+	if err != nil {
+		return model.Wallet{}, fmt.Errorf("wallet secret encryption failed : %w", err)
+	}
+
+	newWallet := model.Wallet{
+		ID:            util.GenerateUniqueID(),
+		Email:         email,
+		WalletAddress: walletAddr,
+		WalletSecret:  encKey,
+	}
+
+	err = s.repo.CreateWallet(newWallet)
+	if err != nil {
+		return model.Wallet{}, fmt.Errorf("failed to create wallet : %w", err)
+	}
+	return newWallet, nil
+}
+
+// CreateRenewalPayment invoices the next period of subscription in currencyCode, priced in
+// fiatCurrency. The invoice is open until the subscription ends and is re-quoted whenever its
+// quote lock runs out.
+func (s *paymentService) CreateRenewalPayment(subscription model.Subscription, currencyCode, fiatCurrency string) (model.Payment, error) {
+	plan, err := s.repo.FindPlanById(subscription.PlanID)
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("plan not found : %w", err)
+	}
+
+	currency, err := s.repo.FindCurrencyByCode(currencyCode)
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("curency not found : %w", err)
+	}
+	if !currency.Enabled {
+		return model.Payment{}, fmt.Errorf("currency %s is not enabled", currency.Code)
+	}
+	if currency.IsToken && currency.ContractAddr == "" {
+		return model.Payment{}, fmt.Errorf("currency %s has no contract address configured", currency.Code)
+	}
+
+	fiatCurrency, fiatAmount, err := s.planPrice(plan, fiatCurrency)
+	if err != nil {
+		return model.Payment{}, err
+	}
+	amountUSD, fxRate, err := s.toUSD(fiatCurrency, fiatAmount)
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("failed to convert %s to USD : %w", fiatCurrency, err)
+	}
+	amount, quote, err := s.quoteAmount(amountUSD, currency)
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("failed to convert amount to %s : %w", currency.Code, err)
+	}
+
+	wallet, err := s.walletForEmail(subscription.Email)
+	if err != nil {
+		return model.Payment{}, err
+	}
+
+	now := time.Now()
+	expiresAt := subscription.EndsAt
+	if window := now.Add(expiryWindow(plan, currency)); expiresAt.Before(window) {
+		expiresAt = window
+	}
+	payment := model.Payment{
+		ID:             util.GenerateUniqueID(),
+		PlanID:         plan.ID,
+		AmountUSD:      amountUSD,
+		FiatCurrency:   fiatCurrency,
+		FiatAmount:     fiatAmount,
+		FXRate:         fxRate,
+		WalletID:       wallet.ID,
+		CurrencyCode:   currency.Code,
+		UserEmail:      subscription.Email,
+		Status:         model.Pending,
+		SubscriptionID: subscription.ID,
+		ExpiresAt:      &expiresAt,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	applyQuote(&payment, amount, quote)
+
+	if err := s.repo.CreatePayment(payment); err != nil {
+		return model.Payment{}, fmt.Errorf("failed to create payment : %w", err)
+	}
+
+	payment.Plan = plan
+	payment.Wallet = wallet
+	payment.Currency = currency
+	s.webhookService.Dispatch(model.PaymentCreatedEvent, payment)
+	return payment, nil
+}
+
+// normalizeMetadata checks the merchant metadata is a JSON object of at most maxMetadataBytes and
+// returns it compacted for storage.
+func normalizeMetadata(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+
+	var object map[string]any
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return "", fmt.Errorf("metadata must be a JSON object")
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, raw); err != nil {
+		return "", fmt.Errorf("metadata must be a JSON object")
+	}
+	if compacted.Len() > maxMetadataBytes {
+		return "", fmt.Errorf("metadata is %d bytes, at most %d are allowed", compacted.Len(), maxMetadataBytes)
+	}
+	return compacted.String(), nil
+}
+
+// isHTTPURL reports whether raw is an absolute http(s) URL, the only kind the checkout redirects
+// to. Anything else, javascript: above all, would run on the gateway's origin.
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	return scheme == "http" || scheme == "https"
+}
+
+// paymentMetadata returns the stored metadata for responses, nil when the payment has none.
+func paymentMetadata(payment model.Payment) json.RawMessage {
+	if payment.Metadata == "" {
+		return nil
+	}
+	return json.RawMessage(payment.Metadata)
+}
+
+func paymentInvoiceID(payment model.Payment) string {
+	if payment.Invoice == nil {
+		return ""
+	}
+	return payment.Invoice.ID
+}
+
+func paymentReference(payment model.Payment) string {
+	if payment.ExternalReference == nil {
+		return ""
+	}
+	return *payment.ExternalReference
+}
+
+func formatExpiresAt(payment model.Payment) string {
+	if payment.ExpiresAt == nil {
+		return ""
+	}
+	return payment.ExpiresAt.Format("2006-01-02T15:04:05Z07:00")
+}
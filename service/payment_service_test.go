@@ -0,0 +1,268 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/internal/oracle"
+	"github.com/thebytearray/BytePayments/internal/tron"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+)
+
+// fakeFXOracle quotes the USD value of one unit of the currencies it knows.
+type fakeFXOracle map[string]float64
+
+func (o fakeFXOracle) Price(symbol string) (oracle.Quote, error) {
+	rate, ok := o[symbol]
+	if !ok {
+		return oracle.Quote{}, oracle.ErrNoPrice
+	}
+	return oracle.Quote{Symbol: symbol, PriceUSD: rate}, nil
+}
+
+var testFXRates = fakeFXOracle{"EUR": 1.08, "GBP": 1.25, "JPY": 0.0067}
+
+func TestConvertFiat(t *testing.T) {
+	s := &paymentService{fx: testFXRates}
+
+	tests := []struct {
+		name     string
+		amount   float64
+		from, to string
+		want     float64
+		wantErr  bool
+	}{
+		{"same currency", 9.99, "EUR", "EUR", 9.99, false},
+		{"from USD", 10, "USD", "EUR", 9.26, false},
+		{"to USD", 10, "EUR", "USD", 10.8, false},
+		{"between fiat currencies through USD", 10, "GBP", "EUR", 11.57, false},
+		{"rounded to cents", 1000, "JPY", "USD", 6.7, false},
+		{"unknown currency", 10, "USD", "CHF", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.convertFiat(tt.amount, tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("convertFiat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("convertFiat(%v, %s, %s) = %v, want %v", tt.amount, tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanPrice(t *testing.T) {
+	s := &paymentService{fx: testFXRates}
+
+	usdPlan := model.Plan{ID: "usd", PriceUSD: 10}
+	eurPlan := model.Plan{ID: "eur", Currency: "EUR", Prices: []model.PlanPrice{{Currency: "EUR", Amount: 9}}}
+	mixedPlan := model.Plan{ID: "mixed", PriceUSD: 10, Prices: []model.PlanPrice{{Currency: "GBP", Amount: 7.5}}}
+
+	tests := []struct {
+		name         string
+		plan         model.Plan
+		fiat         string
+		wantCurrency string
+		wantAmount   float64
+		wantErr      error
+	}{
+		{"plan's own currency by default", usdPlan, "", "USD", 10, nil},
+		{"legacy plan converted", usdPlan, "EUR", "EUR", 9.26, nil},
+		{"currency is case-insensitive", usdPlan, "eur", "EUR", 9.26, nil},
+		{"plan priced in EUR", eurPlan, "", "EUR", 9, nil},
+		{"EUR plan converted to USD", eurPlan, "USD", "USD", 9.72, nil},
+		{"fixed price wins over conversion", mixedPlan, "GBP", "GBP", 7.5, nil},
+		{"no fixed price converts the base", mixedPlan, "EUR", "EUR", 9.26, nil},
+		{"no rate for the currency", usdPlan, "CHF", "", 0, oracle.ErrNoPrice},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			currency, amount, err := s.planPrice(tt.plan, tt.fiat)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("planPrice() error = %v, want %v", err, tt.wantErr)
+			}
+			if currency != tt.wantCurrency || math.Abs(amount-tt.wantAmount) > 1e-9 {
+				t.Errorf("planPrice() = %v %s, want %v %s", amount, currency, tt.wantAmount, tt.wantCurrency)
+			}
+		})
+	}
+
+	if _, _, err := s.planPrice(model.Plan{ID: "free", Currency: "EUR"}, "USD"); err == nil {
+		t.Error("planPrice() of a plan without a price in its currency succeeded")
+	}
+}
+
+func TestCouponDiscount(t *testing.T) {
+	s := &paymentService{fx: testFXRates}
+
+	tests := []struct {
+		name    string
+		coupon  model.Coupon
+		fiat    string
+		amount  float64
+		want    float64
+		wantErr error
+	}{
+		{"percent", model.Coupon{Type: model.CouponPercent, Value: 20}, "USD", 10, 2, nil},
+		{"percent rounded to cents", model.Coupon{Type: model.CouponPercent, Value: 15}, "EUR", 9.99, 1.5, nil},
+		{"fixed in the payment's currency", model.Coupon{Type: model.CouponFixed, Value: 3, Currency: "EUR"}, "EUR", 9, 3, nil},
+		{"fixed converted to the payment's currency", model.Coupon{Type: model.CouponFixed, Value: 5, Currency: "USD"}, "EUR", 9, 4.63, nil},
+		{"fixed leaving a cent", model.Coupon{Type: model.CouponFixed, Value: 9.99, Currency: "USD"}, "USD", 10, 9.99, nil},
+		{"fixed covering the whole price", model.Coupon{Type: model.CouponFixed, Value: 10, Currency: "USD"}, "USD", 10, 0, ErrCouponNotRedeemable},
+		{"fixed above the price", model.Coupon{Type: model.CouponFixed, Value: 50, Currency: "USD"}, "USD", 10, 0, ErrCouponNotRedeemable},
+		{"no rate for the coupon's currency", model.Coupon{Type: model.CouponFixed, Value: 5, Currency: "CHF"}, "USD", 10, 0, oracle.ErrNoPrice},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.coupon.Code = "TEST"
+			got, err := s.couponDiscount(tt.coupon, tt.fiat, tt.amount)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("couponDiscount() error = %v, want %v", err, tt.wantErr)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("couponDiscount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeDepositRepository keeps created deposits in memory, the other methods aren't used by the
+// tests.
+type fakeDepositRepository struct {
+	repository.DepositRepository
+	deposits []model.Deposit
+}
+
+func (r *fakeDepositRepository) CreateDeposit(deposit *model.Deposit) error {
+	r.deposits = append(r.deposits, *deposit)
+	return nil
+}
+
+func (r *fakeDepositRepository) FindDepositsByPaymentId(paymentID string) ([]model.Deposit, error) {
+	var deposits []model.Deposit
+	for _, deposit := range r.deposits {
+		if deposit.PaymentID == paymentID {
+			deposits = append(deposits, deposit)
+		}
+	}
+	return deposits, nil
+}
+
+func (r *fakeDepositRepository) ExistsByTxHash(txHash string) (bool, error) {
+	for _, deposit := range r.deposits {
+		if deposit.TxHash == txHash {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// fakeOrphanRepository knows the tx hashes of orphaned deposits only.
+type fakeOrphanRepository struct {
+	repository.OrphanRepository
+	txHashes []string
+}
+
+func (r *fakeOrphanRepository) ExistsByTxHash(txHash string) (bool, error) {
+	return slices.Contains(r.txHashes, txHash), nil
+}
+
+func TestCreditTransfers(t *testing.T) {
+	config.Cfg = &config.Config{TRX_HOT_WALLET_ADDRESS: "THotWallet"}
+
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	payment := model.Payment{ID: "payment1", CurrencyCode: "TRX", CreatedAt: created, Wallet: model.Wallet{WalletAddress: "TDeposit"}}
+
+	tests := []struct {
+		name      string
+		transfer  tron.IncomingTransfer
+		known     []model.Deposit
+		orphaned  []string
+		wantCount int
+	}{
+		{"customer transfer", tron.IncomingTransfer{TxHash: "tx1", From: "TCustomer", Amount: 10, Timestamp: created.Add(time.Minute)}, nil, nil, 1},
+		{"made before the payment", tron.IncomingTransfer{TxHash: "tx1", From: "TCustomer", Amount: 10, Timestamp: created.Add(-time.Minute)}, nil, nil, 0},
+		{"sweep top-up from the hot wallet", tron.IncomingTransfer{TxHash: "tx1", From: "THotWallet", Amount: 2, Timestamp: created.Add(time.Minute)}, nil, nil, 0},
+		{"already credited", tron.IncomingTransfer{TxHash: "tx1", From: "TCustomer", Amount: 10, Timestamp: created.Add(time.Minute)}, []model.Deposit{{TxHash: "tx1", PaymentID: "payment0"}}, nil, 0},
+		{"orphaned", tron.IncomingTransfer{TxHash: "tx1", From: "TCustomer", Amount: 10, Timestamp: created.Add(time.Minute)}, nil, []string{"tx1"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deposits := &fakeDepositRepository{deposits: tt.known}
+			s := &paymentService{depositRepo: deposits, orphanRepo: &fakeOrphanRepository{txHashes: tt.orphaned}}
+
+			got, err := s.creditTransfers(payment, []tron.IncomingTransfer{tt.transfer})
+			if err != nil {
+				t.Fatalf("creditTransfers() error = %v", err)
+			}
+			if got != tt.wantCount {
+				t.Fatalf("creditTransfers() = %d, want %d", got, tt.wantCount)
+			}
+			if got == 0 {
+				return
+			}
+
+			deposit := deposits.deposits[len(deposits.deposits)-1]
+			if deposit.PaymentID != payment.ID || deposit.TxHash != tt.transfer.TxHash || deposit.Amount != tt.transfer.Amount || deposit.Status != model.DepositDetected {
+				t.Errorf("creditTransfers() recorded %+v", deposit)
+			}
+		})
+	}
+}
+
+func TestConfirmDeposit(t *testing.T) {
+	tests := []struct {
+		name              string
+		deposit           model.Deposit
+		currentBlock      int64
+		wantConfirmations int64
+		wantStatus        model.DepositStatus
+		wantChanged       bool
+	}{
+		{"not in a block yet", model.Deposit{Status: model.DepositDetected}, 100, 0, model.DepositDetected, false},
+		{"block ahead of the node", model.Deposit{BlockNumber: 101, Status: model.DepositDetected}, 100, 0, model.DepositDetected, false},
+		{"in the current block", model.Deposit{BlockNumber: 100, Status: model.DepositDetected}, 100, 1, model.DepositConfirming, true},
+		{"one short of the depth", model.Deposit{BlockNumber: 82, Confirmations: 1, Status: model.DepositConfirming}, 100, 19, model.DepositConfirming, true},
+		{"at the depth", model.Deposit{BlockNumber: 81, Confirmations: 19, Status: model.DepositConfirming}, 100, 20, model.DepositConfirmed, true},
+		{"unchanged", model.Deposit{BlockNumber: 90, Confirmations: 11, Status: model.DepositConfirming}, 100, 11, model.DepositConfirming, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deposit := tt.deposit
+			changed := confirmDeposit(&deposit, tt.currentBlock, 20)
+			if changed != tt.wantChanged {
+				t.Errorf("confirmDeposit() = %v, want %v", changed, tt.wantChanged)
+			}
+			if deposit.Confirmations != tt.wantConfirmations || deposit.Status != tt.wantStatus {
+				t.Errorf("confirmDeposit() left %d confirmations and status %s, want %d and %s", deposit.Confirmations, deposit.Status, tt.wantConfirmations, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSumDeposits(t *testing.T) {
+	tests := []struct {
+		name          string
+		deposits      []model.Deposit
+		wantTotal     float64
+		wantConfirmed float64
+	}{
+		{"no deposits", nil, 0, 0},
+		{"all confirmed", []model.Deposit{{Amount: 4, Status: model.DepositConfirmed}, {Amount: 6, Status: model.DepositConfirmed}}, 10, 10},
+		{"confirming deposits count towards the total only", []model.Deposit{{Amount: 4, Status: model.DepositConfirmed}, {Amount: 6, Status: model.DepositConfirming}, {Amount: 1, Status: model.DepositDetected}}, 11, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, confirmed := sumDeposits(tt.deposits)
+			if total != tt.wantTotal || confirmed != tt.wantConfirmed {
+				t.Errorf("sumDeposits() = %v, %v, want %v, %v", total, confirmed, tt.wantTotal, tt.wantConfirmed)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package service
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/thebytearray/BytePayments/model"
 	"github.com/thebytearray/BytePayments/repository"
 )
@@ -30,13 +33,68 @@ func (s *plansService) GetPlanByID(id string) (*model.Plan, error) {
 }
 
 func (s *plansService) CreatePlan(plan *model.Plan) error {
+	if err := normalizePlanPricing(plan); err != nil {
+		return err
+	}
 	return s.repo.CreatePlan(plan)
 }
 
 func (s *plansService) UpdatePlan(plan *model.Plan) error {
+	if err := normalizePlanPricing(plan); err != nil {
+		return err
+	}
 	return s.repo.UpdatePlan(plan)
 }
 
 func (s *plansService) DeletePlan(id string) error {
 	return s.repo.DeletePlan(id)
 }
+
+// normalizePlanPricing upper-cases the plan's currencies and checks it has a price in its own
+// currency, USD prices being PriceUSD and never one of Prices.
+func normalizePlanPricing(plan *model.Plan) error {
+	plan.Currency = planCurrency(*plan)
+
+	seen := make(map[string]bool)
+	for i := range plan.Prices {
+		price := &plan.Prices[i]
+		price.Currency = strings.ToUpper(price.Currency)
+		price.PlanID = plan.ID
+		if price.Currency == "USD" {
+			return fmt.Errorf("USD prices are set with price_usd")
+		}
+		if seen[price.Currency] {
+			return fmt.Errorf("plan has more than one %s price", price.Currency)
+		}
+		if price.Amount <= 0 {
+			return fmt.Errorf("%s price must be greater than 0", price.Currency)
+		}
+		seen[price.Currency] = true
+	}
+
+	if _, ok := fixedPlanPrice(*plan, plan.Currency); !ok {
+		return fmt.Errorf("plan is priced in %s but has no %s price", plan.Currency, plan.Currency)
+	}
+	return nil
+}
+
+// planCurrency is the currency the plan is priced in, plans made before fiat pricing are in USD.
+func planCurrency(plan model.Plan) string {
+	if plan.Currency == "" {
+		return "USD"
+	}
+	return strings.ToUpper(plan.Currency)
+}
+
+// fixedPlanPrice returns the price the plan has set in currency, if it has one.
+func fixedPlanPrice(plan model.Plan, currency string) (float64, bool) {
+	if currency == "USD" {
+		return plan.PriceUSD, plan.PriceUSD > 0
+	}
+	for _, price := range plan.Prices {
+		if price.Currency == currency {
+			return price.Amount, true
+		}
+	}
+	return 0, false
+}
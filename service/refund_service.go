@@ -0,0 +1,403 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/internal/tron"
+	"github.com/thebytearray/BytePayments/internal/util"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+	"gorm.io/gorm"
+)
+
+const (
+	// a refund is sent this many times before it's marked as failed
+	refundMaxAttempts = 3
+	// transactions expire after a minute, one still unknown after this was dropped
+	refundReceiptTimeout = 10 * time.Minute
+)
+
+var (
+	ErrInvalidRefundLink = errors.New("refund link is invalid or has expired")
+	// ErrNoRefundLinkSecret refuses refunds while REFUND_LINK_SECRET isn't set, links signed with an
+	// empty key could be forged by anyone.
+	ErrNoRefundLinkSecret = errors.New("REFUND_LINK_SECRET is not set, refunds are disabled")
+)
+
+type RefundService interface {
+	CreateRefund(paymentID string, amount float64, reason model.RefundReason) (*model.Refund, error)
+	GetRefunds(status, paymentID string) ([]model.Refund, error)
+	GetRefundByLink(id string, expires int64, signature string) (dto.RefundResponse, error)
+	SubmitRefundAddress(id string, req dto.RefundAddressRequest) (dto.RefundResponse, error)
+	ApproveRefund(id string) (*model.Refund, error)
+	RejectRefund(id string) (*model.Refund, error)
+	RetryRefund(id string) (*model.Refund, error)
+	ResendRefundLink(id string) (*model.Refund, error)
+	ProcessRefunds()
+}
+
+type refundService struct {
+	repo           repository.RefundRepository
+	paymentRepo    repository.PaymentRepository
+	depositRepo    repository.DepositRepository
+	webhookService WebhookService
+}
+
+func NewRefundService(repo repository.RefundRepository) RefundService {
+	return &refundService{
+		repo:           repo,
+		paymentRepo:    repository.NewPaymentRepository(database.DB),
+		depositRepo:    repository.NewDepositRepository(database.DB),
+		webhookService: NewWebhookService(repository.NewWebhookRepository(database.DB)),
+	}
+}
+
+// CreateRefund opens a refund of amount for the payment and emails the customer a signed link to
+// give the address it should go to. Payments can't be refunded more than they received.
+func (s *refundService) CreateRefund(paymentID string, amount float64, reason model.RefundReason) (*model.Refund, error) {
+	if config.Cfg.REFUND_LINK_SECRET == "" {
+		return nil, ErrNoRefundLinkSecret
+	}
+	payment, err := s.paymentRepo.FindPaymentById(paymentID)
+	if err != nil {
+		return nil, err
+	}
+	if payment.ID == "" {
+		return nil, fmt.Errorf("payment %s: %w", paymentID, gorm.ErrRecordNotFound)
+	}
+	if payment.Status != model.Completed && payment.Status != model.Cancelled && payment.Status != model.Expired {
+		return nil, fmt.Errorf("payment is %s, only completed, cancelled and expired payments can be refunded", payment.Status)
+	}
+
+	deposits, err := s.depositRepo.FindDepositsByPaymentId(payment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch deposits: %w", err)
+	}
+	var received float64
+	for _, deposit := range deposits {
+		received += deposit.Amount
+	}
+
+	refunded, err := s.repo.SumRefundsByPaymentId(payment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch refunds: %w", err)
+	}
+
+	amount = math.Floor(amount*1e6) / 1e6
+	if amount <= 0 {
+		return nil, errors.New("refund amount must be positive")
+	}
+	if refunded+amount > received+1e-9 {
+		return nil, fmt.Errorf("payment received %.6f %s and %.6f is already refunded, %.6f can't be refunded", received, payment.CurrencyCode, refunded, amount)
+	}
+
+	// refunds are sent from the hot wallet, so the funds of a payment that never completed have
+	// to be swept there first
+	if payment.SweepStatus == "" {
+		if err := s.paymentRepo.MarkAsUnsweptById(payment.ID); err != nil {
+			return nil, fmt.Errorf("failed to queue the payment's sweep: %w", err)
+		}
+	}
+
+	refund := model.Refund{
+		ID:            util.GenerateUniqueID(),
+		PaymentID:     payment.ID,
+		CurrencyCode:  payment.CurrencyCode,
+		Amount:        amount,
+		Reason:        reason,
+		Status:        model.RefundAwaitingAddress,
+		LinkExpiresAt: refundLinkExpiry(),
+	}
+	if err := s.repo.CreateRefund(&refund); err != nil {
+		return nil, err
+	}
+	log.Printf("Refund %s of %.6f %s opened for payment %s (%s)", refund.ID, amount, refund.CurrencyCode, payment.ID, reason)
+
+	if err := NewEmailService().SendRefundLinkEmail(payment, refund, refundLink(refund)); err != nil {
+		log.Printf("Failed to send refund link for refund %s: %v", refund.ID, err)
+	}
+	return &refund, nil
+}
+
+func (s *refundService) GetRefunds(status, paymentID string) ([]model.Refund, error) {
+	return s.repo.GetRefunds(status, paymentID)
+}
+
+func (s *refundService) GetRefundByLink(id string, expires int64, signature string) (dto.RefundResponse, error) {
+	refund, err := s.linkedRefund(id, expires, signature)
+	if err != nil {
+		return dto.RefundResponse{}, err
+	}
+	return refundResponse(*refund), nil
+}
+
+// SubmitRefundAddress stores the address the customer wants the refund sent to. It can be changed
+// until an admin approves the refund.
+func (s *refundService) SubmitRefundAddress(id string, req dto.RefundAddressRequest) (dto.RefundResponse, error) {
+	refund, err := s.linkedRefund(id, req.Expires, req.Signature)
+	if err != nil {
+		return dto.RefundResponse{}, err
+	}
+	if refund.Status != model.RefundAwaitingAddress && refund.Status != model.RefundRequested {
+		return dto.RefundResponse{}, fmt.Errorf("refund is %s, its address can't be changed anymore", refund.Status)
+	}
+	if err := tron.ValidateAddress(req.Address); err != nil {
+		return dto.RefundResponse{}, err
+	}
+
+	now := time.Now()
+	refund.ToAddress = req.Address
+	refund.Status = model.RefundRequested
+	refund.RequestedAt = &now
+	if err := s.repo.UpdateRefund(refund); err != nil {
+		return dto.RefundResponse{}, err
+	}
+	log.Printf("Refund %s requested to %s", refund.ID, refund.ToAddress)
+	return refundResponse(*refund), nil
+}
+
+// ApproveRefund queues a requested refund, the refund job sends it from the hot wallet.
+func (s *refundService) ApproveRefund(id string) (*model.Refund, error) {
+	refund, err := s.repo.GetRefundByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Status != model.RefundRequested {
+		return nil, fmt.Errorf("refund is %s, only requested refunds can be approved", refund.Status)
+	}
+
+	now := time.Now()
+	refund.Status = model.RefundApproved
+	refund.ApprovedAt = &now
+	if err := s.repo.UpdateRefund(refund); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+func (s *refundService) RejectRefund(id string) (*model.Refund, error) {
+	refund, err := s.repo.GetRefundByID(id)
+	if err != nil {
+		return nil, err
+	}
+	switch refund.Status {
+	case model.RefundAwaitingAddress, model.RefundRequested, model.RefundFailed:
+	default:
+		return nil, fmt.Errorf("refund is %s and can't be rejected", refund.Status)
+	}
+
+	refund.Status = model.RefundRejected
+	if err := s.repo.UpdateRefund(refund); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// RetryRefund queues a failed refund to be sent again.
+func (s *refundService) RetryRefund(id string) (*model.Refund, error) {
+	refund, err := s.repo.GetRefundByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Status != model.RefundFailed {
+		return nil, fmt.Errorf("refund is %s, only failed refunds can be retried", refund.Status)
+	}
+
+	refund.Status = model.RefundApproved
+	refund.Attempts = 0
+	refund.LastError = ""
+	if err := s.repo.UpdateRefund(refund); err != nil {
+		return nil, err
+	}
+	return refund, nil
+}
+
+// ResendRefundLink emails the customer a fresh link, the previous one stops working.
+func (s *refundService) ResendRefundLink(id string) (*model.Refund, error) {
+	refund, err := s.repo.GetRefundByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if refund.Status != model.RefundAwaitingAddress && refund.Status != model.RefundRequested {
+		return nil, fmt.Errorf("refund is %s, its address can't be changed anymore", refund.Status)
+	}
+
+	payment, err := s.paymentRepo.FindPaymentById(refund.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Cfg.REFUND_LINK_SECRET == "" {
+		return nil, ErrNoRefundLinkSecret
+	}
+	refund.LinkExpiresAt = refundLinkExpiry()
+	if err := s.repo.UpdateRefund(refund); err != nil {
+		return nil, err
+	}
+	if err := NewEmailService().SendRefundLinkEmail(payment, *refund, refundLink(*refund)); err != nil {
+		return nil, fmt.Errorf("failed to send refund link: %w", err)
+	}
+	return refund, nil
+}
+
+// ProcessRefunds checks the receipts of sent refunds and sends the approved ones.
+func (s *refundService) ProcessRefunds() {
+	sent, err := s.repo.FindRefundsByStatus(model.RefundSent)
+	if err != nil {
+		log.Println("Error fetching sent refunds : ", err)
+		return
+	}
+	for _, refund := range sent {
+		s.checkReceipt(&refund)
+	}
+
+	approved, err := s.repo.FindRefundsByStatus(model.RefundApproved)
+	if err != nil {
+		log.Println("Error fetching approved refunds : ", err)
+		return
+	}
+	for _, refund := range approved {
+		if err := s.send(&refund); err != nil {
+			log.Printf("Failed to send refund %s: %v", refund.ID, err)
+		}
+	}
+}
+
+// send transfers the refund from the hot wallet.
+func (s *refundService) send(refund *model.Refund) error {
+	currency, err := s.paymentRepo.FindCurrencyByCode(refund.CurrencyCode)
+	if err != nil {
+		return fmt.Errorf("failed to fetch currency: %w", err)
+	}
+	hotWalletKey, err := hotWalletPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	refund.Attempts++
+	var txID string
+	var sendErr error
+	if currency.IsToken {
+		txID, sendErr = tron.SendTRC20(tron.TRON_CLIENT, config.Cfg.TRX_HOT_WALLET_ADDRESS, refund.ToAddress, currency.ContractAddr, refund.Amount, hotWalletKey)
+	} else {
+		txID, sendErr = tron.SendTRX(tron.TRON_CLIENT, config.Cfg.TRX_HOT_WALLET_ADDRESS, refund.ToAddress, refund.Amount, hotWalletKey)
+	}
+
+	if sendErr != nil {
+		refund.LastError = sendErr.Error()
+		if refund.Attempts >= refundMaxAttempts {
+			refund.Status = model.RefundFailed
+		}
+	} else {
+		now := time.Now()
+		refund.TxID = txID
+		refund.Status = model.RefundSent
+		refund.SentAt = &now
+		refund.LastError = ""
+		log.Printf("Refunded %.6f %s to %s for payment %s. TxID: %s", refund.Amount, refund.CurrencyCode, refund.ToAddress, refund.PaymentID, txID)
+	}
+
+	if err := s.repo.UpdateRefund(refund); err != nil {
+		return fmt.Errorf("failed to update refund: %w", err)
+	}
+	return sendErr
+}
+
+func (s *refundService) checkReceipt(refund *model.Refund) {
+	info, err := tron.GetTransactionInfo(tron.TRON_CLIENT, refund.TxID)
+	switch {
+	case err == nil:
+		now := time.Now()
+		refund.Status = model.RefundConfirmed
+		refund.FeeSun = info.GetFee()
+		refund.ConfirmedAt = &now
+	case errors.Is(err, tron.ErrTransactionNotFound):
+		if refund.SentAt != nil && time.Since(*refund.SentAt) < refundReceiptTimeout {
+			return
+		}
+		refund.Status = model.RefundFailed
+		refund.LastError = fmt.Sprintf("transaction not found after %s", refundReceiptTimeout)
+	default:
+		refund.Status = model.RefundFailed
+		refund.LastError = err.Error()
+		if info != nil {
+			refund.FeeSun += info.GetFee()
+		}
+	}
+
+	if err := s.repo.UpdateRefund(refund); err != nil {
+		log.Printf("Failed to update refund %s: %v", refund.ID, err)
+		return
+	}
+
+	if refund.Status == model.RefundFailed {
+		log.Printf("Refund %s of payment %s failed: %s", refund.TxID, refund.PaymentID, refund.LastError)
+		return
+	}
+
+	log.Printf("Refund %s of payment %s confirmed, fee %d sun", refund.TxID, refund.PaymentID, refund.FeeSun)
+	payment, err := s.paymentRepo.FindPaymentById(refund.PaymentID)
+	if err != nil {
+		log.Printf("Failed to fetch payment %s: %v", refund.PaymentID, err)
+		return
+	}
+	s.webhookService.Dispatch(model.PaymentRefundedEvent, payment)
+}
+
+// linkedRefund returns the refund a signed link points to. Only the latest link of a refund is
+// valid, sending a new one moves LinkExpiresAt.
+func (s *refundService) linkedRefund(id string, expires int64, signature string) (*model.Refund, error) {
+	if config.Cfg.REFUND_LINK_SECRET == "" || time.Now().Unix() > expires || !hmac.Equal([]byte(signature), []byte(signRefundLink(id, expires))) {
+		return nil, ErrInvalidRefundLink
+	}
+
+	refund, err := s.repo.GetRefundByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if refund.LinkExpiresAt.Unix() != expires {
+		return nil, ErrInvalidRefundLink
+	}
+	return refund, nil
+}
+
+func refundLinkExpiry() time.Time {
+	return time.Now().Add(time.Duration(config.Cfg.REFUND_LINK_TTL_HOURS) * time.Hour)
+}
+
+func refundLink(refund model.Refund) string {
+	expires := refund.LinkExpiresAt.Unix()
+	return fmt.Sprintf("%s/refund?id=%s&expires=%d&signature=%s", strings.TrimRight(config.Cfg.APP_URL, "/"), refund.ID, expires, signRefundLink(refund.ID, expires))
+}
+
+// signRefundLink returns the hex HMAC-SHA256 of "id.expires" keyed with REFUND_LINK_SECRET.
+func signRefundLink(id string, expires int64) string {
+	mac := hmac.New(sha256.New, []byte(config.Cfg.REFUND_LINK_SECRET))
+	mac.Write([]byte(id))
+	mac.Write([]byte("."))
+	mac.Write([]byte(strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func refundResponse(refund model.Refund) dto.RefundResponse {
+	return dto.RefundResponse{
+		RefundID:     refund.ID,
+		PaymentID:    refund.PaymentID,
+		CurrencyCode: refund.CurrencyCode,
+		Amount:       refund.Amount,
+		Status:       refund.Status,
+		ToAddress:    refund.ToAddress,
+	}
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+	"gorm.io/gorm"
+)
+
+// fakeRefundRepository serves refunds from a map, the other methods aren't used by the tests.
+type fakeRefundRepository struct {
+	repository.RefundRepository
+	refunds map[string]*model.Refund
+}
+
+func (r *fakeRefundRepository) GetRefundByID(id string) (*model.Refund, error) {
+	refund, ok := r.refunds[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return refund, nil
+}
+
+func TestSignRefundLink(t *testing.T) {
+	config.Cfg = &config.Config{REFUND_LINK_SECRET: "secret"}
+
+	signature := signRefundLink("refund1", 1700000000)
+	if signature != signRefundLink("refund1", 1700000000) {
+		t.Fatal("signRefundLink() isn't deterministic")
+	}
+	if len(signature) != 64 {
+		t.Errorf("signRefundLink() = %q, want a hex SHA-256", signature)
+	}
+
+	tests := []struct {
+		name    string
+		secret  string
+		id      string
+		expires int64
+	}{
+		{"other refund", "secret", "refund2", 1700000000},
+		{"other expiry", "secret", "refund1", 1700000001},
+		{"other secret", "other", "refund1", 1700000000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Cfg.REFUND_LINK_SECRET = tt.secret
+			if signRefundLink(tt.id, tt.expires) == signature {
+				t.Errorf("signRefundLink(%q, %d) with secret %q matches the original signature", tt.id, tt.expires, tt.secret)
+			}
+		})
+	}
+}
+
+func TestLinkedRefund(t *testing.T) {
+	config.Cfg = &config.Config{REFUND_LINK_SECRET: "secret"}
+
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	expired := time.Now().Add(-time.Hour).Truncate(time.Second)
+	s := &refundService{repo: &fakeRefundRepository{refunds: map[string]*model.Refund{
+		"current": {ID: "current", LinkExpiresAt: expires},
+		"resent":  {ID: "resent", LinkExpiresAt: expires.Add(time.Hour)},
+		"expired": {ID: "expired", LinkExpiresAt: expired},
+	}}}
+
+	tests := []struct {
+		name      string
+		id        string
+		expires   int64
+		signature string
+		secret    string
+		wantErr   error
+	}{
+		{"valid link", "current", expires.Unix(), signRefundLink("current", expires.Unix()), "secret", nil},
+		{"tampered signature", "current", expires.Unix(), signRefundLink("current", expires.Unix()+1), "secret", ErrInvalidRefundLink},
+		{"tampered expiry", "current", expires.Unix() + 60, signRefundLink("current", expires.Unix()), "secret", ErrInvalidRefundLink},
+		{"signature of another refund", "current", expires.Unix(), signRefundLink("resent", expires.Unix()), "secret", ErrInvalidRefundLink},
+		{"expired link", "expired", expired.Unix(), signRefundLink("expired", expired.Unix()), "secret", ErrInvalidRefundLink},
+		{"superseded by a newer link", "resent", expires.Unix(), signRefundLink("resent", expires.Unix()), "secret", ErrInvalidRefundLink},
+		{"unknown refund", "missing", expires.Unix(), signRefundLink("missing", expires.Unix()), "secret", gorm.ErrRecordNotFound},
+		{"no secret configured", "current", expires.Unix(), signRefundLink("current", expires.Unix()), "", ErrInvalidRefundLink},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// links are signed with "secret", the service verifies them with tt.secret
+			config.Cfg.REFUND_LINK_SECRET = tt.secret
+			refund, err := s.linkedRefund(tt.id, tt.expires, tt.signature)
+			config.Cfg.REFUND_LINK_SECRET = "secret"
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("linkedRefund() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && refund.ID != tt.id {
+				t.Errorf("linkedRefund() = refund %s, want %s", refund.ID, tt.id)
+			}
+		})
+	}
+}
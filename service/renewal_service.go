@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+)
+
+type RenewalService interface {
+	ProcessRenewals()
+}
+
+type renewalService struct {
+	repo           repository.SubscriptionRepository
+	paymentRepo    repository.PaymentRepository
+	paymentService PaymentService
+}
+
+func NewRenewalService(repo repository.SubscriptionRepository) RenewalService {
+	paymentRepo := repository.NewPaymentRepository(database.DB)
+	return &renewalService{
+		repo:           repo,
+		paymentRepo:    paymentRepo,
+		paymentService: NewPaymentService(paymentRepo),
+	}
+}
+
+// ProcessRenewals invoices the next period of the subscriptions ending within
+// RENEWAL_INVOICE_DAYS and emails the customer a pay link. The invoice is in the currency the
+// subscription was last paid in.
+func (s *renewalService) ProcessRenewals() {
+	if config.Cfg.RENEWAL_INVOICE_DAYS <= 0 {
+		return
+	}
+
+	subscriptions, err := s.repo.FindSubscriptionsToRenew(time.Now().AddDate(0, 0, config.Cfg.RENEWAL_INVOICE_DAYS))
+	if err != nil {
+		log.Println("Error fetching subscriptions to renew : ", err)
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		if err := s.renew(&subscription); err != nil {
+			log.Printf("Failed to invoice renewal of subscription %s: %v", subscription.ID, err)
+		}
+	}
+}
+
+func (s *renewalService) renew(subscription *model.Subscription) error {
+	// an open payment holds the customer's wallet, the renewal waits for it to be settled
+	open, err := s.paymentRepo.HasPendingPayment(subscription.Email)
+	if err != nil {
+		return fmt.Errorf("failed to check pending payment: %w", err)
+	}
+	if open {
+		return fmt.Errorf("%s has an open payment, retrying on the next run", subscription.Email)
+	}
+
+	period, err := s.repo.FindLatestPeriod(subscription.ID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the last period: %w", err)
+	}
+	last, err := s.paymentRepo.FindPaymentById(period.PaymentID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the last payment: %w", err)
+	}
+
+	// renewals stay in the currencies the customer last paid with
+	fiatCurrency, _ := paymentFiat(last)
+	payment, err := s.paymentService.CreateRenewalPayment(*subscription, last.CurrencyCode, fiatCurrency)
+	if err != nil {
+		return err
+	}
+
+	// the invoice doubles as the expiry reminder
+	now := time.Now()
+	subscription.RenewalPaymentID = payment.ID
+	subscription.ReminderSentAt = &now
+	if err := s.repo.UpdateSubscription(subscription); err != nil {
+		return fmt.Errorf("failed to record renewal payment %s: %w", payment.ID, err)
+	}
+	log.Printf("Renewal payment %s created for subscription %s", payment.ID, subscription.ID)
+
+	if err := NewEmailService().SendRenewalInvoiceEmail(payment, *subscription, renewalPayLink(payment)); err != nil {
+		log.Printf("Failed to send renewal invoice for payment %s: %v", payment.ID, err)
+	}
+	return nil
+}
+
+// renewalPayLink is the payment page of the renewal invoice, it shows the current quote.
+func renewalPayLink(payment model.Payment) string {
+	return fmt.Sprintf("%s/pay?id=%s", strings.TrimRight(config.Cfg.APP_URL, "/"), payment.ID)
+}
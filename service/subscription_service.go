@@ -0,0 +1,189 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/util"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+	"gorm.io/gorm"
+)
+
+type SubscriptionService interface {
+	ActivateFromPayment(payment model.Payment) (*model.Subscription, error)
+	GetSubscriptions(email, status string) ([]model.Subscription, error)
+	GetEntitlement(email, planID string) (dto.EntitlementResponse, error)
+	ProcessSubscriptions()
+	ReleaseRenewal(paymentID string) error
+}
+
+type subscriptionService struct {
+	repo repository.SubscriptionRepository
+}
+
+func NewSubscriptionService(repo repository.SubscriptionRepository) SubscriptionService {
+	return &subscriptionService{repo: repo}
+}
+
+// ActivateFromPayment credits a completed payment to its email's subscription to the plan. The
+// plan's DurationDays are added after the current end while the subscription is active, otherwise
+// a new run starts now. Crediting the same payment again is a no-op.
+func (s *subscriptionService) ActivateFromPayment(payment model.Payment) (*model.Subscription, error) {
+	if payment.Status != model.Completed {
+		return nil, fmt.Errorf("payment is %s, only completed payments grant a subscription", payment.Status)
+	}
+	if payment.Plan.DurationDays <= 0 {
+		return nil, fmt.Errorf("plan %s has no duration", payment.PlanID)
+	}
+
+	credited, err := s.repo.HasPeriodForPayment(payment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check subscription periods: %w", err)
+	}
+	if credited {
+		return nil, nil
+	}
+
+	email := normalizeEmail(payment.UserEmail)
+	subscription, err := s.repo.FindSubscriptionByEmailAndPlan(email, payment.PlanID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		subscription = &model.Subscription{
+			ID:     util.GenerateUniqueID(),
+			Email:  email,
+			PlanID: payment.PlanID,
+		}
+	}
+
+	now := time.Now()
+	start := now
+	if subscription.Status == model.SubscriptionActive && subscription.EndsAt.After(now) {
+		// renewals stack on the time left
+		start = subscription.EndsAt
+	} else {
+		subscription.StartsAt = now
+	}
+
+	period := model.SubscriptionPeriod{
+		ID:             util.GenerateUniqueID(),
+		SubscriptionID: subscription.ID,
+		PaymentID:      payment.ID,
+		StartsAt:       start,
+		EndsAt:         start.AddDate(0, 0, int(payment.Plan.DurationDays)),
+	}
+
+	subscription.EndsAt = period.EndsAt
+	subscription.Status = model.SubscriptionActive
+	subscription.ReminderSentAt = nil
+	subscription.RenewalPaymentID = ""
+	if err := s.repo.SaveSubscriptionPeriod(subscription, &period); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Subscription %s of %s to plan %s extended to %s by payment %s", subscription.ID, email, payment.PlanID, subscription.EndsAt.Format("2006-01-02T15:04:05Z07:00"), payment.ID)
+	return subscription, nil
+}
+
+func (s *subscriptionService) GetSubscriptions(email, status string) ([]model.Subscription, error) {
+	return s.repo.GetSubscriptions(normalizeEmail(email), status)
+}
+
+// GetEntitlement returns the subscriptions email holds right now, limited to planID when given.
+func (s *subscriptionService) GetEntitlement(email, planID string) (dto.EntitlementResponse, error) {
+	email = normalizeEmail(email)
+	subscriptions, err := s.repo.FindActiveSubscriptionsByEmail(email, time.Now())
+	if err != nil {
+		return dto.EntitlementResponse{}, err
+	}
+
+	res := dto.EntitlementResponse{
+		Email:         email,
+		PlanID:        planID,
+		Subscriptions: make([]dto.SubscriptionResponse, 0, len(subscriptions)),
+	}
+	for _, subscription := range subscriptions {
+		if planID != "" && subscription.PlanID != planID {
+			continue
+		}
+		res.Subscriptions = append(res.Subscriptions, subscriptionResponse(subscription))
+	}
+	res.Active = len(res.Subscriptions) > 0
+	return res, nil
+}
+
+// ProcessSubscriptions expires the subscriptions that ran out, lapsed when their renewal invoice
+// went unpaid, and reminds the customers whose subscription ends within SUBSCRIPTION_REMINDER_DAYS.
+func (s *subscriptionService) ProcessSubscriptions() {
+	now := time.Now()
+	emailService := NewEmailService()
+
+	ended, err := s.repo.FindSubscriptionsEndingBefore(now)
+	if err != nil {
+		log.Println("Error fetching ended subscriptions : ", err)
+		return
+	}
+	for _, subscription := range ended {
+		subscription.Status = model.SubscriptionExpired
+		if subscription.RenewalPaymentID != "" {
+			subscription.Status = model.SubscriptionLapsed
+		}
+		if err := s.repo.UpdateSubscription(&subscription); err != nil {
+			log.Printf("Failed to expire subscription %s: %v", subscription.ID, err)
+			continue
+		}
+		log.Printf("Subscription %s of %s to plan %s %s", subscription.ID, subscription.Email, subscription.PlanID, subscription.Status)
+
+		if err := emailService.SendSubscriptionExpiredEmail(subscription); err != nil {
+			log.Printf("Failed to send expiry email for subscription %s: %v", subscription.ID, err)
+		}
+	}
+
+	remindBefore := now.AddDate(0, 0, config.Cfg.SUBSCRIPTION_REMINDER_DAYS)
+	ending, err := s.repo.FindSubscriptionsToRemind(remindBefore)
+	if err != nil {
+		log.Println("Error fetching ending subscriptions : ", err)
+		return
+	}
+	for _, subscription := range ending {
+		if err := emailService.SendSubscriptionReminderEmail(subscription); err != nil {
+			log.Printf("Failed to send reminder for subscription %s: %v", subscription.ID, err)
+			continue
+		}
+
+		subscription.ReminderSentAt = &now
+		if err := s.repo.UpdateSubscription(&subscription); err != nil {
+			log.Printf("Failed to record reminder of subscription %s: %v", subscription.ID, err)
+		}
+	}
+}
+
+func subscriptionResponse(subscription model.Subscription) dto.SubscriptionResponse {
+	return dto.SubscriptionResponse{
+		SubscriptionID: subscription.ID,
+		PlanID:         subscription.PlanID,
+		PlanName:       subscription.Plan.Name,
+		Status:         subscription.Status,
+		StartsAt:       subscription.StartsAt.Format("2006-01-02T15:04:05Z07:00"),
+		EndsAt:         subscription.EndsAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// normalizeEmail is the form subscriptions, payments and wallets are stored and looked up under,
+// so a renewal finds the customer's open payments and wallet.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// ReleaseRenewal detaches a cancelled renewal invoice from its subscription, so the subscription
+// doesn't lapse over it and is invoiced again.
+func (s *subscriptionService) ReleaseRenewal(paymentID string) error {
+	return s.repo.ClearRenewalPayment(paymentID)
+}
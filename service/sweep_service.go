@@ -0,0 +1,624 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/internal/tron"
+	"github.com/thebytearray/BytePayments/internal/util"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+)
+
+const (
+	// a sweep is re-sent this many times before it's handed to an admin
+	sweepMaxAttempts = 3
+	// transactions expire after a minute, one still unknown after this was dropped
+	sweepReceiptTimeout = 10 * time.Minute
+)
+
+// Sweep policies, set with SWEEP_POLICY.
+const (
+	SweepPolicyImmediate = "immediate" // sweep wallets as soon as their payments complete
+	SweepPolicySchedule  = "schedule"  // sweep all wallets on SWEEP_SCHEDULE
+	SweepPolicyThreshold = "threshold" // sweep a wallet once its payments add up to the currency's sweep threshold
+)
+
+var errNothingToSweep = errors.New("no transferable amount available")
+
+type SweepService interface {
+	SweepUnsweptPayments()
+	PlanSweeps() (dto.SweepPlan, error)
+	ProcessPendingSweeps()
+	RetrySweep(id string) (*model.Sweep, error)
+	SweepOrphan(orphan model.OrphanDeposit, refundTo string) (string, error)
+	GetSweeps(status, paymentID string) ([]model.Sweep, error)
+}
+
+type sweepService struct {
+	repo               repository.SweepRepository
+	paymentRepo        repository.PaymentRepository
+	depositRepo        repository.DepositRepository
+	orphanRepo         repository.OrphanRepository
+	destinationService DestinationService
+	topUpService       TopUpService
+	webhookService     WebhookService
+}
+
+func NewSweepService(repo repository.SweepRepository) SweepService {
+	return &sweepService{
+		repo:               repo,
+		paymentRepo:        repository.NewPaymentRepository(database.DB),
+		depositRepo:        repository.NewDepositRepository(database.DB),
+		orphanRepo:         repository.NewOrphanRepository(database.DB),
+		destinationService: NewDestinationService(repository.NewDestinationRepository(database.DB)),
+		topUpService:       NewTopUpService(repository.NewTopUpRepository(database.DB)),
+		webhookService:     NewWebhookService(repository.NewWebhookRepository(database.DB)),
+	}
+}
+
+// walletSweep is the unswept payments of one deposit wallet and currency, swept together.
+type walletSweep struct {
+	payments []model.Payment
+	plan     dto.SweepPlanItem
+}
+
+// sweepLeg is one transfer of a wallet sweep, there is one per split of the rule that applies.
+type sweepLeg struct {
+	destinationID string
+	to            string
+	percent       float64
+	amount        float64
+}
+
+// SweepUnsweptPayments sweeps the wallets of completed payments the sweep policy says are due.
+// Payments sharing a wallet go out in a single batch.
+func (s *sweepService) SweepUnsweptPayments() {
+	wallets, err := s.planWallets()
+	if err != nil {
+		log.Println("Error planning sweeps : ", err)
+		return
+	}
+
+	for _, wallet := range wallets {
+		if !wallet.plan.Sweep {
+			continue
+		}
+		if err := s.sweepWallet(wallet.payments); err != nil {
+			log.Printf("Failed to sweep wallet %s: %v", wallet.plan.WalletAddress, err)
+		}
+	}
+}
+
+// PlanSweeps reports what the next sweep run would do without sending anything.
+func (s *sweepService) PlanSweeps() (dto.SweepPlan, error) {
+	wallets, err := s.planWallets()
+	if err != nil {
+		return dto.SweepPlan{}, err
+	}
+
+	plan := dto.SweepPlan{
+		Policy:  config.Cfg.SWEEP_POLICY,
+		Wallets: make([]dto.SweepPlanItem, 0, len(wallets)),
+	}
+	if plan.Policy == SweepPolicySchedule {
+		plan.Schedule = config.Cfg.SWEEP_SCHEDULE
+	}
+	for _, wallet := range wallets {
+		plan.Wallets = append(plan.Wallets, wallet.plan)
+		if wallet.plan.Sweep {
+			plan.TotalFeeSun += wallet.plan.EstimatedFeeSun
+		}
+	}
+	return plan, nil
+}
+
+func (s *sweepService) planWallets() ([]walletSweep, error) {
+	payments, err := s.paymentRepo.FindPaymentsBySweepStatus(model.PaymentUnswept)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch unswept payments: %w", err)
+	}
+
+	var wallets []walletSweep
+	index := make(map[string]int)
+	for _, payment := range payments {
+		key := payment.Wallet.WalletAddress + "/" + payment.CurrencyCode
+		i, ok := index[key]
+		if !ok {
+			wallets = append(wallets, walletSweep{})
+			i = len(wallets) - 1
+			index[key] = i
+		}
+		wallets[i].payments = append(wallets[i].payments, payment)
+	}
+
+	for i := range wallets {
+		wallets[i].plan = s.planWallet(wallets[i].payments)
+	}
+	return wallets, nil
+}
+
+// planWallet estimates the sweep of a wallet and decides whether the policy sweeps it.
+func (s *sweepService) planWallet(payments []model.Payment) dto.SweepPlanItem {
+	payment := payments[0]
+	item := dto.SweepPlanItem{
+		WalletAddress: payment.Wallet.WalletAddress,
+		CurrencyCode:  payment.CurrencyCode,
+		Threshold:     payment.Currency.SweepThreshold,
+	}
+	for _, p := range payments {
+		item.PaymentIDs = append(item.PaymentIDs, p.ID)
+	}
+
+	balance, amount, err := s.sweepAmount(payments)
+	if err != nil {
+		item.Reason = err.Error()
+		return item
+	}
+	item.Balance = balance
+	item.Amount = amount
+
+	ruleID, legs, feeSun, err := s.planLegs(payment, amount)
+	switch {
+	case errors.Is(err, errNothingToSweep) || errors.Is(err, tron.ErrInsufficientBalance):
+		item.Reason = "nothing to transfer"
+		return item
+	case err != nil:
+		item.Reason = err.Error()
+		return item
+	}
+
+	item.RuleID = ruleID
+	item.EstimatedFeeSun = feeSun
+	for _, leg := range legs {
+		item.Transferable += leg.amount
+		item.Legs = append(item.Legs, dto.SweepPlanLeg{
+			DestinationID: leg.destinationID,
+			Address:       leg.to,
+			Percent:       leg.percent,
+			Amount:        leg.amount,
+		})
+	}
+
+	if config.Cfg.SWEEP_POLICY == SweepPolicyThreshold && amount < item.Threshold {
+		item.Reason = fmt.Sprintf("amount below the %g %s threshold", item.Threshold, item.CurrencyCode)
+		return item
+	}
+	item.Sweep = true
+	return item
+}
+
+// sweepAmount returns the balance of the wallet the payments were made to and the amount to sweep
+// out of it, which is what was credited to the payments. The wallet is shared by all payments of
+// the email, so anything else it holds belongs to open, late or orphaned deposits and stays.
+func (s *sweepService) sweepAmount(payments []model.Payment) (float64, float64, error) {
+	balance, err := walletBalance(payments[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to check balance: %w", err)
+	}
+	credited, err := s.creditedAmount(payments)
+	if err != nil {
+		return 0, 0, err
+	}
+	return balance, math.Min(credited, balance), nil
+}
+
+// creditedAmount returns the total of the confirmed deposits of the payments.
+func (s *sweepService) creditedAmount(payments []model.Payment) (float64, error) {
+	var credited float64
+	for _, payment := range payments {
+		deposits, err := s.depositRepo.FindDepositsByPaymentId(payment.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to fetch deposits of payment %s: %w", payment.ID, err)
+		}
+		_, confirmed := sumDeposits(deposits)
+		credited += confirmed
+	}
+	return credited, nil
+}
+
+// planLegs splits amount, less the network fee when it's TRX, across the destinations of the sweep
+// rule that applies to the payment and estimates the total fee. Without a rule everything goes to
+// the hot wallet.
+func (s *sweepService) planLegs(payment model.Payment, amount float64) (string, []sweepLeg, int64, error) {
+	rule, err := s.destinationService.ResolveRule(payment)
+	if err != nil {
+		return "", nil, 0, err
+	}
+
+	var ruleID string
+	legs := []sweepLeg{{to: config.Cfg.TRX_HOT_WALLET_ADDRESS, percent: 100}}
+	if rule != nil {
+		ruleID = rule.ID
+		legs = make([]sweepLeg, 0, len(rule.Splits))
+		for _, split := range rule.Splits {
+			legs = append(legs, sweepLeg{
+				destinationID: split.DestinationID,
+				to:            split.Destination.Address,
+				percent:       split.Percent,
+			})
+		}
+	}
+
+	var available float64
+	var feeSun int64
+	if payment.Currency.IsToken {
+		// the whole amount moves, the fees are paid in TRX topped up from the hot wallet
+		available = amount
+		if available > 0 {
+			cost, err := tron.EstimateTRC20TransferCost(tron.TRON_CLIENT, payment.Wallet.WalletAddress, legs[0].to, payment.Currency.ContractAddr, amount)
+			if err != nil {
+				return "", nil, 0, fmt.Errorf("failed to estimate fee: %w", err)
+			}
+			feeSun = cost.FeeSun() * int64(len(legs))
+		}
+	} else {
+		transferable, err := tron.GetTransferableAmount(payment.Wallet.WalletAddress, amount)
+		if err != nil {
+			return "", nil, 0, fmt.Errorf("failed to calculate transferable amount: %w", err)
+		}
+		// only the first transfer can use the wallet's free bandwidth, budget the full fee for the rest
+		availableSun := tron.TrxToSun(transferable) - tron.MaxTRXTransferFeeSun()*int64(len(legs)-1)
+		available = tron.SunToTrx(availableSun)
+		feeSun = tron.TrxToSun(amount) - availableSun
+	}
+
+	if available <= 0 {
+		return "", nil, 0, errNothingToSweep
+	}
+
+	if err := splitLegs(legs, available); err != nil {
+		return "", nil, 0, err
+	}
+	return ruleID, legs, feeSun, nil
+}
+
+// splitLegs sets the amount of every leg to its percentage of available, to 6 decimals. Every leg
+// but the last is rounded down, the last one takes the remainder so nothing is left behind.
+func splitLegs(legs []sweepLeg, available float64) error {
+	var assigned float64
+	for i := range legs {
+		if i == len(legs)-1 {
+			legs[i].amount = math.Round((available-assigned)*1e6) / 1e6
+		} else {
+			legs[i].amount = math.Floor(available*legs[i].percent/100*1e6) / 1e6
+		}
+		if legs[i].amount <= 0 {
+			return fmt.Errorf("balance is too small to send %g%% to %s", legs[i].percent, legs[i].to)
+		}
+		assigned += legs[i].amount
+	}
+	return nil
+}
+
+// sweepWallet moves what was credited to the payments out of the wallet they were made to in one
+// batch.
+func (s *sweepService) sweepWallet(payments []model.Payment) error {
+	payment := payments[0]
+
+	_, amount, err := s.sweepAmount(payments)
+	if err != nil {
+		return err
+	}
+
+	ruleID, legs, _, err := s.planLegs(payment, amount)
+	if err != nil {
+		return err
+	}
+
+	batchID := s.sendBatch(payment, ruleID, legs, "")
+
+	ids := make([]string, 0, len(payments))
+	for _, p := range payments {
+		ids = append(ids, p.ID)
+	}
+	if err := s.paymentRepo.AssignSweepBatchByIds(ids, batchID); err != nil {
+		return fmt.Errorf("failed to link payments %v to sweep batch %s: %w", ids, batchID, err)
+	}
+	return nil
+}
+
+// SweepOrphan moves an orphaned deposit out of its wallet, back to refundTo when it's given and
+// to the sweep destinations otherwise. Only the orphan's amount is moved, less the network fee
+// when it's TRX. It returns the sweep batch the transfers were recorded under.
+func (s *sweepService) SweepOrphan(orphan model.OrphanDeposit, refundTo string) (string, error) {
+	payment := orphanPayment(orphan)
+
+	balance, err := walletBalance(payment)
+	if err != nil {
+		return "", fmt.Errorf("failed to check balance: %w", err)
+	}
+	amount := math.Min(orphan.Amount, balance)
+
+	if refundTo == "" {
+		ruleID, legs, _, err := s.planLegs(payment, amount)
+		if err != nil {
+			return "", err
+		}
+		return s.sendBatch(payment, ruleID, legs, orphan.ID), nil
+	}
+
+	if !payment.Currency.IsToken {
+		amount, err = tron.GetTransferableAmount(payment.Wallet.WalletAddress, amount)
+		if err != nil {
+			return "", fmt.Errorf("failed to calculate transferable amount: %w", err)
+		}
+	}
+	amount = math.Floor(amount*1e6) / 1e6
+	if amount <= 0 {
+		return "", errNothingToSweep
+	}
+	return s.sendBatch(payment, "", []sweepLeg{{to: refundTo, percent: 100, amount: amount}}, orphan.ID), nil
+}
+
+// sendBatch sends the legs out of the payment's deposit wallet and records one sweep per leg
+// under a new batch. A leg that couldn't be sent is recorded as failed and retried by the sweep job.
+func (s *sweepService) sendBatch(payment model.Payment, ruleID string, legs []sweepLeg, orphanID string) string {
+	batchID := util.GenerateUniqueID()
+	for _, leg := range legs {
+		txID, sendErr := s.sendLeg(payment, leg.to, leg.amount)
+
+		sweep := model.Sweep{
+			ID:            util.GenerateUniqueID(),
+			BatchID:       batchID,
+			PaymentID:     payment.ID,
+			OrphanID:      orphanID,
+			FromAddress:   payment.Wallet.WalletAddress,
+			ToAddress:     leg.to,
+			RuleID:        ruleID,
+			DestinationID: leg.destinationID,
+			Percent:       leg.percent,
+			CurrencyCode:  payment.CurrencyCode,
+			Amount:        leg.amount,
+			TxID:          txID,
+			Status:        model.SweepPending,
+			Attempts:      1,
+		}
+		if sendErr != nil {
+			sweep.Status = model.SweepFailed
+			sweep.LastError = sendErr.Error()
+			log.Printf("Failed to sweep %.6f %s from %s to %s: %v", leg.amount, payment.CurrencyCode, payment.Wallet.WalletAddress, leg.to, sendErr)
+		}
+
+		if err := s.repo.CreateSweep(&sweep); err != nil {
+			log.Printf("Failed to record sweep %s of payment %s: %v", txID, payment.ID, err)
+		}
+	}
+	return batchID
+}
+
+// ProcessPendingSweeps checks the receipts of broadcast sweeps and re-sends failed ones.
+func (s *sweepService) ProcessPendingSweeps() {
+	pending, err := s.repo.FindSweepsByStatus(model.SweepPending)
+	if err != nil {
+		log.Println("Error fetching pending sweeps : ", err)
+		return
+	}
+	for _, sweep := range pending {
+		s.checkReceipt(&sweep)
+	}
+
+	failed, err := s.repo.FindSweepsByStatus(model.SweepFailed)
+	if err != nil {
+		log.Println("Error fetching failed sweeps : ", err)
+		return
+	}
+	for _, sweep := range failed {
+		if sweep.Attempts >= sweepMaxAttempts {
+			log.Printf("Sweep %s of payment %s failed %d times, needs manual review", sweep.ID, sweep.PaymentID, sweep.Attempts)
+			sweep.Status = model.SweepManualReview
+			if err := s.repo.UpdateSweep(&sweep); err != nil {
+				log.Printf("Failed to update sweep %s: %v", sweep.ID, err)
+				continue
+			}
+			s.refreshBatch(sweep.BatchID)
+			continue
+		}
+
+		if err := s.resend(&sweep); err != nil {
+			log.Printf("Failed to retry sweep %s of payment %s: %v", sweep.ID, sweep.PaymentID, err)
+		}
+	}
+}
+
+func (s *sweepService) checkReceipt(sweep *model.Sweep) {
+	info, err := tron.GetTransactionInfo(tron.TRON_CLIENT, sweep.TxID)
+	switch {
+	case err == nil:
+		now := time.Now()
+		sweep.Status = model.SweepConfirmed
+		sweep.FeeSun = info.GetFee()
+		sweep.LastError = ""
+		sweep.ConfirmedAt = &now
+	case errors.Is(err, tron.ErrTransactionNotFound):
+		if time.Since(sweep.UpdatedAt) < sweepReceiptTimeout {
+			return
+		}
+		sweep.Status = model.SweepFailed
+		sweep.LastError = fmt.Sprintf("transaction not found after %s", sweepReceiptTimeout)
+	default:
+		sweep.Status = model.SweepFailed
+		sweep.LastError = err.Error()
+		if info != nil {
+			sweep.FeeSun += info.GetFee()
+		}
+	}
+
+	if err := s.repo.UpdateSweep(sweep); err != nil {
+		log.Printf("Failed to update sweep %s: %v", sweep.ID, err)
+		return
+	}
+
+	if sweep.Status == model.SweepFailed {
+		log.Printf("Sweep %s of payment %s failed: %s", sweep.TxID, sweep.PaymentID, sweep.LastError)
+		return
+	}
+
+	log.Printf("Sweep %s of payment %s confirmed, fee %d sun", sweep.TxID, sweep.PaymentID, sweep.FeeSun)
+	if s.refreshBatch(sweep.BatchID) != model.PaymentSwept {
+		return
+	}
+
+	// this was the last leg of the batch
+	payments, err := s.paymentRepo.FindPaymentsBySweepBatchId(sweep.BatchID)
+	if err != nil {
+		log.Printf("Failed to fetch payments of sweep batch %s: %v", sweep.BatchID, err)
+		return
+	}
+	for _, payment := range payments {
+		s.webhookService.Dispatch(model.PaymentSweptEvent, payment)
+	}
+}
+
+// refreshBatch derives the sweep status of the payments carried by a batch from its legs:
+// swept once every leg is confirmed, sweep_failed while any leg waits for manual review.
+func (s *sweepService) refreshBatch(batchID string) model.PaymentSweepStatus {
+	legs, err := s.repo.FindSweepsByBatchId(batchID)
+	if err != nil {
+		log.Printf("Failed to fetch sweeps of batch %s: %v", batchID, err)
+		return ""
+	}
+
+	status := model.PaymentSwept
+	for _, leg := range legs {
+		if leg.Status == model.SweepManualReview {
+			status = model.PaymentSweepFailed
+			break
+		}
+		if leg.Status != model.SweepConfirmed {
+			status = model.PaymentSweeping
+		}
+	}
+
+	if err := s.paymentRepo.UpdateSweepStatusBySweepBatchId(batchID, status); err != nil {
+		log.Printf("Failed to mark payments of sweep batch %s as %s: %v", batchID, status, err)
+	}
+	if err := s.orphanRepo.UpdateSweepStatusBySweepBatchId(batchID, status); err != nil {
+		log.Printf("Failed to mark orphaned deposits of sweep batch %s as %s: %v", batchID, status, err)
+	}
+	return status
+}
+
+// resend sends the leg again under the same record.
+func (s *sweepService) resend(sweep *model.Sweep) error {
+	payment, err := s.sweepSource(sweep)
+	if err != nil {
+		return err
+	}
+
+	sweep.Attempts++
+	txID, sendErr := s.sendLeg(payment, sweep.ToAddress, sweep.Amount)
+	if sendErr != nil {
+		sweep.LastError = sendErr.Error()
+	} else {
+		sweep.TxID = txID
+		sweep.Status = model.SweepPending
+		sweep.LastError = ""
+	}
+
+	if err := s.repo.UpdateSweep(sweep); err != nil {
+		return fmt.Errorf("failed to update sweep: %w", err)
+	}
+	if sendErr == nil {
+		s.refreshBatch(sweep.BatchID)
+	}
+	return sendErr
+}
+
+// RetrySweep re-sends a failed sweep right away, including ones waiting for manual review.
+func (s *sweepService) RetrySweep(id string) (*model.Sweep, error) {
+	sweep, err := s.repo.GetSweepByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if sweep.Status != model.SweepFailed && sweep.Status != model.SweepManualReview {
+		return nil, fmt.Errorf("sweep is %s, only failed sweeps can be retried", sweep.Status)
+	}
+
+	if err := s.resend(sweep); err != nil {
+		return nil, err
+	}
+	return sweep, nil
+}
+
+func (s *sweepService) GetSweeps(status, paymentID string) ([]model.Sweep, error) {
+	return s.repo.GetSweeps(status, paymentID)
+}
+
+// sendLeg sends amount of the payment's currency from its deposit wallet to the given address
+// and returns the broadcast transaction id.
+func (s *sweepService) sendLeg(payment model.Payment, to string, amount float64) (string, error) {
+	paymentWalletPrivKey, err := util.AesDecryptPK(payment.Wallet.WalletSecret)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt wallet key: %w", err)
+	}
+
+	var txID string
+	if payment.Currency.IsToken {
+		// deposit wallets hold no TRX, fund the energy and bandwidth of the transfer first
+		if err := s.topUpService.PrepareTokenSweep(payment, amount, to); err != nil {
+			return "", fmt.Errorf("failed to top up wallet: %w", err)
+		}
+
+		txID, err = tron.SendTRC20(tron.TRON_CLIENT, payment.Wallet.WalletAddress, to, payment.Currency.ContractAddr, amount, paymentWalletPrivKey)
+		if err != nil {
+			return "", fmt.Errorf("failed to send %s: %w", payment.CurrencyCode, err)
+		}
+
+		s.topUpService.ReclaimDelegatedEnergy(payment.Wallet.WalletAddress, txID)
+	} else {
+		txID, err = tron.SendTRX(tron.TRON_CLIENT, payment.Wallet.WalletAddress, to, amount, paymentWalletPrivKey)
+		if err != nil {
+			return "", fmt.Errorf("failed to send trx: %w", err)
+		}
+	}
+
+	log.Printf("Swept %.6f %s from %s to %s. TxID: %s", amount, payment.CurrencyCode, payment.Wallet.WalletAddress, to, txID)
+	return txID, nil
+}
+
+// sweepSource returns the payment whose deposit wallet the sweep is sent from.
+func (s *sweepService) sweepSource(sweep *model.Sweep) (model.Payment, error) {
+	if sweep.OrphanID == "" {
+		payment, err := s.paymentRepo.FindPaymentById(sweep.PaymentID)
+		if err != nil {
+			return model.Payment{}, fmt.Errorf("failed to fetch payment: %w", err)
+		}
+		return payment, nil
+	}
+
+	orphan, err := s.orphanRepo.GetOrphanByID(sweep.OrphanID)
+	if err != nil {
+		return model.Payment{}, fmt.Errorf("failed to fetch orphaned deposit: %w", err)
+	}
+	return orphanPayment(*orphan), nil
+}
+
+// orphanPayment is the stand-in the payment based sweep code moves an orphaned deposit with, it
+// carries the orphan's wallet and currency under the wallet's last payment. It has no plan, so
+// only sweep rules that aren't scoped to a plan apply.
+func orphanPayment(orphan model.OrphanDeposit) model.Payment {
+	return model.Payment{
+		ID:           orphan.LastPaymentID,
+		WalletID:     orphan.WalletID,
+		Wallet:       orphan.Wallet,
+		CurrencyCode: orphan.CurrencyCode,
+		Currency:     orphan.Currency,
+	}
+}
+
+// walletBalance returns the deposit wallet balance in the payment's currency,
+// reading the token contract for TRC20 payments and the account for TRX.
+func walletBalance(payment model.Payment) (float64, error) {
+	if payment.Currency.IsToken {
+		return tron.CheckTRC20Balance(tron.TRON_CLIENT, payment.Wallet.WalletAddress, payment.Currency.ContractAddr)
+	}
+	return tron.CheckBalance(tron.TRON_CLIENT, payment.Wallet.WalletAddress)
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"github.com/thebytearray/BytePayments/model"
+)
+
+func TestSplitLegs(t *testing.T) {
+	tests := []struct {
+		name      string
+		available float64
+		percents  []float64
+		want      []float64
+		wantErr   bool
+	}{
+		{"single leg takes everything", 12.345678, []float64{100}, []float64{12.345678}, false},
+		{"even split", 10, []float64{50, 50}, []float64{5, 5}, false},
+		{"uneven split", 100, []float64{70, 20, 10}, []float64{70, 20, 10}, false},
+		{"rounding goes to the last leg", 1, []float64{33.33, 33.33, 33.34}, []float64{0.3333, 0.3333, 0.3334}, false},
+		{"thirds of a sun amount", 0.000010, []float64{33.3, 33.3, 33.4}, []float64{0.000003, 0.000003, 0.000004}, false},
+		{"leg rounded down to nothing", 0.000001, []float64{50, 50}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			legs := make([]sweepLeg, len(tt.percents))
+			for i, percent := range tt.percents {
+				legs[i] = sweepLeg{to: "T" + string(rune('A'+i)), percent: percent}
+			}
+
+			err := splitLegs(legs, tt.available)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitLegs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var total float64
+			for i, leg := range legs {
+				if math.Abs(leg.amount-tt.want[i]) > 1e-9 {
+					t.Errorf("leg %d amount = %v, want %v", i, leg.amount, tt.want[i])
+				}
+				total += leg.amount
+			}
+			if math.Abs(total-tt.available) > 1e-9 {
+				t.Errorf("legs total %v, want all of %v", total, tt.available)
+			}
+		})
+	}
+}
+
+func TestCreditedAmount(t *testing.T) {
+	// wallet shared by the swept payments, an open payment and a late one
+	deposits := &fakeDepositRepository{deposits: []model.Deposit{
+		{PaymentID: "completed1", Amount: 10, Status: model.DepositConfirmed},
+		{PaymentID: "completed1", Amount: 2.5, Status: model.DepositConfirmed},
+		{PaymentID: "completed2", Amount: 4, Status: model.DepositConfirmed},
+		{PaymentID: "completed2", Amount: 1, Status: model.DepositConfirming},
+		{PaymentID: "open", Amount: 7, Status: model.DepositConfirmed},
+		{PaymentID: "late", Amount: 3, Status: model.DepositConfirmed},
+	}}
+	s := &sweepService{depositRepo: deposits}
+
+	tests := []struct {
+		name     string
+		payments []string
+		want     float64
+	}{
+		{"one payment", []string{"completed1"}, 12.5},
+		{"batch leaves other payments alone", []string{"completed1", "completed2"}, 16.5},
+		{"payment without deposits", []string{"unpaid"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payments := make([]model.Payment, len(tt.payments))
+			for i, id := range tt.payments {
+				payments[i] = model.Payment{ID: id}
+			}
+
+			got, err := s.creditedAmount(payments)
+			if err != nil {
+				t.Fatalf("creditedAmount() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("creditedAmount(%v) = %v, want %v", tt.payments, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/thebytearray/BytePayments/config"
+	"github.com/thebytearray/BytePayments/internal/tron"
+	"github.com/thebytearray/BytePayments/internal/util"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+)
+
+const (
+	// how long a sweep waits for its top-up to land before trying again next run
+	topUpConfirmTimeout = 60 * time.Second
+	// unconfirmed top-ups older than this have expired on chain
+	topUpExpiry = 10 * time.Minute
+	// extra TRX sent on top of the estimate in case energy usage shifts
+	topUpMarginPercent = 10
+)
+
+type TopUpService interface {
+	PrepareTokenSweep(payment model.Payment, amount float64, to string) error
+	ReclaimDelegatedEnergy(walletAddress, sweepTxID string)
+	GetAllTopUps() ([]model.TopUp, error)
+}
+
+type topUpService struct {
+	repo repository.TopUpRepository
+}
+
+func NewTopUpService(repo repository.TopUpRepository) TopUpService {
+	return &topUpService{repo}
+}
+
+// PrepareTokenSweep makes sure the deposit wallet of payment has the energy and bandwidth
+// (or the TRX to burn for them) to transfer amount tokens to the given address. Missing
+// resources are funded from the hot wallet and the call waits until the top-up is confirmed.
+func (s *topUpService) PrepareTokenSweep(payment model.Payment, amount float64, to string) error {
+	walletAddr := payment.Wallet.WalletAddress
+
+	// settle top-ups from earlier runs first so we never fund the same wallet twice
+	if err := s.settlePendingTopUps(walletAddr); err != nil {
+		return err
+	}
+
+	cost, err := tron.EstimateTRC20TransferCost(tron.TRON_CLIENT, walletAddr, to, payment.Currency.ContractAddr, amount)
+	if err != nil {
+		return fmt.Errorf("failed to estimate sweep cost: %w", err)
+	}
+
+	if cost.MissingEnergy() > 0 && config.Cfg.TRX_TOPUP_MODE == string(model.TopUpDelegate) {
+		if err := s.delegateEnergy(payment, cost); err != nil {
+			return err
+		}
+		cost.EnergyAvailable = cost.EnergyRequired
+	}
+
+	balance, err := tron.CheckBalance(tron.TRON_CLIENT, walletAddr)
+	if err != nil {
+		return fmt.Errorf("failed to check wallet trx balance: %w", err)
+	}
+
+	neededSun := cost.FeeSun() * (100 + topUpMarginPercent) / 100
+	deficitSun := neededSun - tron.TrxToSun(balance)
+	if deficitSun <= 0 {
+		return nil
+	}
+
+	return s.sendTRX(payment, cost, deficitSun)
+}
+
+func (s *topUpService) settlePendingTopUps(walletAddr string) error {
+	pending, err := s.repo.FindTopUpsByWalletAndStatus(walletAddr, model.TopUpPending)
+	if err != nil {
+		return fmt.Errorf("failed to fetch pending top-ups: %w", err)
+	}
+
+	for _, topUp := range pending {
+		_, err := tron.GetTransactionInfo(tron.TRON_CLIENT, topUp.TxID)
+		switch {
+		case err == nil:
+			topUp.Status = model.TopUpConfirmed
+		case errors.Is(err, tron.ErrTransactionNotFound) && time.Since(topUp.CreatedAt) < topUpExpiry:
+			return fmt.Errorf("top-up %s is not confirmed yet", topUp.TxID)
+		default:
+			log.Printf("Top-up %s for wallet %s failed: %v", topUp.TxID, walletAddr, err)
+			topUp.Status = model.TopUpFailed
+		}
+
+		if err := s.repo.UpdateTopUp(&topUp); err != nil {
+			return fmt.Errorf("failed to update top-up: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *topUpService) sendTRX(payment model.Payment, cost tron.TRC20TransferCost, amountSun int64) error {
+	hotWalletKey, err := hotWalletPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	txID, err := tron.SendTRX(tron.TRON_CLIENT, config.Cfg.TRX_HOT_WALLET_ADDRESS, payment.Wallet.WalletAddress, tron.SunToTrx(amountSun), hotWalletKey)
+	if err != nil {
+		return fmt.Errorf("failed to send top-up trx: %w", err)
+	}
+
+	log.Printf("Topped up %s with %.6f TRX for payment %s. TxID: %s", payment.Wallet.WalletAddress, tron.SunToTrx(amountSun), payment.ID, txID)
+
+	return s.recordAndWait(&model.TopUp{
+		ID:                util.GenerateUniqueID(),
+		PaymentID:         payment.ID,
+		WalletAddress:     payment.Wallet.WalletAddress,
+		Method:            model.TopUpTransfer,
+		AmountSun:         amountSun,
+		EnergyRequired:    cost.EnergyRequired,
+		BandwidthRequired: cost.BandwidthRequired,
+		TxID:              txID,
+		Status:            model.TopUpPending,
+	})
+}
+
+func (s *topUpService) delegateEnergy(payment model.Payment, cost tron.TRC20TransferCost) error {
+	hotWalletKey, err := hotWalletPrivateKey()
+	if err != nil {
+		return err
+	}
+
+	txID, stakedSun, err := tron.DelegateEnergy(tron.TRON_CLIENT, config.Cfg.TRX_HOT_WALLET_ADDRESS, payment.Wallet.WalletAddress, cost.MissingEnergy(), hotWalletKey)
+	if err != nil {
+		return fmt.Errorf("failed to delegate energy: %w", err)
+	}
+
+	log.Printf("Delegated %d energy to %s for payment %s. TxID: %s", cost.MissingEnergy(), payment.Wallet.WalletAddress, payment.ID, txID)
+
+	return s.recordAndWait(&model.TopUp{
+		ID:                util.GenerateUniqueID(),
+		PaymentID:         payment.ID,
+		WalletAddress:     payment.Wallet.WalletAddress,
+		Method:            model.TopUpDelegate,
+		AmountSun:         stakedSun,
+		EnergyRequired:    cost.EnergyRequired,
+		BandwidthRequired: cost.BandwidthRequired,
+		TxID:              txID,
+		Status:            model.TopUpPending,
+	})
+}
+
+// recordAndWait stores the top-up and blocks until it is confirmed. A top-up that is still
+// unconfirmed after the timeout stays pending and is settled on the next run.
+func (s *topUpService) recordAndWait(topUp *model.TopUp) error {
+	if err := s.repo.CreateTopUp(topUp); err != nil {
+		return fmt.Errorf("failed to record top-up: %w", err)
+	}
+
+	_, err := tron.WaitForTransaction(tron.TRON_CLIENT, topUp.TxID, topUpConfirmTimeout)
+	if errors.Is(err, tron.ErrTransactionNotFound) {
+		return fmt.Errorf("top-up %s is not confirmed yet", topUp.TxID)
+	}
+
+	topUp.Status = model.TopUpConfirmed
+	if err != nil {
+		topUp.Status = model.TopUpFailed
+	}
+
+	if updateErr := s.repo.UpdateTopUp(topUp); updateErr != nil {
+		return fmt.Errorf("failed to update top-up: %w", updateErr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("top-up %s failed: %w", topUp.TxID, err)
+	}
+	return nil
+}
+
+// ReclaimDelegatedEnergy takes back energy delegated to a wallet once its sweep is confirmed.
+func (s *topUpService) ReclaimDelegatedEnergy(walletAddress, sweepTxID string) {
+	confirmed, err := s.repo.FindTopUpsByWalletAndStatus(walletAddress, model.TopUpConfirmed)
+	if err != nil {
+		log.Printf("Failed to fetch top-ups for %s: %v", walletAddress, err)
+		return
+	}
+
+	var delegations []model.TopUp
+	for _, topUp := range confirmed {
+		if topUp.Method == model.TopUpDelegate {
+			delegations = append(delegations, topUp)
+		}
+	}
+	if len(delegations) == 0 {
+		return
+	}
+
+	// the energy is only spent once the sweep is in a block
+	if _, err := tron.WaitForTransaction(tron.TRON_CLIENT, sweepTxID, topUpConfirmTimeout); err != nil {
+		log.Printf("Sweep %s not confirmed, keeping energy delegated to %s: %v", sweepTxID, walletAddress, err)
+		return
+	}
+
+	hotWalletKey, err := hotWalletPrivateKey()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for _, topUp := range delegations {
+		txID, err := tron.UndelegateEnergy(tron.TRON_CLIENT, config.Cfg.TRX_HOT_WALLET_ADDRESS, walletAddress, topUp.AmountSun, hotWalletKey)
+		if err != nil {
+			log.Printf("Failed to reclaim energy of top-up %s: %v", topUp.ID, err)
+			continue
+		}
+
+		topUp.Status = model.TopUpReclaimed
+		if err := s.repo.UpdateTopUp(&topUp); err != nil {
+			log.Printf("Failed to update top-up %s: %v", topUp.ID, err)
+		}
+		log.Printf("Reclaimed energy delegated to %s. TxID: %s", walletAddress, txID)
+	}
+}
+
+func (s *topUpService) GetAllTopUps() ([]model.TopUp, error) {
+	return s.repo.GetAllTopUps()
+}
+
+func hotWalletPrivateKey() (string, error) {
+	if config.Cfg.TRX_HOT_WALLET_PRIVATE_KEY == "" {
+		return "", errors.New("TRX_HOT_WALLET_PRIVATE_KEY is not configured")
+	}
+	return config.Cfg.TRX_HOT_WALLET_PRIVATE_KEY, nil
+}
@@ -15,6 +15,7 @@ type VerificationService interface {
 	GenerateAndSendCode(email string) error
 	VerifyCode(email, code string) (bool, string, error)
 	IsEmailVerified(verificationToken string) bool
+	VerifiedEmail(verificationToken string) (string, bool)
 }
 
 type verificationService struct {
@@ -93,6 +94,18 @@ func (v *verificationService) IsEmailVerified(verificationToken string) bool {
 	return found
 }
 
+// VerifiedEmail returns the email the verification token was issued for
+func (v *verificationService) VerifiedEmail(verificationToken string) (string, bool) {
+	tokenKey := fmt.Sprintf("verified_email:%s", verificationToken)
+
+	email, found := v.cache.Get(tokenKey)
+	if !found {
+		return "", false
+	}
+	emailStr, ok := email.(string)
+	return emailStr, ok
+}
+
 // generateCode creates a random 6-digit code
 func (v *verificationService) generateCode() (string, error) {
 	code := ""
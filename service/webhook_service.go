@@ -0,0 +1,353 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thebytearray/BytePayments/dto"
+	"github.com/thebytearray/BytePayments/internal/database"
+	"github.com/thebytearray/BytePayments/internal/util"
+	"github.com/thebytearray/BytePayments/model"
+	"github.com/thebytearray/BytePayments/repository"
+)
+
+const (
+	webhookMaxAttempts  = 8
+	webhookBaseBackoff  = 30 * time.Second
+	webhookBatchSize    = 50
+	webhookTimeout      = 10 * time.Second
+	webhookSignatureHdr = "X-BytePayments-Signature"
+)
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+type WebhookService interface {
+	CreateEndpoint(req dto.CreateWebhookEndpointRequest) (dto.WebhookEndpointSecretResponse, error)
+	GetEndpoints() ([]model.WebhookEndpoint, error)
+	UpdateEndpoint(id string, req dto.UpdateWebhookEndpointRequest) (*model.WebhookEndpoint, error)
+	DeleteEndpoint(id string) error
+	Dispatch(event model.WebhookEvent, payment model.Payment)
+	ProcessDueDeliveries()
+	GetDeliveries(endpointID, status string) ([]model.WebhookDelivery, error)
+	ReplayDelivery(id string) (*model.WebhookDelivery, error)
+}
+
+type webhookService struct {
+	repo       repository.WebhookRepository
+	apiKeyRepo repository.APIKeyRepository
+}
+
+func NewWebhookService(repo repository.WebhookRepository) WebhookService {
+	return &webhookService{
+		repo:       repo,
+		apiKeyRepo: repository.NewAPIKeyRepository(database.DB),
+	}
+}
+
+func (s *webhookService) CreateEndpoint(req dto.CreateWebhookEndpointRequest) (dto.WebhookEndpointSecretResponse, error) {
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return dto.WebhookEndpointSecretResponse{}, err
+	}
+
+	if req.APIKeyID != "" {
+		if _, err := s.apiKeyRepo.GetAPIKeyByID(req.APIKeyID); err != nil {
+			return dto.WebhookEndpointSecretResponse{}, fmt.Errorf("API key %s not found: %w", req.APIKeyID, err)
+		}
+	}
+
+	secret, err := util.GenerateRandomHex(32)
+	if err != nil {
+		return dto.WebhookEndpointSecretResponse{}, err
+	}
+
+	endpoint := model.WebhookEndpoint{
+		ID:       util.GenerateUniqueID(),
+		APIKeyID: req.APIKeyID,
+		URL:      req.URL,
+		Secret:   "whsec_" + secret,
+		Events:   events,
+		Enabled:  true,
+	}
+
+	if err := s.repo.CreateEndpoint(&endpoint); err != nil {
+		return dto.WebhookEndpointSecretResponse{}, err
+	}
+
+	return dto.WebhookEndpointSecretResponse{WebhookEndpoint: endpoint, Secret: endpoint.Secret}, nil
+}
+
+func (s *webhookService) GetEndpoints() ([]model.WebhookEndpoint, error) {
+	return s.repo.GetEndpoints()
+}
+
+func (s *webhookService) UpdateEndpoint(id string, req dto.UpdateWebhookEndpointRequest) (*model.WebhookEndpoint, error) {
+	endpoint, err := s.repo.GetEndpointByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.URL = req.URL
+	endpoint.Events = events
+	endpoint.Enabled = req.Enabled
+
+	if err := s.repo.UpdateEndpoint(endpoint); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+func (s *webhookService) DeleteEndpoint(id string) error {
+	return s.repo.DeleteEndpoint(id)
+}
+
+// Dispatch queues event for every enabled endpoint of the payment's API key subscribed to it, a
+// merchant never receives the payments of another key. Deliveries are sent by the webhook cron so
+// a slow merchant server never blocks payment processing.
+func (s *webhookService) Dispatch(event model.WebhookEvent, payment model.Payment) {
+	endpoints, err := s.repo.GetEnabledEndpoints(payment.APIKeyID)
+	if err != nil {
+		log.Printf("Failed to fetch webhook endpoints for %s: %v", event, err)
+		return
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(dto.WebhookPayload{
+		ID:        util.GenerateUniqueID(),
+		Event:     event,
+		CreatedAt: now.Format(time.RFC3339),
+		Data:      webhookPaymentData(payment),
+	})
+	if err != nil {
+		log.Printf("Failed to encode webhook payload for payment %s: %v", payment.ID, err)
+		return
+	}
+
+	for _, endpoint := range endpoints {
+		if !subscribedTo(endpoint, event) {
+			continue
+		}
+
+		delivery := model.WebhookDelivery{
+			ID:            util.GenerateUniqueID(),
+			EndpointID:    endpoint.ID,
+			Event:         event,
+			PaymentID:     payment.ID,
+			Payload:       string(payload),
+			Status:        model.DeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err := s.repo.CreateDelivery(&delivery); err != nil {
+			log.Printf("Failed to queue %s webhook for endpoint %s: %v", event, endpoint.ID, err)
+		}
+	}
+}
+
+// ProcessDueDeliveries sends every pending delivery whose next attempt is due.
+func (s *webhookService) ProcessDueDeliveries() {
+	deliveries, err := s.repo.FindDueDeliveries(time.Now(), webhookBatchSize)
+	if err != nil {
+		log.Println("Error fetching due webhook deliveries : ", err)
+		return
+	}
+
+	for _, delivery := range deliveries {
+		endpoint, err := s.repo.GetEndpointByID(delivery.EndpointID)
+		if err != nil {
+			// the endpoint was deleted, nothing left to deliver to
+			delivery.Status = model.DeliveryFailed
+			delivery.LastError = "endpoint not found"
+			delivery.NextAttemptAt = nil
+			if err := s.repo.UpdateDelivery(&delivery); err != nil {
+				log.Printf("Failed to update webhook delivery %s: %v", delivery.ID, err)
+			}
+			continue
+		}
+
+		s.attempt(&delivery, endpoint)
+	}
+}
+
+func (s *webhookService) attempt(delivery *model.WebhookDelivery, endpoint *model.WebhookEndpoint) {
+	delivery.Attempts++
+	statusCode, err := s.send(delivery, endpoint)
+	delivery.LastResponseStatus = statusCode
+
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = model.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+	case delivery.Attempts >= webhookMaxAttempts:
+		delivery.Status = model.DeliveryFailed
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = nil
+		log.Printf("Webhook delivery %s to %s gave up after %d attempts: %v", delivery.ID, endpoint.URL, delivery.Attempts, err)
+	default:
+		// 30s, 1m, 2m, 4m ... between attempts
+		next := now.Add(webhookBaseBackoff * time.Duration(1<<(delivery.Attempts-1)))
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := s.repo.UpdateDelivery(delivery); err != nil {
+		log.Printf("Failed to update webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+func (s *webhookService) send(delivery *model.WebhookDelivery, endpoint *model.WebhookEndpoint) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, endpoint.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BytePayments-Webhooks/1.0")
+	req.Header.Set("X-BytePayments-Event", string(delivery.Event))
+	req.Header.Set("X-BytePayments-Delivery", delivery.ID)
+	req.Header.Set(webhookSignatureHdr, fmt.Sprintf("t=%s,v1=%s", timestamp, SignWebhookPayload(endpoint.Secret, timestamp, []byte(delivery.Payload))))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return resp.StatusCode, fmt.Errorf("endpoint responded %d: %s", resp.StatusCode, string(bytes.TrimSpace(body)))
+	}
+
+	return resp.StatusCode, nil
+}
+
+func (s *webhookService) GetDeliveries(endpointID, status string) ([]model.WebhookDelivery, error) {
+	return s.repo.GetDeliveries(endpointID, status)
+}
+
+// ReplayDelivery queues a fresh copy of a past delivery, the original stays in the log as it was.
+func (s *webhookService) ReplayDelivery(id string) (*model.WebhookDelivery, error) {
+	original, err := s.repo.GetDeliveryByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.repo.GetEndpointByID(original.EndpointID); err != nil {
+		return nil, fmt.Errorf("endpoint of delivery not found: %w", err)
+	}
+
+	now := time.Now()
+	replay := model.WebhookDelivery{
+		ID:            util.GenerateUniqueID(),
+		EndpointID:    original.EndpointID,
+		Event:         original.Event,
+		PaymentID:     original.PaymentID,
+		Payload:       original.Payload,
+		Status:        model.DeliveryPending,
+		NextAttemptAt: &now,
+	}
+
+	if err := s.repo.CreateDelivery(&replay); err != nil {
+		return nil, err
+	}
+	return &replay, nil
+}
+
+// SignWebhookPayload returns the hex HMAC-SHA256 of "timestamp.payload" keyed with the endpoint secret.
+// Receivers recompute it from the t= value of the signature header and the raw request body.
+func SignWebhookPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookPaymentData(payment model.Payment) dto.WebhookPaymentData {
+	var quoteExpiresAt, expiresAt string
+	if payment.QuoteExpiresAt != nil {
+		quoteExpiresAt = payment.QuoteExpiresAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if payment.ExpiresAt != nil {
+		expiresAt = payment.ExpiresAt.Format("2006-01-02T15:04:05Z07:00")
+	}
+
+	fiatCurrency, fiatAmount := paymentFiat(payment)
+	return dto.WebhookPaymentData{
+		PaymentId:         payment.ID,
+		Status:            payment.Status,
+		SweepStatus:       payment.SweepStatus,
+		PlanId:            payment.PlanID,
+		SubscriptionID:    payment.SubscriptionID,
+		Email:             payment.UserEmail,
+		CurrencyCode:      payment.CurrencyCode,
+		Amount:            payment.AmountTRX,
+		AmountUSD:         payment.AmountUSD,
+		FiatCurrency:      fiatCurrency,
+		FiatAmount:        fiatAmount,
+		CouponCode:        payment.CouponCode,
+		OriginalAmount:    payment.OriginalAmount,
+		DiscountAmount:    payment.DiscountAmount,
+		QuoteRate:         payment.QuoteRate,
+		QuoteSource:       payment.QuoteSource,
+		QuoteExpiresAt:    quoteExpiresAt,
+		ExpiresAt:         expiresAt,
+		PaidAmount:        payment.PaidAmountTRX,
+		RemainingAmount:   payment.RemainingAmount,
+		WalletAddress:     payment.Wallet.WalletAddress,
+		ExternalReference: paymentReference(payment),
+		Metadata:          paymentMetadata(payment),
+		SuccessURL:        payment.SuccessURL,
+		CancelURL:         payment.CancelURL,
+		CreatedAt:         payment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:         payment.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+var webhookEvents = []model.WebhookEvent{
+	model.PaymentCreatedEvent,
+	model.PaymentUnderpaidEvent,
+	model.PaymentCompletedEvent,
+	model.PaymentExpiredEvent,
+	model.PaymentRequotedEvent,
+	model.PaymentLateEvent,
+	model.PaymentCancelledEvent,
+	model.PaymentSweptEvent,
+	model.PaymentRefundedEvent,
+}
+
+func normalizeWebhookEvents(events []string) (string, error) {
+	for _, e := range events {
+		if !slices.Contains(webhookEvents, model.WebhookEvent(e)) {
+			return "", fmt.Errorf("unknown webhook event: %s", e)
+		}
+	}
+	return strings.Join(events, ","), nil
+}
+
+func subscribedTo(endpoint model.WebhookEndpoint, event model.WebhookEvent) bool {
+	if endpoint.Events == "" {
+		return true
+	}
+	return slices.Contains(strings.Split(endpoint.Events, ","), string(event))
+}